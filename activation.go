@@ -48,8 +48,6 @@ func GetActivation(act ActivationType) Differentiable {
 		return RootSwish{}
 	case ActivationMish:
 		return Mish{}
-	case ActivationCustom:
-		return Custom{}
 	case ActivationLinear:
 		return Linear{}
 	case ActivationSoftmax:
@@ -65,12 +63,31 @@ func GetActivation(act ActivationType) Differentiable {
 	case ActivationRootPow:
 		return RootPow{}
 	case ActivationDoublePow:
-		return RootPow{}
+		return DoublePow{}
+	case ActivationGELU:
+		return GELU{}
+	case ActivationGELUTanh:
+		return GELUTanh{}
+	case ActivationSELU:
+		return SELU{}
+	case ActivationLeakyReLU:
+		return LeakyReLU{Slope: DefaultLeakySlope}
+	case ActivationSoftplus:
+		return Softplus{}
+	case ActivationHardSigmoid:
+		return HardSigmoid{}
+	case ActivationHardSwish:
+		return HardSwish{}
+	case ActivationPReLU:
+		return PReLU{}
+	case ActivationSwishBeta:
+		return SwishBeta{}
 	}
 	return Linear{}
 }
 
-// ActivationType is represents a neuron activation function
+// ActivationType is represents a neuron activation function. Dump stores
+// activations by value, so values are never renumbered or reused.
 type ActivationType int
 
 const (
@@ -92,7 +109,8 @@ const (
 	ActivationSwish ActivationType = 7
 	// ActivationMish is a Mish activation
 	ActivationMish ActivationType = 8
-	// ActivationCustom is a Custom activation
+	// ActivationCustom is an activation registered by name, see
+	// LayerConfig.Custom
 	ActivationCustom ActivationType = 9
 	// ActivationCustom is a Custom activation
 	ActivationDoubleRoot ActivationType = 10
@@ -108,34 +126,52 @@ const (
 	ActivationDoublePow ActivationType = 15
 	// ActivationMulDiv is a Custom activation
 	ActivationRootSwish ActivationType = 16
+	// ActivationGELU is a Gaussian error linear unit activation
+	ActivationGELU ActivationType = 17
+	// ActivationGELUTanh is a GELU activation with the tanh approximation
+	ActivationGELUTanh ActivationType = 18
+	// ActivationSELU is a scaled exponential linear unit activation, whose
+	// layers are initialized with LeCunNormal by default
+	ActivationSELU ActivationType = 19
+	// ActivationLeakyReLU is a leaky rectified linear unit activation, whose
+	// slope for negative inputs is set by LayerConfig.Slope
+	ActivationLeakyReLU ActivationType = 20
+	// ActivationSoftplus is a softplus activation
+	ActivationSoftplus ActivationType = 21
+	// ActivationHardSigmoid is a piecewise linear sigmoid activation
+	ActivationHardSigmoid ActivationType = 22
+	// ActivationHardSwish is a piecewise Swish activation
+	ActivationHardSwish ActivationType = 23
+	// ActivationPReLU is a ReLU activation with a learned slope for negative
+	// inputs
+	ActivationPReLU ActivationType = 24
+	// ActivationSwishBeta is a Swish activation with a learned β
+	ActivationSwishBeta ActivationType = 25
 )
 
-// Differentiable is an activation function and its first order derivative,
-// where the latter is expressed as a function of the former for efficiency
+// Differentiable is an activation function and its first order derivative.
+// Df receives both the pre-activation x and the activation y = F(x), so that
+// it can use whichever is cheaper, and keeps no state between calls.
 type Differentiable interface {
-	F(float32, bool) float32
-	Df(float32) float32
+	F(x float32, training bool) float32
+	Df(x, y float32) float32
 }
 
 // Sigmoid is a logistic activator in the special case of a = 1
-type Sigmoid struct {
-	Mem map[float32]float32
-}
+type Sigmoid struct{}
 
 // F is Sigmoid(x)
 func (a Sigmoid) F(x float32, training bool) float32 { return Logistic(x, 1) }
 
-// Df is Sigmoid'(y), where y = Sigmoid(x)
-func (a Sigmoid) Df(y float32) float32 { return y * (1 - y) }
+// Df is Sigmoid'(x), where y = Sigmoid(x)
+func (a Sigmoid) Df(x, y float32) float32 { return y * (1 - y) }
 
 func Sqrt(N float32) float32 {
 	return math.Sqrt(N)
 }
 
-// DoubleRoot is a logistic activator in the special case of a = 1
-type DoubleRoot struct {
-	Mem map[float32]float32
-}
+// DoubleRoot is the square root of x, mirrored for negative x
+type DoubleRoot struct{}
 
 // F is DoubleRoot(x)
 func (a DoubleRoot) F(x float32, training bool) float32 {
@@ -148,23 +184,18 @@ func (a DoubleRoot) F(x float32, training bool) float32 {
 	}
 }
 
-// Df is DoubleRoot'(y), where y = DoubleRoot(x)
-func (a DoubleRoot) Df(x float32) float32 {
+// Df is DoubleRoot'(x), which is taken as 0 at 0
+func (a DoubleRoot) Df(x, y float32) float32 {
 	if x == 0 {
 		return 0
-	} else if x > 0 {
-		return 1 / (2 * Sqrt(x))
-	} else {
-		return 1 / (2 * Sqrt(-x))
 	}
+	return 1 / (2 * Sqrt(math.Abs(x)))
 }
 
-// RootX is a logistic activator in the special case of a = 1
-type DoublePow struct {
-	Mem map[float32]float32
-}
+// DoublePow is the square of x, mirrored for negative x
+type DoublePow struct{}
 
-// F is RootX(x)
+// F is DoublePow(x)
 func (a DoublePow) F(x float32, training bool) float32 {
 	if x == 0 {
 		return 0
@@ -175,23 +206,16 @@ func (a DoublePow) F(x float32, training bool) float32 {
 	}
 }
 
-// Df is DoubleRoot'(y), where y = DoubleRoot(x)
-func (a DoublePow) Df(x float32) float32 {
-	if x == 0 {
-		return 0
-	} else if x > 0 {
-		return (2 * x)
-	} else {
-		return -(2 * x)
-	}
+// Df is DoublePow'(x)
+func (a DoublePow) Df(x, y float32) float32 {
+	return 2 * math.Abs(x)
 }
 
-// RootX is a logistic activator in the special case of a = 1
-type RootPow struct {
-	Mem map[float32]float32
-}
+// RootPow is a shifted square for positive x and a shifted square root for
+// negative x, with slope 1 at 0
+type RootPow struct{}
 
-// F is RootX(x)
+// F is RootPow(x)
 func (a RootPow) F(x float32, training bool) float32 {
 	if x == 0 {
 		return 0
@@ -202,21 +226,17 @@ func (a RootPow) F(x float32, training bool) float32 {
 	}
 }
 
-// Df is DoubleRoot'(y), where y = DoubleRoot(x)
-func (a RootPow) Df(x float32) float32 {
-	if x == 0 {
-		return 0
-	} else if x > 0 {
+// Df is RootPow'(x)
+func (a RootPow) Df(x, y float32) float32 {
+	if x >= 0 {
 		return (2 * x) + 1
-	} else {
-		return 1 / (2 * Sqrt(0.25-x))
 	}
+	return 1 / (2 * Sqrt(0.25-x))
 }
 
-// RootX is a logistic activator in the special case of a = 1
-type RootX struct {
-	Mem map[float32]float32
-}
+// RootX is the identity for positive x and a shifted square root for
+// negative x, with slope 1 at 0
+type RootX struct{}
 
 // F is RootX(x)
 func (a RootX) F(x float32, training bool) float32 {
@@ -229,23 +249,18 @@ func (a RootX) F(x float32, training bool) float32 {
 	}
 }
 
-// Df is DoubleRoot'(y), where y = DoubleRoot(x)
-func (a RootX) Df(x float32) float32 {
-	if x == 0 {
-		return 0
-	} else if x > 0 {
+// Df is RootX'(x)
+func (a RootX) Df(x, y float32) float32 {
+	if x >= 0 {
 		return 1
-	} else {
-		return 1 / (2 * Sqrt(0.25-x))
 	}
+	return 1 / (2 * Sqrt(0.25-x))
 }
 
-// MulDiv is a logistic activator in the special case of a = 1
-type DivX struct {
-	Mem map[float32]float32
-}
+// DivX is the identity for positive x and approaches -1 for negative x
+type DivX struct{}
 
-// F is MulDiv(x)
+// F is DivX(x)
 func (a DivX) F(x float32, training bool) float32 {
 	if x >= 0 {
 		return x
@@ -254,21 +269,18 @@ func (a DivX) F(x float32, training bool) float32 {
 	}
 }
 
-// Df is MulDiv'(y), where y = MulDiv(x)
-func (a DivX) Df(x float32) float32 {
+// Df is DivX'(x)
+func (a DivX) Df(x, y float32) float32 {
 	if x >= 0 {
 		return 1
-	} else {
-		return (1 / ((x - 1) * (x - 1)))
 	}
+	return 1 / ((x - 1) * (x - 1))
 }
 
-// MulDiv is a logistic activator in the special case of a = 1
-type DoubleDiv struct {
-	Mem map[float32]float32
-}
+// DoubleDiv approaches 1 for positive x and -1 for negative x
+type DoubleDiv struct{}
 
-// F is MulDiv(x)
+// F is DoubleDiv(x)
 func (a DoubleDiv) F(x float32, training bool) float32 {
 
 	if x == 0 {
@@ -281,15 +293,10 @@ func (a DoubleDiv) F(x float32, training bool) float32 {
 
 }
 
-// Df is MulDiv'(y), where y = MulDiv(x)
-func (a DoubleDiv) Df(x float32) float32 {
-	if x == 0 {
-		return 0
-	} else if x > 0 {
-		return (1 / ((x + 1) * (x + 1)))
-	} else {
-		return (1 / ((x - 1) * (x - 1)))
-	}
+// Df is DoubleDiv'(x)
+func (a DoubleDiv) Df(x, y float32) float32 {
+	d := math.Abs(x) + 1
+	return 1 / (d * d)
 }
 
 // Logistic is the logistic function
@@ -298,20 +305,16 @@ func Logistic(x, a float32) float32 {
 }
 
 // Tanh is a hyperbolic activator
-type Tanh struct {
-	Mem map[float32]float32
-}
+type Tanh struct{}
 
 // F is Tanh(x)
 func (a Tanh) F(x float32, training bool) float32 { return (1 - math.Exp(-2*x)) / (1 + math.Exp(-2*x)) }
 
-// Df is Tanh'(y), where y = Tanh(x)
-func (a Tanh) Df(y float32) float32 { return 1 - math.Pow(y, 2) }
+// Df is Tanh'(x), where y = Tanh(x)
+func (a Tanh) Df(x, y float32) float32 { return 1 - y*y }
 
 // ReLU is a rectified linear unit activator
-type ReLU struct {
-	Mem map[float32]float32
-}
+type ReLU struct{}
 
 // F is ReLU(x)
 func (a ReLU) F(x float32, training bool) float32 {
@@ -320,197 +323,222 @@ func (a ReLU) F(x float32, training bool) float32 {
 
 }
 
-// Df is ReLU'(y), where y = ReLU(x)
-func (a ReLU) Df(y float32) float32 {
-
-	if y > 0 {
+// Df is ReLU'(x)
+func (a ReLU) Df(x, y float32) float32 {
+	if x > 0 {
 		return 1
 	}
 	return 0
 }
 
-type eLU struct {
-	Mem map[float32]float32
-}
+// eLU is an exponential linear unit activator, which is continuous at 0
+// and approaches -1 for negative x
+type eLU struct{}
 
 // F is ELU(x)
 func (a eLU) F(x float32, training bool) float32 {
-
 	if x >= 0 {
-		// elu formula
-		return x + 0.0000001
-	} else {
-		return 1.0*math.Pow(math.E, x)*-1 + float32(math.SmallestNonzeroFloat32)
+		return x
 	}
-
+	return math.Expm1(x)
 }
 
-// Df is ReLU'(y), where y = ReLU(x)
-func (a eLU) Df(y float32) float32 {
-	if y > 0 {
-		return 1 - 0.0000001
-	} else {
-		return 1.0*math.Exp(y) - float32(math.SmallestNonzeroFloat32)
+// Df is ELU'(x), where y = ELU(x)
+func (a eLU) Df(x, y float32) float32 {
+	if x >= 0 {
+		return 1
 	}
-
+	return y + 1
 }
 
-type Swish struct {
-	Mem map[float32]float32
-}
+// Swish is x·Sigmoid(x)
+type Swish struct{}
 
 // F is Swish(x)
 func (a Swish) F(x float32, training bool) float32 {
-	// 	if a.Mem == nil {
-	// 		a.Mem = map[float32]float32{}
-	// 	}
-	// 	ans := x * Logistic(x, 1)
-	// 	if training {
-	// 		a.Mem[ans] = x
-	// 	}
-	// 	return ans
-
 	return x / (math.Exp(-x) + 1)
-
 }
 
-// Df is swish'(y), where y = Swish(x)
-func (a Swish) Df(y float32) float32 {
-	// 	x := a.Mem[y]
-	// 	delete(a.Mem, y)
-	// 	sigX := Logistic(x, 1)
-	// 	return y * (sigX * (1 + x*(1-sigX)))
-	ey := math.Exp(y)
-	ey1 := ey + 1
-	return (ey * (ey1 + y)) / (ey1 * ey1)
-
+// Df is Swish'(x), where y = Swish(x)
+func (a Swish) Df(x, y float32) float32 {
+	return y + Logistic(x, 1)*(1-y)
 }
 
-type RootSwish struct {
-	Mem map[float32]float32
-}
+// RootSwish is Swish for positive x and a shifted square root for negative x
+type RootSwish struct{}
 
-// F is Swish(x)
+// F is RootSwish(x)
 func (a RootSwish) F(x float32, training bool) float32 {
-	// 	if a.Mem == nil {
-	// 		a.Mem = map[float32]float32{}
-	// 	}
-	// 	ans := x * Logistic(x, 1)
-	// 	if training {
-	// 		a.Mem[ans] = x
-	// 	}
-	// 	return ans
 	if x > 0 {
-
 		return x / (math.Exp(-x) + 1)
 	} else {
 		return 0.5 - math.Sqrt(0.25-(0.5*x))
 	}
-
 }
 
-// Df is swish'(y), where y = Swish(x)
-func (a RootSwish) Df(y float32) float32 {
-	// 	x := a.Mem[y]
-	// 	delete(a.Mem, y)
-	// 	sigX := Logistic(x, 1)
-	// 	return y * (sigX * (1 + x*(1-sigX)))
-	if y > 0 {
-		ey := math.Exp(y)
-		ey1 := ey + 1
-		return (ey * (ey1 + y)) / (ey1 * ey1)
-	} else {
-		return 1 / (2 * math.Sqrt(1-(2*y)))
+// Df is RootSwish'(x), where y = RootSwish(x)
+func (a RootSwish) Df(x, y float32) float32 {
+	if x > 0 {
+		return y + Logistic(x, 1)*(1-y)
 	}
-
+	return 1 / (4 * math.Sqrt(0.25-(0.5*x)))
 }
 
-type Mish struct {
-	Mem map[float32]float32
-}
+// Mish is x·tanh(softplus(x))
+type Mish struct{}
 
 // F is Mish(x)
 func (a Mish) F(x float32, training bool) float32 {
-	if a.Mem == nil {
-		a.Mem = map[float32]float32{}
-	}
+	return x * math.Tanh(softplus(x))
+}
 
-	ans := x * math.Tanh(math.Log(1+math.Exp(x)))
-	if training {
-		a.Mem[ans] = x
-	}
-	return ans
+// Df is Mish'(x)
+func (a Mish) Df(x, y float32) float32 {
+	t := math.Tanh(softplus(x))
+	return t + x*Logistic(x, 1)*(1-t*t)
+}
 
+// softplus is log(1 + eˣ), computed without overflow for large x
+func softplus(x float32) float32 {
+	if x > 20 {
+		return x
+	}
+	return math.Log1p(math.Exp(x))
 }
 
-// Df is Mish'(y), where y = Mish(x)
-func (a Mish) Df(y float32) float32 {
-	x := a.Mem[y]
-	delete(a.Mem, y)
-	sigX := Logistic(x, 1)
-	xTanhSp := math.Tanh(math.Log(1 + math.Exp(x)))
-	return y * (xTanhSp + x*sigX*(1-xTanhSp*xTanhSp))
+// GELU is x·Φ(x), where Φ is the standard normal distribution function
+type GELU struct{}
+
+// F is GELU(x)
+func (a GELU) F(x float32, training bool) float32 {
+	return 0.5 * x * (1 + math.Erf(x/math.Sqrt2))
+}
 
+// Df is GELU'(x)
+func (a GELU) Df(x, y float32) float32 {
+	return 0.5*(1+math.Erf(x/math.Sqrt2)) + x*math.Exp(-0.5*x*x)/math.Sqrt(2*math.Pi)
 }
 
-type Custom struct {
-	Mem map[float32]float32
+// geluTanh returns the tanh approximation of 2Φ(x)-1 and the derivative of
+// its argument
+func geluTanh(x float32) (t, du float32) {
+	const c = 0.7978845608 // √(2/π)
+	return math.Tanh(c * (x + 0.044715*x*x*x)), c * (1 + 3*0.044715*x*x)
 }
 
-var customF func(float32) float32
-var customDf func(float32, float32) float32
+// GELUTanh is GELU with Φ approximated through tanh
+type GELUTanh struct{}
 
-func SetCustomF(F func(float32) float32) {
-	customF = F
+// F is GELUTanh(x)
+func (a GELUTanh) F(x float32, training bool) float32 {
+	t, _ := geluTanh(x)
+	return 0.5 * x * (1 + t)
 }
 
-func SetCustomDf(Df func(float32, float32) float32) {
-	customDf = Df
+// Df is GELUTanh'(x)
+func (a GELUTanh) Df(x, y float32) float32 {
+	t, du := geluTanh(x)
+	return 0.5*(1+t) + 0.5*x*(1-t*t)*du
 }
 
-// F is Custom(x)
-func (a Custom) F(x float32, training bool) float32 {
+// Constants of SELU, which keep the mean and variance of the activations of
+// LeCunNormal initialized layers at 0 and 1
+const (
+	seluAlpha = 1.6732632423543772
+	seluScale = 1.0507009873554805
+)
+
+// SELU is a scaled exponential linear unit
+type SELU struct{}
 
-	if a.Mem == nil {
-		a.Mem = map[float32]float32{}
+// F is SELU(x)
+func (a SELU) F(x float32, training bool) float32 {
+	if x >= 0 {
+		return seluScale * x
 	}
+	return seluScale * seluAlpha * math.Expm1(x)
+}
 
-	if customF != nil {
-		ans := customF(x)
-		if training {
-			a.Mem[ans] = x
-		}
-		return ans
-	} else {
-		ans := x
-		if training {
-			a.Mem[ans] = x
-		}
+// Df is SELU'(x), where y = SELU(x)
+func (a SELU) Df(x, y float32) float32 {
+	if x >= 0 {
+		return seluScale
+	}
+	return y + seluScale*seluAlpha
+}
+
+// DefaultLeakySlope is the slope of LeakyReLU for negative inputs if unset
+const DefaultLeakySlope = 0.01
+
+// LeakyReLU is a rectified linear unit with Slope for negative inputs
+type LeakyReLU struct {
+	Slope float32
+}
+
+// F is LeakyReLU(x)
+func (a LeakyReLU) F(x float32, training bool) float32 {
+	if x > 0 {
 		return x
 	}
+	return a.Slope * x
+}
 
+// Df is LeakyReLU'(x)
+func (a LeakyReLU) Df(x, y float32) float32 {
+	if x > 0 {
+		return 1
+	}
+	return a.Slope
 }
 
-// Df is Custom'(y), where y = Custom(x)
-func (a Custom) Df(y float32) float32 {
-	x := a.Mem[y]
-	delete(a.Mem, y)
+// Softplus is log(1 + eˣ)
+type Softplus struct{}
 
-	if customDf != nil {
-		return customDf(y, x)
-	} else {
-		return x
+// F is Softplus(x)
+func (a Softplus) F(x float32, training bool) float32 { return softplus(x) }
+
+// Df is Softplus'(x)
+func (a Softplus) Df(x, y float32) float32 { return Logistic(x, 1) }
+
+// HardSigmoid is the piecewise linear sigmoid (x+3)/6, clipped to [0, 1]
+type HardSigmoid struct{}
+
+// F is HardSigmoid(x)
+func (a HardSigmoid) F(x float32, training bool) float32 {
+	return math.Max(0, math.Min(1, x/6+0.5))
+}
+
+// Df is HardSigmoid'(x)
+func (a HardSigmoid) Df(x, y float32) float32 {
+	if x <= -3 || x >= 3 {
+		return 0
 	}
+	return 1.0 / 6
+}
+
+// HardSwish is x·HardSigmoid(x)
+type HardSwish struct{}
 
+// F is HardSwish(x)
+func (a HardSwish) F(x float32, training bool) float32 {
+	return x * math.Max(0, math.Min(1, x/6+0.5))
 }
 
-// Linear is a linear activator
-type Linear struct {
-	Mem map[float32]float32
+// Df is HardSwish'(x)
+func (a HardSwish) Df(x, y float32) float32 {
+	if x <= -3 {
+		return 0
+	} else if x >= 3 {
+		return 1
+	}
+	return (2*x + 3) / 6
 }
 
+// Linear is a linear activator
+type Linear struct{}
+
 // F is the identity function
 func (a Linear) F(x float32, training bool) float32 { return x }
 
 // Df is constant
-func (a Linear) Df(x float32) float32 { return 1 }
+func (a Linear) Df(x, y float32) float32 { return 1 }
@@ -0,0 +1,317 @@
+package deep
+
+import "math"
+
+// Differentiable64 is the float64 counterpart of Differentiable, used by
+// float64 networks
+type Differentiable64 interface {
+	F64(x float64, training bool) float64
+	Df64(x, y float64) float64
+}
+
+// GetActivation64 returns the concrete float64 activation given an
+// ActivationType
+func GetActivation64(act ActivationType) Differentiable64 {
+	if a, ok := GetActivation(act).(Differentiable64); ok {
+		return a
+	}
+	return Linear{}
+}
+
+// F64 is Sigmoid(x)
+func (a Sigmoid) F64(x float64, training bool) float64 { return Logistic64(x, 1) }
+
+// Df64 is Sigmoid'(x), where y = Sigmoid(x)
+func (a Sigmoid) Df64(x, y float64) float64 { return y * (1 - y) }
+
+// F64 is DoubleRoot(x)
+func (a DoubleRoot) F64(x float64, training bool) float64 {
+	if x == 0 {
+		return 0
+	} else if x > 0 {
+		return math.Sqrt(x)
+	} else {
+		return -math.Sqrt(-x)
+	}
+}
+
+// Df64 is DoubleRoot'(x), which is taken as 0 at 0
+func (a DoubleRoot) Df64(x, y float64) float64 {
+	if x == 0 {
+		return 0
+	}
+	return 1 / (2 * math.Sqrt(math.Abs(x)))
+}
+
+// F64 is DoublePow(x)
+func (a DoublePow) F64(x float64, training bool) float64 {
+	if x == 0 {
+		return 0
+	} else if x > 0 {
+		return x * x
+	} else {
+		return x * (-x)
+	}
+}
+
+// Df64 is DoublePow'(x)
+func (a DoublePow) Df64(x, y float64) float64 {
+	return 2 * math.Abs(x)
+}
+
+// F64 is RootPow(x)
+func (a RootPow) F64(x float64, training bool) float64 {
+	if x == 0 {
+		return 0
+	} else if x > 0 {
+		return ((x + 0.5) * (x + 0.5)) - 0.25
+	} else {
+		return 0.5 - math.Sqrt(0.25-x)
+	}
+}
+
+// Df64 is RootPow'(x)
+func (a RootPow) Df64(x, y float64) float64 {
+	if x >= 0 {
+		return (2 * x) + 1
+	}
+	return 1 / (2 * math.Sqrt(0.25-x))
+}
+
+// F64 is RootX(x)
+func (a RootX) F64(x float64, training bool) float64 {
+	if x == 0 {
+		return 0
+	} else if x > 0 {
+		return x
+	} else {
+		return 0.5 - math.Sqrt(0.25-x)
+	}
+}
+
+// Df64 is RootX'(x)
+func (a RootX) Df64(x, y float64) float64 {
+	if x >= 0 {
+		return 1
+	}
+	return 1 / (2 * math.Sqrt(0.25-x))
+}
+
+// F64 is DivX(x)
+func (a DivX) F64(x float64, training bool) float64 {
+	if x >= 0 {
+		return x
+	} else {
+		return (1/(x-1) + 1) * -1
+	}
+}
+
+// Df64 is DivX'(x)
+func (a DivX) Df64(x, y float64) float64 {
+	if x >= 0 {
+		return 1
+	}
+	return 1 / ((x - 1) * (x - 1))
+}
+
+// F64 is DoubleDiv(x)
+func (a DoubleDiv) F64(x float64, training bool) float64 {
+	if x == 0 {
+		return 0
+	} else if x > 0 {
+		return (1/(x+1) - 1) * -1
+	} else {
+		return (1/(x-1) + 1) * -1
+	}
+}
+
+// Df64 is DoubleDiv'(x)
+func (a DoubleDiv) Df64(x, y float64) float64 {
+	d := math.Abs(x) + 1
+	return 1 / (d * d)
+}
+
+// Logistic64 is the float64 logistic function
+func Logistic64(x, a float64) float64 {
+	return 1 / (1 + math.Exp(-a*x))
+}
+
+// F64 is Tanh(x)
+func (a Tanh) F64(x float64, training bool) float64 { return math.Tanh(x) }
+
+// Df64 is Tanh'(x), where y = Tanh(x)
+func (a Tanh) Df64(x, y float64) float64 { return 1 - y*y }
+
+// F64 is ReLU(x)
+func (a ReLU) F64(x float64, training bool) float64 { return math.Max(x, 0) }
+
+// Df64 is ReLU'(x)
+func (a ReLU) Df64(x, y float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+// F64 is ELU(x)
+func (a eLU) F64(x float64, training bool) float64 {
+	if x >= 0 {
+		return x
+	}
+	return math.Expm1(x)
+}
+
+// Df64 is ELU'(x), where y = ELU(x)
+func (a eLU) Df64(x, y float64) float64 {
+	if x >= 0 {
+		return 1
+	}
+	return y + 1
+}
+
+// F64 is Swish(x)
+func (a Swish) F64(x float64, training bool) float64 {
+	return x / (math.Exp(-x) + 1)
+}
+
+// Df64 is Swish'(x), where y = Swish(x)
+func (a Swish) Df64(x, y float64) float64 {
+	return y + Logistic64(x, 1)*(1-y)
+}
+
+// F64 is RootSwish(x)
+func (a RootSwish) F64(x float64, training bool) float64 {
+	if x > 0 {
+		return x / (math.Exp(-x) + 1)
+	} else {
+		return 0.5 - math.Sqrt(0.25-(0.5*x))
+	}
+}
+
+// Df64 is RootSwish'(x), where y = RootSwish(x)
+func (a RootSwish) Df64(x, y float64) float64 {
+	if x > 0 {
+		return y + Logistic64(x, 1)*(1-y)
+	}
+	return 1 / (4 * math.Sqrt(0.25-(0.5*x)))
+}
+
+// F64 is Mish(x)
+func (a Mish) F64(x float64, training bool) float64 {
+	return x * math.Tanh(softplus64(x))
+}
+
+// Df64 is Mish'(x)
+func (a Mish) Df64(x, y float64) float64 {
+	t := math.Tanh(softplus64(x))
+	return t + x*Logistic64(x, 1)*(1-t*t)
+}
+
+// softplus64 is log(1 + eˣ), computed without overflow for large x
+func softplus64(x float64) float64 {
+	if x > 40 {
+		return x
+	}
+	return math.Log1p(math.Exp(x))
+}
+
+// F64 is GELU(x)
+func (a GELU) F64(x float64, training bool) float64 {
+	return 0.5 * x * (1 + math.Erf(x/math.Sqrt2))
+}
+
+// Df64 is GELU'(x)
+func (a GELU) Df64(x, y float64) float64 {
+	return 0.5*(1+math.Erf(x/math.Sqrt2)) + x*math.Exp(-0.5*x*x)/math.Sqrt(2*math.Pi)
+}
+
+// geluTanh64 returns the tanh approximation of 2Φ(x)-1 and the derivative
+// of its argument
+func geluTanh64(x float64) (t, du float64) {
+	c := math.Sqrt(2 / math.Pi)
+	return math.Tanh(c * (x + 0.044715*x*x*x)), c * (1 + 3*0.044715*x*x)
+}
+
+// F64 is GELUTanh(x)
+func (a GELUTanh) F64(x float64, training bool) float64 {
+	t, _ := geluTanh64(x)
+	return 0.5 * x * (1 + t)
+}
+
+// Df64 is GELUTanh'(x)
+func (a GELUTanh) Df64(x, y float64) float64 {
+	t, du := geluTanh64(x)
+	return 0.5*(1+t) + 0.5*x*(1-t*t)*du
+}
+
+// F64 is SELU(x)
+func (a SELU) F64(x float64, training bool) float64 {
+	if x >= 0 {
+		return seluScale * x
+	}
+	return seluScale * seluAlpha * math.Expm1(x)
+}
+
+// Df64 is SELU'(x), where y = SELU(x)
+func (a SELU) Df64(x, y float64) float64 {
+	if x >= 0 {
+		return seluScale
+	}
+	return y + seluScale*seluAlpha
+}
+
+// F64 is LeakyReLU(x)
+func (a LeakyReLU) F64(x float64, training bool) float64 {
+	if x > 0 {
+		return x
+	}
+	return float64(a.Slope) * x
+}
+
+// Df64 is LeakyReLU'(x)
+func (a LeakyReLU) Df64(x, y float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return float64(a.Slope)
+}
+
+// F64 is Softplus(x)
+func (a Softplus) F64(x float64, training bool) float64 { return softplus64(x) }
+
+// Df64 is Softplus'(x)
+func (a Softplus) Df64(x, y float64) float64 { return Logistic64(x, 1) }
+
+// F64 is HardSigmoid(x)
+func (a HardSigmoid) F64(x float64, training bool) float64 {
+	return math.Max(0, math.Min(1, x/6+0.5))
+}
+
+// Df64 is HardSigmoid'(x)
+func (a HardSigmoid) Df64(x, y float64) float64 {
+	if x <= -3 || x >= 3 {
+		return 0
+	}
+	return 1.0 / 6
+}
+
+// F64 is HardSwish(x)
+func (a HardSwish) F64(x float64, training bool) float64 {
+	return x * math.Max(0, math.Min(1, x/6+0.5))
+}
+
+// Df64 is HardSwish'(x)
+func (a HardSwish) Df64(x, y float64) float64 {
+	if x <= -3 {
+		return 0
+	} else if x >= 3 {
+		return 1
+	}
+	return (2*x + 3) / 6
+}
+
+// F64 is the identity function
+func (a Linear) F64(x float64, training bool) float64 { return x }
+
+// Df64 is constant
+func (a Linear) Df64(x, y float64) float64 { return 1 }
@@ -0,0 +1,144 @@
+package deep
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ActivationDerivatives(t *testing.T) {
+	activations := []Differentiable{
+		Sigmoid{}, Tanh{}, ReLU{}, eLU{}, Swish{}, RootSwish{}, Mish{}, Linear{},
+		DoubleRoot{}, RootX{}, DivX{}, DoubleDiv{}, RootPow{}, DoublePow{},
+		GELU{}, GELUTanh{}, SELU{}, LeakyReLU{Slope: 0.2}, Softplus{}, HardSigmoid{}, HardSwish{},
+	}
+	for _, a := range activations {
+		worst, at := CheckActivation(a, []float32{-2.5, -0.7, 0.3, 1.5, 2.5}, 1e-3)
+		assert.True(t, worst < 1e-3, "%T at %v: %v", a, at, worst)
+	}
+}
+
+func Test_ActivationDerivatives64(t *testing.T) {
+	activations := []Differentiable64{
+		Sigmoid{}, Tanh{}, ReLU{}, eLU{}, Swish{}, RootSwish{}, Mish{}, Linear{},
+		DoubleRoot{}, RootX{}, DivX{}, DoubleDiv{}, RootPow{}, DoublePow{},
+		GELU{}, GELUTanh{}, SELU{}, LeakyReLU{Slope: 0.2}, Softplus{}, HardSigmoid{}, HardSwish{},
+	}
+	const h = 1e-6
+	for _, a := range activations {
+		for _, x := range []float64{-2.5, -0.7, 0.3, 1.5, 4} {
+			numeric := (a.F64(x+h, false) - a.F64(x-h, false)) / (2 * h)
+			assert.InDelta(t, numeric, a.Df64(x, a.F64(x, false)), 1e-5, "%T at %v", a, x)
+		}
+	}
+}
+
+func Test_ActivationValues(t *testing.T) {
+	tests := []struct {
+		act  ActivationType
+		x, y float32
+	}{
+		{ActivationGELU, 1, 0.841345},
+		{ActivationGELU, -1, -0.158655},
+		{ActivationGELUTanh, 1, 0.841192},
+		{ActivationSELU, 1, 1.050701},
+		{ActivationSELU, -1, -1.111331},
+		{ActivationLeakyReLU, -2, -0.02},
+		{ActivationSoftplus, 0, 0.693147},
+		{ActivationSoftplus, 100, 100},
+		{ActivationHardSigmoid, 0, 0.5},
+		{ActivationHardSigmoid, 4, 1},
+		{ActivationHardSwish, 1, 0.666667},
+		{ActivationHardSwish, -4, 0},
+	}
+	for _, test := range tests {
+		assert.InDelta(t, test.y, GetActivation(test.act).F(test.x, false), 1e-5, "activation %d at %v", test.act, test.x)
+		assert.InDelta(t, test.y, GetActivation64(test.act).F64(float64(test.x), false), 1e-5, "activation %d at %v", test.act, test.x)
+	}
+
+	// Dump stores activations by value
+	for act, value := range map[ActivationType]int{
+		ActivationGELU:        17,
+		ActivationGELUTanh:    18,
+		ActivationSELU:        19,
+		ActivationLeakyReLU:   20,
+		ActivationSoftplus:    21,
+		ActivationHardSigmoid: 22,
+		ActivationHardSwish:   23,
+	} {
+		assert.Equal(t, value, int(act))
+	}
+}
+
+func Test_LeakyReLUSlope(t *testing.T) {
+	for _, precision := range []Precision{PrecisionFloat32, PrecisionFloat64} {
+		n := NewNeural(&Config{
+			Inputs:     1,
+			Layout:     []int{2, 1},
+			Layers:     []LayerConfig{{Slope: 0.3}},
+			Activation: []ActivationType{ActivationLeakyReLU},
+			Mode:       ModeRegression,
+			Weight:     NewUniform(0, -1),
+			Precision:  precision,
+		})
+		assert.InDelta(t, -0.3, n.Layers[0].activation().F(-1, false), 1e-6)
+		assert.InDelta(t, 0.3, n.Layers[0].activation64().Df64(-1, 0.3), 1e-6)
+
+		dump, err := n.Marshal()
+		assert.NoError(t, err)
+		m, err := Unmarshal(dump)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(0.3), m.Layers[0].Slope)
+		assert.Equal(t, n.Predict([]float32{2}), m.Predict([]float32{2}))
+	}
+
+	// Layers without a slope use the default
+	l := NewLayer(1, 1, ActivationLeakyReLU)
+	assert.InDelta(t, -DefaultLeakySlope, l.activation().F(-1, false), 1e-6)
+}
+
+func Test_ActivationConcurrent(t *testing.T) {
+	var a Mish
+	want := a.Df(-1, a.F(-1, false))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(x float32) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				a.Df(x, a.F(x, false))
+			}
+		}(float32(g))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				assert.Equal(t, want, a.Df(-1, a.F(-1, false)))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_StateSums(t *testing.T) {
+	n := NewNeural(&Config{
+		Inputs:     2,
+		Layout:     []int{3, 1},
+		Activation: []ActivationType{ActivationReLU},
+		Mode:       ModeRegression,
+		Weight:     NewNormal(1, 0),
+		Bias:       true,
+	})
+	s := n.NewState()
+	input := []float32{0.5, -1}
+	n.ForwardState(s, input, false)
+
+	l := n.Layers[0]
+	for k := range s.Sums[0] {
+		sum := Dot(l.Row(k), input) + l.Bias[k]
+		assert.InDelta(t, sum, s.Sums[0][k], 1e-6)
+		assert.Equal(t, l.activation().F(sum, false), s.Values[0][k])
+	}
+}
@@ -0,0 +1,286 @@
+package deep
+
+import (
+	"fmt"
+
+	math "github.com/chewxy/math32"
+)
+
+// NewAttention creates a multi-head self-attention layer over a window of
+// length positions, whose features are laid out position by position in its
+// inputs. Its weight rows hold the query, key, value and output projections
+// of each feature in turn, each connected to the features of one position.
+// The features are split evenly between heads, each attending over the
+// positions with scaled dot products. Causal layers mask the positions after
+// each query, and positional layers add sinusoidal encodings of the position
+// to their input.
+func NewAttention(inputs, length, heads int, causal, positional bool, activation ActivationType) *Layer {
+	if length == 0 {
+		length = 1
+	}
+	if heads == 0 {
+		heads = 1
+	}
+	if inputs%length != 0 {
+		panic(fmt.Sprintf("Invalid attention length %d - expected a divisor of the %d inputs", length, inputs))
+	}
+	if (inputs/length)%heads != 0 {
+		panic(fmt.Sprintf("Invalid attention heads %d - expected a divisor of the %d features", heads, inputs/length))
+	}
+	l := &Layer{
+		A:          activation,
+		Type:       LayerAttention,
+		Inputs:     inputs,
+		Size:       inputs,
+		Value:      make([]float32, inputs),
+		Heads:      heads,
+		Length:     length,
+		Causal:     causal,
+		Positional: positional,
+	}
+	l.Weights = make([]float32, l.Rows()*l.RowSize())
+	return l
+}
+
+// dim returns the number of features of each position of an attention layer
+func (l *Layer) dim() int {
+	return l.Inputs / l.Length
+}
+
+// Window returns the number of steps of a sequence the attention layers of n
+// see at once, or 0 if n has none. Each input of n is then a window of
+// steps, as built by WindowInput.
+func (n *Neural) Window() int {
+	for _, l := range n.Layers {
+		if l.Type == LayerAttention {
+			return l.Length
+		}
+	}
+	return 0
+}
+
+// checkAttention panics if attention is combined with recurrent layers,
+// which step through sequences rather than see them in windows
+func checkAttention(layers []*Layer) {
+	var attention, recurrent bool
+	for _, l := range layers {
+		attention = attention || l.Type == LayerAttention
+		recurrent = recurrent || l.Recurrent()
+	}
+	if attention && recurrent {
+		panic("Invalid layers - attention cannot be combined with recurrent layers")
+	}
+}
+
+// WindowInput returns the input of a network of the given Window for the
+// last step of steps, which holds the last window steps in order, preceded
+// by zeros if there are fewer
+func WindowInput(steps [][]float32, window int) []float32 {
+	if len(steps) == 0 {
+		return nil
+	}
+	dim := len(steps[0])
+	input := make([]float32, window*dim)
+	for t := max(0, len(steps)-window); t < len(steps); t++ {
+		copy(input[(window-len(steps)+t)*dim:], steps[t])
+	}
+	return input
+}
+
+// predictWindows predicts every step of a sequence from the window ending
+// at the step
+func (n *Neural) predictWindows(inputs [][]float32) ([][]float32, error) {
+	out := make([][]float32, len(inputs))
+	for t := range inputs {
+		y := make([]float32, n.Layers[len(n.Layers)-1].Size)
+		if err := n.PredictInto(y, WindowInput(inputs[:t+1], n.Window())); err != nil {
+			return nil, err
+		}
+		out[t] = y
+	}
+	return out, nil
+}
+
+// positionalEncoding returns the sinusoidal encoding of feature k of
+// position p of dim features
+func positionalEncoding(p, k, dim int) float32 {
+	angle := float32(p) / math.Pow(10000, float32(k-k%2)/float32(dim))
+	if k%2 == 0 {
+		return math.Sin(angle)
+	}
+	return math.Cos(angle)
+}
+
+// attention holds the intermediate values of a forward pass through an
+// attention layer: its input with positional encodings, the query, key and
+// value projections and the attended context of each position, and the
+// attention weights of each head, query and key position
+type attention struct {
+	x, q, k, v, ctx []float32
+	weights         []float32
+}
+
+func newAttention(l *Layer) *attention {
+	return &attention{
+		x:       make([]float32, l.Inputs),
+		q:       make([]float32, l.Inputs),
+		k:       make([]float32, l.Inputs),
+		v:       make([]float32, l.Inputs),
+		ctx:     make([]float32, l.Inputs),
+		weights: make([]float32, l.Heads*l.Length*l.Length),
+	}
+}
+
+// project computes the projection of block b of the weight rows of each
+// position of x into out
+func (l *Layer) project(b int, x, out []float32) {
+	d := l.dim()
+	for p := 0; p < l.Length; p++ {
+		in := x[p*d : (p+1)*d]
+		for r := 0; r < d; r++ {
+			sum := Dot(l.Row(b*d+r), in)
+			if l.Bias != nil {
+				sum += l.Bias[b*d+r]
+			}
+			out[p*d+r] = sum
+		}
+	}
+}
+
+// attend computes the attention of input through l into a, up to the
+// attended context of each position
+func (l *Layer) attend(a *attention, input []float32) {
+	d, dh := l.dim(), l.dim()/l.Heads
+	copy(a.x, input)
+	if l.Positional {
+		for p := 0; p < l.Length; p++ {
+			for k := 0; k < d; k++ {
+				a.x[p*d+k] += positionalEncoding(p, k, d)
+			}
+		}
+	}
+	l.project(0, a.x, a.q)
+	l.project(1, a.x, a.k)
+	l.project(2, a.x, a.v)
+
+	scale := 1 / math.Sqrt(float32(dh))
+	for k := range a.ctx {
+		a.ctx[k] = 0
+	}
+	for h := 0; h < l.Heads; h++ {
+		for i := 0; i < l.Length; i++ {
+			w := a.weights[(h*l.Length+i)*l.Length : (h*l.Length+i+1)*l.Length]
+			q := a.q[i*d+h*dh : i*d+(h+1)*dh]
+			keys := l.Length
+			if l.Causal {
+				keys = i + 1
+			}
+			for j := range w {
+				w[j] = 0
+				if j < keys {
+					w[j] = scale * Dot(q, a.k[j*d+h*dh:j*d+(h+1)*dh])
+				}
+			}
+			SoftmaxTo(w[:keys], w[:keys])
+			ctx := a.ctx[i*d+h*dh : i*d+(h+1)*dh]
+			for j, wj := range w[:keys] {
+				for k, v := range a.v[j*d+h*dh : j*d+(h+1)*dh] {
+					ctx[k] += wj * v
+				}
+			}
+		}
+	}
+}
+
+// fireAttention attends over the whole window, as a single unit of work
+func (j *layerJob) fireAttention(from, to int) {
+	l, a := j.l, j.attn
+	l.attend(a, j.in)
+	l.project(3, a.ctx, j.out)
+	for k, y := range j.out {
+		j.out[k] = j.activate(k, y)
+	}
+}
+
+// backwardAttention recomputes the forward pass of input and backpropagates
+// deltas through the output projection, the attention weights of each head
+// and the query, key and value projections
+func (l *Layer) backwardAttention(input, deltas, grad, dx []float32) {
+	d, dh := l.dim(), l.dim()/l.Heads
+	a := newAttention(l)
+	l.attend(a, input)
+
+	dctx := make([]float32, l.Inputs)
+	l.backwardProjection(3, a.ctx, deltas, grad, dctx)
+
+	dq, dk, dv := make([]float32, l.Inputs), make([]float32, l.Inputs), make([]float32, l.Inputs)
+	dw := make([]float32, l.Length)
+	scale := 1 / math.Sqrt(float32(dh))
+	for h := 0; h < l.Heads; h++ {
+		for i := 0; i < l.Length; i++ {
+			w := a.weights[(h*l.Length+i)*l.Length : (h*l.Length+i+1)*l.Length]
+			dc := dctx[i*d+h*dh : i*d+(h+1)*dh]
+			keys := l.Length
+			if l.Causal {
+				keys = i + 1
+			}
+
+			// Gradients of the weights, then of the scores through the
+			// softmax
+			var dot float32
+			for j, wj := range w[:keys] {
+				dw[j] = Dot(dc, a.v[j*d+h*dh:j*d+(h+1)*dh])
+				dot += wj * dw[j]
+				for k, g := range dc {
+					dv[j*d+h*dh+k] += wj * g
+				}
+			}
+			q := a.q[i*d+h*dh : i*d+(h+1)*dh]
+			for j, wj := range w[:keys] {
+				ds := wj * (dw[j] - dot) * scale
+				key := a.k[j*d+h*dh : j*d+(h+1)*dh]
+				for k := range q {
+					dq[i*d+h*dh+k] += ds * key[k]
+					dk[j*d+h*dh+k] += ds * q[k]
+				}
+			}
+		}
+	}
+
+	// Positional encodings are constant, so the gradients of the input are
+	// those of the encoded input
+	l.backwardProjection(0, a.x, dq, grad, dx)
+	l.backwardProjection(1, a.x, dk, grad, dx)
+	l.backwardProjection(2, a.x, dv, grad, dx)
+}
+
+// backwardProjection propagates the gradients dy of the projection of x
+// through block b of the weight rows, adding the weight gradients into grad
+// and the gradients of x into dx, skipping nil slices
+func (l *Layer) backwardProjection(b int, x, dy, grad, dx []float32) {
+	d := l.dim()
+	for p := 0; p < l.Length; p++ {
+		in := x[p*d : (p+1)*d]
+		for r := 0; r < d; r++ {
+			g := dy[p*d+r]
+			if g == 0 {
+				continue
+			}
+			row := b*d + r
+			if grad != nil {
+				gr := grad[row*d : (row+1)*d]
+				for k, v := range in {
+					gr[k] += g * v
+				}
+				if l.Bias != nil {
+					grad[len(l.Weights)+row] += g
+				}
+			}
+			if dx != nil {
+				for k, w := range l.Row(row) {
+					dx[p*d+k] += w * g
+				}
+			}
+		}
+	}
+}
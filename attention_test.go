@@ -0,0 +1,173 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAttentionNet(causal bool) *Neural {
+	return NewNeural(&Config{
+		Inputs:     3 * 4,
+		Layout:     []int{0, 2},
+		Layers:     []LayerConfig{{Type: LayerAttention, Length: 3, Heads: 2, Causal: causal, Positional: true}},
+		Activation: []ActivationType{ActivationTanh},
+		Mode:       ModeRegression,
+		Weight:     NewNormal(0.5, 0),
+		Bias:       true,
+	})
+}
+
+func Test_Attention(t *testing.T) {
+	rand.Seed(0)
+	n := newAttentionNet(false)
+	l := n.Layers[0]
+	assert.Equal(t, LayerAttention, l.Type)
+	assert.Equal(t, 12, l.Size)
+	assert.Equal(t, 16, l.Rows())
+	assert.Equal(t, 4, l.RowSize())
+	assert.Equal(t, 16*4+16, l.NumWeights())
+	assert.Equal(t, 3, n.Window())
+
+	// Without query and key weights every position attends uniformly, so
+	// the context is the mean of the values
+	for r := 0; r < 8; r++ {
+		for k := range l.Row(r) {
+			l.Row(r)[k] = 0
+		}
+		l.Bias[r] = 0
+	}
+	l.Positional = false
+	input := make([]float32, 12)
+	for k := range input {
+		input[k] = rand.Float32()*2 - 1
+	}
+	a := newAttention(l)
+	l.attend(a, input)
+	for p := 0; p < 3; p++ {
+		for k := 0; k < 4; k++ {
+			mean := (a.v[k] + a.v[4+k] + a.v[8+k]) / 3
+			assert.InDelta(t, mean, a.ctx[p*4+k], 1e-6)
+		}
+	}
+	for _, w := range a.weights {
+		assert.InDelta(t, 1./3, w, 1e-6)
+	}
+
+	l.Causal = true
+	l.attend(a, input)
+	for k := 0; k < 4; k++ {
+		assert.InDelta(t, a.v[k], a.ctx[k], 1e-6)
+		assert.InDelta(t, (a.v[k]+a.v[4+k])/2, a.ctx[4+k], 1e-6)
+	}
+}
+
+func Test_AttentionCausal(t *testing.T) {
+	rand.Seed(0)
+	for _, causal := range []bool{false, true} {
+		n := newAttentionNet(causal)
+		input := make([]float32, 12)
+		for k := range input {
+			input[k] = rand.Float32()*2 - 1
+		}
+		s := n.NewState()
+		assert.NoError(t, n.ForwardState(s, input, false))
+		before := append([]float32{}, s.Values[0]...)
+
+		// Only the last position sees a change of its own input
+		input[10] += 1
+		assert.NoError(t, n.ForwardState(s, input, false))
+		if causal {
+			assert.Equal(t, before[:8], s.Values[0][:8])
+		} else {
+			assert.NotEqual(t, before[:8], s.Values[0][:8])
+		}
+		assert.NotEqual(t, before[8:], s.Values[0][8:])
+	}
+}
+
+func Test_AttentionBackward(t *testing.T) {
+	rand.Seed(0)
+	for _, causal := range []bool{false, true} {
+		l := newAttentionNet(causal).Layers[0]
+		input, deltas := make([]float32, 12), make([]float32, 12)
+		for k := range input {
+			input[k], deltas[k] = rand.Float32()*2-1, rand.Float32()*2-1
+		}
+		grad, dx := make([]float32, l.NumWeights()), make([]float32, 12)
+		l.Backward(input, deltas, grad, dx)
+
+		// loss is the dot product of the pre-activations with deltas
+		loss := func() float32 {
+			j := newLayerJob(l, make([]float32, 12))
+			l.A = ActivationLinear
+			j.fire(Sequential{}, input, false)
+			l.A = ActivationTanh
+			return Dot(j.out, deltas)
+		}
+		const eps = 1e-2
+		for k := range l.Weights {
+			assert.InDelta(t, NumericGradient(loss, &l.Weights[k], eps), grad[k], 5e-3, "weight %d", k)
+		}
+		for k := range l.Bias {
+			assert.InDelta(t, NumericGradient(loss, &l.Bias[k], eps), grad[len(l.Weights)+k], 5e-3, "bias %d", k)
+		}
+		for k := range input {
+			assert.InDelta(t, NumericGradient(loss, &input[k], eps), dx[k], 5e-3, "input %d", k)
+		}
+	}
+}
+
+func Test_AttentionInvalid(t *testing.T) {
+	config := func(inputs int, spec LayerConfig, layers ...LayerConfig) *Config {
+		layout := make([]int, len(layers)+2)
+		for i := range layout {
+			layout[i] = 2
+		}
+		return &Config{
+			Inputs: inputs,
+			Layout: layout,
+			Layers: append([]LayerConfig{spec}, layers...),
+			Mode:   ModeRegression,
+		}
+	}
+
+	assert.NotPanics(t, func() { NewNeural(config(12, LayerConfig{Type: LayerAttention, Length: 3, Heads: 4})) })
+	assert.Panics(t, func() { NewNeural(config(12, LayerConfig{Type: LayerAttention, Length: 5})) })
+	assert.Panics(t, func() { NewNeural(config(12, LayerConfig{Type: LayerAttention, Length: 3, Heads: 3})) })
+	assert.Panics(t, func() {
+		NewNeural(config(12, LayerConfig{Type: LayerAttention, Length: 3}, LayerConfig{Type: LayerGRU}))
+	})
+}
+
+func Test_AttentionSequence(t *testing.T) {
+	rand.Seed(0)
+	assert.Equal(t, []float32{0, 0, 1, 2, 3, 4}, WindowInput([][]float32{{1, 2}, {3, 4}}, 3))
+	assert.Equal(t, []float32{3, 4, 5, 6}, WindowInput([][]float32{{1, 2}, {3, 4}, {5, 6}}, 2))
+
+	n := newAttentionNet(true)
+	steps := [][]float32{{0.1, 0.2, 0.3, 0.4}, {-0.5, 0.6, -0.7, 0.8}}
+	out, err := n.PredictSequence(steps)
+	assert.NoError(t, err)
+	assert.Equal(t, n.Predict(WindowInput(steps[:1], 3)), out[0])
+	assert.Equal(t, n.Predict(WindowInput(steps, 3)), out[1])
+}
+
+func Test_MarshalAttention(t *testing.T) {
+	rand.Seed(0)
+	n := newAttentionNet(true)
+	dump, err := n.Marshal()
+	assert.Nil(t, err)
+	restored, err := Unmarshal(dump)
+	assert.Nil(t, err)
+	assert.Equal(t, n.Weights(), restored.Weights())
+	assert.Equal(t, n.Layers[0].Heads, restored.Layers[0].Heads)
+	assert.True(t, restored.Layers[0].Causal && restored.Layers[0].Positional)
+
+	input := make([]float32, 12)
+	for k := range input {
+		input[k] = rand.Float32()
+	}
+	assert.Equal(t, n.Predict(input), restored.Predict(input))
+}
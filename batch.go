@@ -0,0 +1,96 @@
+package deep
+
+import "fmt"
+
+// PredictBatch computes a forward pass for a mini-batch of inputs, one layer
+// at a time, and returns a prediction for each input. Recurrent networks
+// predict each input on its own, as a sequence of one step, and so do
+// networks with skip connections.
+func (n *Neural) PredictBatch(inputs [][]float32) ([][]float32, error) {
+	if n.Config.Precision == PrecisionFloat64 {
+		inputs64 := make([][]float64, len(inputs))
+		for i, input := range inputs {
+			inputs64[i] = toFloat64(input)
+		}
+		out64, err := n.PredictBatch64(inputs64)
+		if err != nil {
+			return nil, err
+		}
+		out := make([][]float32, len(out64))
+		for i, o := range out64 {
+			out[i] = toFloat32(o)
+		}
+		return out, nil
+	}
+
+	if n.Recurrent() || n.Skips() {
+		out := make([][]float32, len(inputs))
+		for i, input := range inputs {
+			if len(input) != n.Config.Inputs {
+				return nil, fmt.Errorf("Invalid input dimension at %d - expected: %d got: %d", i, n.Config.Inputs, len(input))
+			}
+			out[i] = n.Predict(input)
+		}
+		return out, nil
+	}
+
+	x := make([]float32, len(inputs)*n.Config.Inputs)
+	for i, input := range inputs {
+		if len(input) != n.Config.Inputs {
+			return nil, fmt.Errorf("Invalid input dimension at %d - expected: %d got: %d", i, n.Config.Inputs, len(input))
+		}
+		copy(x[i*n.Config.Inputs:], input)
+	}
+
+	for _, l := range n.Layers {
+		y := make([]float32, len(inputs)*l.Size)
+		l.fireBatch(n.Config.Executor, x, y, len(inputs))
+		x = y
+	}
+
+	size := n.Layers[len(n.Layers)-1].Size
+	out := make([][]float32, len(inputs))
+	for i := range out {
+		out[i] = x[i*size : (i+1)*size : (i+1)*size]
+	}
+	return out, nil
+}
+
+// fireBatch computes the activations of a batch of inputs, stored as a
+// batch x l.Inputs row-major matrix, into a batch x l.Size matrix, splitting
+// the batch into ranges of rows
+func (l *Layer) fireBatch(exec Executor, in, out []float32, batch int) {
+	if l.Type != LayerDense {
+		units, cost := l.work()
+		exec.Run(batch, units*cost, func(from, to int) {
+			j := newLayerJob(l, nil)
+			for b := from; b < to; b++ {
+				j.out = out[b*l.Size : (b+1)*l.Size]
+				j.fire(Sequential{}, in[b*l.Inputs:(b+1)*l.Inputs], false)
+			}
+		})
+		return
+	}
+
+	act := l.activation()
+	exec.Run(batch, l.Size*l.Inputs, func(from, to int) {
+		l.fireRows(act, in[from*l.Inputs:to*l.Inputs], out[from*l.Size:to*l.Size], to-from)
+	})
+}
+
+func (l *Layer) fireRows(act Differentiable, in, out []float32, batch int) {
+	MulT(out, in, l.Weights, batch, l.Size, l.Inputs)
+
+	for b := 0; b < batch; b++ {
+		row := out[b*l.Size : (b+1)*l.Size]
+		for j := range row {
+			if l.Bias != nil {
+				row[j] += l.Bias[j]
+			}
+			row[j] = l.activate(act, j, row[j], false)
+		}
+		if l.A == ActivationSoftmax {
+			SoftmaxTo(row, row)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package deep
+
+import (
+	"errors"
+	"fmt"
+
+	math "github.com/chewxy/math32"
+)
+
+// NewBatchNorm creates a new batch normalization layer, which normalizes
+// each of its inputs to zero mean and unit variance before scaling it by a
+// learned gain and shifting it by a learned bias. Training on mini-batches
+// normalizes with the statistics of the batch and folds them into running
+// statistics with the given momentum, which inference normalizes with.
+// Momentum and epsilon default to 0.9 and 1e-5 if unset.
+func NewBatchNorm(inputs int, momentum, epsilon float32, activation ActivationType) *Layer {
+	if momentum == 0 {
+		momentum = 0.9
+	}
+	if epsilon == 0 {
+		epsilon = 1e-5
+	}
+	l := &Layer{
+		A:        activation,
+		Type:     LayerBatchNorm,
+		Inputs:   inputs,
+		Size:     inputs,
+		Weights:  make([]float32, inputs),
+		Bias:     make([]float32, inputs),
+		Value:    make([]float32, inputs),
+		Mean:     make([]float32, inputs),
+		Variance: make([]float32, inputs),
+		Momentum: momentum,
+		Epsilon:  epsilon,
+	}
+	for k := range l.Weights {
+		l.Weights[k], l.Variance[k] = 1, 1
+	}
+	return l
+}
+
+// BatchNormalized reports whether n has batch normalization layers
+func (n *Neural) BatchNormalized() bool {
+	for _, l := range n.Layers {
+		if l.Type == LayerBatchNorm {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize returns the output of unit k for input x, given the mean and
+// inverse standard deviation it is normalized with
+func (l *Layer) normalize(k int, x, mean, inv float32) float32 {
+	return l.Weights[k]*(x-mean)*inv + l.Bias[k]
+}
+
+// fireNorm normalizes units [from, to) with the running statistics
+func (j *layerJob) fireNorm(from, to int) {
+	l := j.l
+	for k := from; k < to; k++ {
+		inv := 1 / math.Sqrt(l.Variance[k]+l.Epsilon)
+		j.out[k] = j.activate(k, l.normalize(k, j.in[k], l.Mean[k], inv))
+	}
+}
+
+// backwardNorm backpropagates through the running statistics, which are
+// constant with respect to the input
+func (l *Layer) backwardNorm(input, deltas, grad, dx []float32) {
+	for k, d := range deltas {
+		inv := 1 / math.Sqrt(l.Variance[k]+l.Epsilon)
+		if grad != nil {
+			grad[k] += d * (input[k] - l.Mean[k]) * inv
+			grad[len(l.Weights)+k] += d
+		}
+		if dx != nil {
+			dx[k] += d * l.Weights[k] * inv
+		}
+	}
+}
+
+// batchStatistics returns the mean and biased variance of unit k over a
+// batch of inputs
+func batchStatistics(inputs [][]float32, k int) (mean, variance float32) {
+	for _, x := range inputs {
+		mean += x[k]
+	}
+	mean /= float32(len(inputs))
+	for _, x := range inputs {
+		variance += (x[k] - mean) * (x[k] - mean)
+	}
+	return mean, variance / float32(len(inputs))
+}
+
+// fireBatchNorm normalizes a batch of inputs of layer i with the statistics
+// of the batch into states, and folds those into the running statistics
+func (l *Layer) fireBatchNorm(states []*State, i int, inputs [][]float32) {
+	act, batch := l.activation(), float32(len(inputs))
+	for k := 0; k < l.Size; k++ {
+		mean, variance := batchStatistics(inputs, k)
+		inv := 1 / math.Sqrt(variance+l.Epsilon)
+		for b, s := range states {
+			v := l.normalize(k, inputs[b][k], mean, inv)
+			for _, r := range s.jobs[i].residual {
+				v += r[k]
+			}
+			s.Sums[i][k] = v
+			s.Values[i][k] = l.activate(act, k, v, true)
+		}
+
+		if batch > 1 {
+			variance *= batch / (batch - 1)
+		}
+		l.Mean[k] = l.Momentum*l.Mean[k] + (1-l.Momentum)*mean
+		l.Variance[k] = l.Momentum*l.Variance[k] + (1-l.Momentum)*variance
+	}
+	if l.A == ActivationSoftmax {
+		for _, s := range states {
+			SoftmaxTo(s.Values[i], s.Values[i])
+		}
+	}
+}
+
+// ForwardBatch computes a forward pass of a mini-batch of inputs, one into
+// each of states, one layer at a time. When training, batch normalization
+// layers normalize with the statistics of the batch and update their
+// running statistics, which makes ForwardBatch unsafe for concurrent use.
+func (n *Neural) ForwardBatch(states []*State, inputs [][]float32, training bool) error {
+	return n.ForwardBatchInto(make([][]float32, len(inputs)), states, inputs, training)
+}
+
+// ForwardBatchInto is ForwardBatch gathering the inputs of batch
+// normalization layers into buf, which must hold a row per input, so that
+// trainers can reuse it across batches
+func (n *Neural) ForwardBatchInto(buf [][]float32, states []*State, inputs [][]float32, training bool) error {
+	if n.Config.Precision == PrecisionFloat64 {
+		return errors.New("Invalid precision - batch forward passes support float32 only")
+	}
+	if len(states) != len(inputs) {
+		return fmt.Errorf("Invalid number of states - expected: %d got: %d", len(inputs), len(states))
+	}
+	if len(buf) < len(inputs) {
+		return fmt.Errorf("Invalid batch buffer - expected: %d rows got: %d", len(inputs), len(buf))
+	}
+	for b, input := range inputs {
+		if len(input) != n.Config.Inputs {
+			return fmt.Errorf("Invalid input dimension at %d - expected: %d got: %d", b, n.Config.Inputs, len(input))
+		}
+	}
+
+	for i, l := range n.Layers {
+		if !training || l.Type != LayerBatchNorm {
+			units, cost := l.work()
+			n.Config.Executor.Run(len(states), units*cost, func(from, to int) {
+				for b := from; b < to; b++ {
+					n.fireLayer(Sequential{}, states[b], i, inputs[b], training)
+				}
+			})
+			continue
+		}
+
+		x := buf[:len(inputs)]
+		for b, s := range states {
+			x[b] = n.merge(s, i, inputs[b])
+		}
+		l.fireBatchNorm(states, i, x)
+		for _, s := range states {
+			if s.masks[i] != nil {
+				s.drop(i, n.Config.Dropout[i], s.Values[i])
+			}
+		}
+	}
+	return nil
+}
+
+// BackwardBatch propagates the deltas of a mini-batch back through l given
+// the inputs of its forward pass, as Backward does for a single example.
+// Batch normalization layers account for the batch statistics they were
+// trained with, which couple the examples of the batch.
+func (l *Layer) BackwardBatch(inputs, deltas [][]float32, grad []float32, dx [][]float32) {
+	if l.Type != LayerBatchNorm {
+		for b, input := range inputs {
+			var d []float32
+			if dx != nil {
+				d = dx[b]
+			}
+			l.Backward(input, deltas[b], grad, d)
+		}
+		return
+	}
+
+	batch := float32(len(inputs))
+	for k := 0; k < l.Size; k++ {
+		mean, variance := batchStatistics(inputs, k)
+		inv := 1 / math.Sqrt(variance+l.Epsilon)
+		var sum, dot float32
+		for b, x := range inputs {
+			sum += deltas[b][k]
+			dot += deltas[b][k] * (x[k] - mean) * inv
+		}
+		if grad != nil {
+			grad[k] += dot
+			grad[len(l.Weights)+k] += sum
+		}
+		if dx == nil {
+			continue
+		}
+		scale := l.Weights[k] * inv / batch
+		for b, x := range inputs {
+			dx[b][k] += scale * (batch*deltas[b][k] - sum - (x[k]-mean)*inv*dot)
+		}
+	}
+}
+
+// Statistics returns the running mean and variance of each batch
+// normalization layer, and nil for other layers or if n has none
+func (n Neural) Statistics() [][][]float32 {
+	if !n.BatchNormalized() {
+		return nil
+	}
+	stats := make([][][]float32, len(n.Layers))
+	for i, l := range n.Layers {
+		if l.Type == LayerBatchNorm {
+			stats[i] = [][]float32{l.Mean, l.Variance}
+		}
+	}
+	return stats
+}
+
+// ApplyStatistics sets the running mean and variance of each batch
+// normalization layer from a slice laid out as returned by Statistics
+func (n *Neural) ApplyStatistics(stats [][][]float32) {
+	for i, l := range n.Layers {
+		if l.Type == LayerBatchNorm && i < len(stats) && stats[i] != nil {
+			copy(l.Mean, stats[i][0])
+			copy(l.Variance, stats[i][1])
+		}
+	}
+}
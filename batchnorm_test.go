@@ -0,0 +1,133 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	math "github.com/chewxy/math32"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBatchNormNet() *Neural {
+	return NewNeural(&Config{
+		Inputs:     2,
+		Layout:     []int{3, 0, 1},
+		Layers:     []LayerConfig{{}, {Type: LayerBatchNorm, Momentum: 0.8}},
+		Activation: []ActivationType{ActivationLinear, ActivationTanh},
+		Mode:       ModeRegression,
+		Weight:     NewNormal(0.5, 0),
+		Bias:       true,
+	})
+}
+
+func Test_BatchNorm(t *testing.T) {
+	rand.Seed(0)
+	n := newBatchNormNet()
+	l := n.Layers[1]
+	assert.Equal(t, LayerBatchNorm, l.Type)
+	assert.Equal(t, 3, l.Size)
+	assert.Equal(t, []float32{1, 1, 1}, l.Weights)
+	assert.Equal(t, []float32{0, 0, 0}, l.Bias)
+	assert.Equal(t, 6, l.NumWeights())
+
+	inputs := [][]float32{{1, 2}, {-1, 0.5}, {0.3, -2}, {2, 1}}
+	states := make([]*State, len(inputs))
+	for i := range states {
+		states[i] = n.NewState()
+	}
+	assert.NoError(t, n.ForwardBatch(states, inputs, true))
+
+	// With unit gain and zero bias, the batch is normalized before tanh
+	for k := 0; k < 3; k++ {
+		var mean, variance, input float32
+		for _, s := range states {
+			x := math.Atanh(s.Values[1][k])
+			mean += x / 4
+			variance += x * x / 4
+			input += s.Values[0][k] / 4
+		}
+		assert.InDelta(t, 0, mean, 1e-4)
+		assert.InDelta(t, 1, variance, 1e-2)
+		assert.InDelta(t, 0.2*input, l.Mean[k], 1e-5)
+	}
+
+	// Inference normalizes with the running statistics
+	s := n.NewState()
+	assert.NoError(t, n.ForwardState(s, inputs[0], false))
+	for k := 0; k < 3; k++ {
+		x := (s.Values[0][k] - l.Mean[k]) / math.Sqrt(l.Variance[k]+l.Epsilon)
+		assert.InDelta(t, math.Tanh(x), s.Values[1][k], 1e-6)
+	}
+	assert.Equal(t, s.Output(), n.Predict(inputs[0]))
+
+	assert.Error(t, n.ForwardBatch(states[:2], inputs, true))
+	assert.Error(t, n.ForwardBatchInto(make([][]float32, 2), states, inputs, true))
+}
+
+func Test_BatchNormBackward(t *testing.T) {
+	rand.Seed(0)
+	l := NewBatchNorm(3, 0, 0, ActivationLinear)
+	for k := range l.Weights {
+		l.Weights[k], l.Bias[k] = rand.Float32()+0.5, rand.Float32()-0.5
+	}
+
+	inputs := make([][]float32, 5)
+	deltas := make([][]float32, len(inputs))
+	for i := range inputs {
+		inputs[i] = []float32{rand.Float32() * 2, rand.Float32() - 1, rand.Float32() * 4}
+		deltas[i] = []float32{rand.Float32() - 0.5, rand.Float32() - 0.5, rand.Float32() - 0.5}
+	}
+	states := make([]*State, len(inputs))
+	n := &Neural{Layers: []*Layer{l}, Config: &Config{Inputs: 3, Executor: Sequential{}}, seeds: newSeeder(0)}
+	for i := range states {
+		states[i] = n.NewState()
+	}
+
+	// loss = sum(deltas * y) has the given deltas as output gradients
+	loss := func() float32 {
+		n.ForwardBatch(states, inputs, true)
+		var sum float32
+		for i, s := range states {
+			sum += Dot(deltas[i], s.Values[0])
+		}
+		return sum
+	}
+
+	grad := make([]float32, l.NumWeights())
+	dx := make([][]float32, len(inputs))
+	for i := range dx {
+		dx[i] = make([]float32, 3)
+	}
+	l.BackwardBatch(inputs, deltas, grad, dx)
+
+	const eps = 1e-2
+	for k := range l.Weights {
+		assert.InDelta(t, NumericGradient(loss, &l.Weights[k], eps), grad[k], 2e-3)
+		assert.InDelta(t, NumericGradient(loss, &l.Bias[k], eps), grad[len(l.Weights)+k], 2e-3)
+	}
+	for i := range inputs {
+		for k := range inputs[i] {
+			assert.InDelta(t, NumericGradient(loss, &inputs[i][k], eps), dx[i][k], 2e-3)
+		}
+	}
+}
+
+func Test_MarshalBatchNorm(t *testing.T) {
+	rand.Seed(0)
+	n := newBatchNormNet()
+	l := n.Layers[1]
+	for k := range l.Mean {
+		l.Mean[k], l.Variance[k], l.Weights[k] = rand.Float32(), rand.Float32()+0.5, rand.Float32()
+	}
+
+	dump, err := n.Marshal()
+	assert.Nil(t, err)
+	restored, err := Unmarshal(dump)
+	assert.Nil(t, err)
+	assert.Equal(t, n.Weights(), restored.Weights())
+	assert.Equal(t, n.Statistics(), restored.Statistics())
+	assert.Equal(t, float32(0.8), restored.Layers[1].Momentum)
+	assert.Equal(t, n.Predict([]float32{0.5, -0.5}), restored.Predict([]float32{0.5, -0.5}))
+
+	assert.Nil(t, NewNeural(&Config{Inputs: 2, Layout: []int{1}}).Dump().Statistics)
+}
@@ -0,0 +1,132 @@
+package deep
+
+import "fmt"
+
+// Shape is the spatial shape of a layer input or output. Values are laid out
+// channel by channel, each channel as a row-major Height x Width image.
+type Shape struct {
+	Height   int
+	Width    int
+	Channels int
+}
+
+// Len returns the number of values of the shape
+func (s Shape) Len() int {
+	return s.Height * s.Width * s.Channels
+}
+
+// checkWindow panics unless the kernel, stride and padding of layer i
+// declared in spec slide at least once over the padded input shape in
+func checkWindow(i int, spec LayerConfig, in Shape) {
+	if spec.Kernel < 1 || spec.Stride < 0 || spec.Padding < 0 {
+		panic(fmt.Sprintf("Invalid layer %d - expected a positive kernel and non-negative stride and padding, got: %d, %d and %d", i, spec.Kernel, spec.Stride, spec.Padding))
+	}
+	if h, w := in.Height+2*spec.Padding, in.Width+2*spec.Padding; spec.Kernel > h || spec.Kernel > w {
+		panic(fmt.Sprintf("Invalid layer %d - kernel %d exceeds the padded %dx%d input", i, spec.Kernel, h, w))
+	}
+}
+
+// NewConv2D creates a new 2D convolution layer with the given number of
+// filters, each a kernel x kernel window over all input channels, connected
+// through zero-initialized weights
+func NewConv2D(in Shape, filters, kernel, stride, padding int, activation ActivationType) *Layer {
+	if stride < 1 {
+		stride = 1
+	}
+	out := Shape{
+		Height:   (in.Height+2*padding-kernel)/stride + 1,
+		Width:    (in.Width+2*padding-kernel)/stride + 1,
+		Channels: filters,
+	}
+	return &Layer{
+		A:       activation,
+		Type:    LayerConv2D,
+		Inputs:  in.Len(),
+		Size:    out.Len(),
+		Weights: make([]float32, filters*in.Channels*kernel*kernel),
+		Value:   make([]float32, out.Len()),
+		In:      in,
+		Out:     out,
+		Kernel:  kernel,
+		Stride:  stride,
+		Padding: padding,
+	}
+}
+
+// fireConv computes the feature maps of filters [from, to)
+func (j *layerJob) fireConv(from, to int) {
+	l := j.l
+	area := l.In.Height * l.In.Width
+	for f := from; f < to; f++ {
+		kernel := l.Row(f)
+		out := j.out[f*l.Out.Height*l.Out.Width:]
+		for oy := 0; oy < l.Out.Height; oy++ {
+			for ox := 0; ox < l.Out.Width; ox++ {
+				var sum float32
+				if l.Bias != nil {
+					sum = l.Bias[f]
+				}
+				for c := 0; c < l.In.Channels; c++ {
+					for ky := 0; ky < l.Kernel; ky++ {
+						y := oy*l.Stride - l.Padding + ky
+						if y < 0 || y >= l.In.Height {
+							continue
+						}
+						in := j.in[c*area+y*l.In.Width:]
+						w := kernel[(c*l.Kernel+ky)*l.Kernel:]
+						for kx := 0; kx < l.Kernel; kx++ {
+							if x := ox*l.Stride - l.Padding + kx; x >= 0 && x < l.In.Width {
+								sum += w[kx] * in[x]
+							}
+						}
+					}
+				}
+				out[oy*l.Out.Width+ox] = j.activate(f*l.Out.Height*l.Out.Width+oy*l.Out.Width+ox, sum)
+			}
+		}
+	}
+}
+
+func (l *Layer) backwardConv(input, deltas, grad, dx []float32) {
+	area := l.In.Height * l.In.Width
+	for f := 0; f < l.Out.Channels; f++ {
+		kernel := l.Row(f)
+		var g []float32
+		if grad != nil {
+			g = grad[f*len(kernel) : (f+1)*len(kernel)]
+		}
+		for oy := 0; oy < l.Out.Height; oy++ {
+			for ox := 0; ox < l.Out.Width; ox++ {
+				d := deltas[(f*l.Out.Height+oy)*l.Out.Width+ox]
+				if d == 0 {
+					continue
+				}
+				if g != nil && l.Bias != nil {
+					grad[len(l.Weights)+f] += d
+				}
+				for c := 0; c < l.In.Channels; c++ {
+					for ky := 0; ky < l.Kernel; ky++ {
+						y := oy*l.Stride - l.Padding + ky
+						if y < 0 || y >= l.In.Height {
+							continue
+						}
+						k := (c*l.Kernel + ky) * l.Kernel
+						for kx := 0; kx < l.Kernel; kx++ {
+							x := ox*l.Stride - l.Padding + kx
+							if x < 0 || x >= l.In.Width {
+								continue
+							}
+							in := c*area + y*l.In.Width + x
+							if g != nil {
+								g[k+kx] += d * input[in]
+							}
+							if dx != nil {
+								dx[in] += kernel[k+kx] * d
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
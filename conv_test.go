@@ -0,0 +1,144 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Conv2D(t *testing.T) {
+	n := NewNeural(&Config{
+		Inputs:     9,
+		Shape:      &Shape{Height: 3, Width: 3, Channels: 1},
+		Layout:     []int{1, 1},
+		Layers:     []LayerConfig{{Type: LayerConv2D, Kernel: 2}},
+		Activation: []ActivationType{ActivationLinear},
+		Mode:       ModeRegression,
+		Weight:     NewUniform(0.5, 0),
+		Bias:       true,
+	})
+	l := n.Layers[0]
+	assert.Equal(t, Shape{Height: 2, Width: 2, Channels: 1}, l.Out)
+	assert.Equal(t, 4, l.Size)
+	assert.Equal(t, 4, n.Layers[1].Inputs)
+	assert.Equal(t, 5+4, n.NumWeights())
+
+	l.SetNeuron(0, []float32{1, 2, 3, 4, 0.5})
+	n.Forward([]float32{
+		1, 0, 2,
+		0, 1, 0,
+		3, 0, 1,
+	}, false)
+	assert.Equal(t, []float32{1 + 4 + 0.5, 4 + 3 + 0.5, 2 + 9 + 0.5, 1 + 4 + 0.5}, l.Value)
+}
+
+func Test_Conv2DPadding(t *testing.T) {
+	l := NewConv2D(Shape{Height: 5, Width: 4, Channels: 2}, 3, 3, 2, 1, ActivationLinear)
+	assert.Equal(t, Shape{Height: 3, Width: 2, Channels: 3}, l.Out)
+	assert.Equal(t, 3, l.Rows())
+	assert.Equal(t, 18, l.RowSize())
+	assert.Equal(t, 54, len(l.Weights))
+}
+
+func Test_Conv2DBackward(t *testing.T) {
+	rand.Seed(0)
+	l := NewConv2D(Shape{Height: 4, Width: 4, Channels: 2}, 2, 3, 1, 1, ActivationLinear)
+	l.ApplyBias(NewNormal(1, 0))
+	for i := range l.Weights {
+		l.Weights[i] = rand.Float32() - 0.5
+	}
+	input := make([]float32, l.Inputs)
+	for i := range input {
+		input[i] = rand.Float32()
+	}
+	deltas := make([]float32, l.Size)
+	for i := range deltas {
+		deltas[i] = rand.Float32() - 0.5
+	}
+
+	// loss = sum(deltas * out) has the given deltas as output gradients
+	loss := func() float32 {
+		out := make([]float32, l.Size)
+		newLayerJob(l, out).fire(Sequential{}, input, false)
+		return Dot(out, deltas)
+	}
+
+	grad := make([]float32, l.NumWeights())
+	dx := make([]float32, l.Inputs)
+	l.Backward(input, deltas, grad, dx)
+
+	const eps = 1e-2
+	for k := range l.Weights {
+		assert.InDelta(t, NumericGradient(loss, &l.Weights[k], eps), grad[k], 1e-3)
+	}
+	for k := range l.Bias {
+		assert.InDelta(t, NumericGradient(loss, &l.Bias[k], eps), grad[len(l.Weights)+k], 1e-3)
+	}
+	for k := range input {
+		assert.InDelta(t, NumericGradient(loss, &input[k], eps), dx[k], 1e-3)
+	}
+}
+
+func Test_MarshalConv2D(t *testing.T) {
+	n := NewNeural(&Config{
+		Inputs:     32,
+		Shape:      &Shape{Height: 4, Width: 4, Channels: 2},
+		Layout:     []int{3, 2},
+		Layers:     []LayerConfig{{Type: LayerConv2D, Kernel: 3, Stride: 1, Padding: 1}},
+		Activation: []ActivationType{ActivationReLU},
+		Mode:       ModeMultiClass,
+		Weight:     NewNormal(0.5, 0),
+		Bias:       true,
+	})
+	input := make([]float32, 32)
+	for i := range input {
+		input[i] = float32(i) / 32
+	}
+
+	dump, err := n.Marshal()
+	assert.Nil(t, err)
+	restored, err := Unmarshal(dump)
+	assert.Nil(t, err)
+	assert.Equal(t, n.Weights(), restored.Weights())
+	assert.Equal(t, n.Predict(input), restored.Predict(input))
+
+	batch, err := n.PredictBatch([][]float32{input, input})
+	assert.Nil(t, err)
+	assert.Equal(t, n.Predict(input), batch[1])
+}
+
+func Test_Conv2DInvalid(t *testing.T) {
+	assert.Panics(t, func() {
+		NewNeural(&Config{Inputs: 4, Layout: []int{1}, Layers: []LayerConfig{{Type: LayerConv2D, Kernel: 1}}})
+	})
+	assert.Panics(t, func() {
+		NewNeural(&Config{Inputs: 4, Shape: &Shape{Height: 3, Width: 1, Channels: 1}, Layout: []int{1}})
+	})
+	assert.Panics(t, func() {
+		NewNeural(&Config{
+			Inputs:    4,
+			Shape:     &Shape{Height: 2, Width: 2, Channels: 1},
+			Layout:    []int{1},
+			Layers:    []LayerConfig{{Type: LayerConv2D, Kernel: 1}},
+			Precision: PrecisionFloat64,
+		})
+	})
+
+	conv := func(kernel, stride, padding int) func() {
+		return func() {
+			NewNeural(&Config{
+				Inputs: 4,
+				Shape:  &Shape{Height: 2, Width: 2, Channels: 1},
+				Layout: []int{1},
+				Layers: []LayerConfig{{Type: LayerConv2D, Kernel: kernel, Stride: stride, Padding: padding}},
+			})
+		}
+	}
+	assert.NotPanics(t, conv(2, 1, 0))
+	assert.NotPanics(t, conv(4, 1, 1))
+	assert.Panics(t, conv(5, 1, 0))
+	assert.Panics(t, conv(0, 1, 0))
+	assert.Panics(t, conv(2, -1, 0))
+	assert.Panics(t, conv(2, 1, -1))
+}
@@ -0,0 +1,98 @@
+package deep
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// dropout returns the dropout rate of layer i of c
+func (c *Config) dropout(i int) float32 {
+	if i < len(c.Dropout) {
+		return c.Dropout[i]
+	}
+	return 0
+}
+
+// checkDropout panics if the dropout rate of layer i of c is invalid
+func checkDropout(c *Config, i int, l *Layer) {
+	p := c.dropout(i)
+	if p == 0 {
+		return
+	}
+	if p < 0 || p >= 1 {
+		panic(fmt.Sprintf("Invalid dropout rate of layer %d - expected: [0, 1) got: %v", i, p))
+	}
+	if i == len(c.Layout)-1 {
+		panic(fmt.Sprintf("Invalid layer %d - the output layer cannot drop out", i))
+	}
+	if l.Recurrent() {
+		panic(fmt.Sprintf("Invalid layer %d - %s layers cannot drop out", i, l.Type))
+	}
+}
+
+// seeder draws the seeds of the dropout masks of each State of a network
+type seeder struct {
+	sync.Mutex
+	rng *rand.Rand
+}
+
+func newSeeder(seed int64) *seeder {
+	return &seeder{rng: rand.New(rand.NewSource(seed))}
+}
+
+// seed draws the seed of the dropout masks of a new State
+func (n *Neural) seed() int64 {
+	n.seeds.Lock()
+	defer n.seeds.Unlock()
+	return n.seeds.rng.Int63()
+}
+
+// Mask returns the dropout mask of layer i from the most recent training pass
+// through s, or nil if the layer has no dropout. Kept units hold the inverted
+// dropout scale 1/(1-p) and dropped units hold 0, so the activations in
+// Values are the unmasked activations multiplied by the mask.
+func (s *State) Mask(i int) []float32 {
+	return s.masks[i]
+}
+
+// drop draws a new dropout mask for layer i with rate p and applies it to out
+func (s *State) drop(i int, p float32, out []float32) {
+	mask, scale := s.masks[i], 1/(1-p)
+	for k := range mask {
+		mask[k] = 0
+		if s.rng.Float32() >= p {
+			mask[k] = scale
+		}
+		out[k] *= mask[k]
+	}
+}
+
+// drop64 draws a new dropout mask for layer i with rate p and applies it to
+// the activations of a float64 layer
+func (s *State) drop64(i int, p float32, out []float64) {
+	mask, scale := s.masks[i], 1/(1-p)
+	for k := range mask {
+		mask[k] = 0
+		if s.rng.Float32() >= p {
+			mask[k] = scale
+		}
+		out[k] *= float64(mask[k])
+	}
+}
+
+// newMasks allocates the dropout masks of s for the layers of n that drop
+// out
+func (s *State) newMasks(n *Neural) {
+	s.masks = make([][]float32, len(n.Layers))
+	var any bool
+	for i, l := range n.Layers {
+		if n.Config.dropout(i) > 0 {
+			s.masks[i] = make([]float32, l.Size)
+			any = true
+		}
+	}
+	if any {
+		s.rng = rand.New(rand.NewSource(n.seed()))
+	}
+}
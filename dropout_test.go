@@ -0,0 +1,109 @@
+package deep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDropoutNet(seed int64) *Neural {
+	n := NewNeural(&Config{
+		Inputs:     3,
+		Layout:     []int{100, 2},
+		Activation: []ActivationType{ActivationSigmoid, ActivationLinear},
+		Mode:       ModeRegression,
+		Weight:     NewUniform(0.5, 0),
+		Bias:       true,
+		Dropout:    []float32{0.25},
+		Seed:       seed,
+	})
+	for _, l := range n.Layers {
+		for k := range l.Weights {
+			l.Weights[k] = float32(k%7)/7 - 0.5
+		}
+	}
+	return n
+}
+
+func Test_Dropout(t *testing.T) {
+	n := newDropoutNet(1)
+	input := []float32{0.1, -0.4, 0.7}
+	s := n.NewState()
+
+	assert.NoError(t, n.ForwardState(s, input, false))
+	plain := append([]float32{}, s.Values[0]...)
+	assert.Equal(t, n.Predict(input), s.Output())
+
+	assert.NoError(t, n.ForwardState(s, input, true))
+	mask := s.Mask(0)
+	var dropped int
+	for k, m := range mask {
+		if m == 0 {
+			dropped++
+		} else {
+			assert.InDelta(t, 1/0.75, m, 1e-6)
+		}
+		assert.InDelta(t, plain[k]*m, s.Values[0][k], 1e-6)
+	}
+	assert.True(t, dropped > 10 && dropped < 40)
+	assert.Nil(t, s.Mask(1))
+
+	// Inference is unaffected by dropout
+	assert.NoError(t, n.ForwardState(s, input, false))
+	assert.Equal(t, plain, s.Values[0])
+}
+
+func Test_DropoutSeed(t *testing.T) {
+	input := []float32{0.1, -0.4, 0.7}
+	masks := func(seed int64) [][]float32 {
+		n := newDropoutNet(seed)
+		s := n.NewState()
+		var masks [][]float32
+		for i := 0; i < 3; i++ {
+			n.ForwardState(s, input, true)
+			masks = append(masks, append([]float32{}, s.Mask(0)...))
+		}
+		return masks
+	}
+
+	assert.Equal(t, masks(7), masks(7))
+	assert.NotEqual(t, masks(7), masks(8))
+}
+
+func Test_DropoutFloat64(t *testing.T) {
+	n := NewNeural(&Config{
+		Inputs:     3,
+		Layout:     []int{100, 2},
+		Activation: []ActivationType{ActivationTanh, ActivationLinear},
+		Mode:       ModeRegression,
+		Weight:     NewUniform(0.5, 0),
+		Dropout:    []float32{0.5},
+		Precision:  PrecisionFloat64,
+	})
+	input := []float64{0.1, -0.4, 0.7}
+	s := n.NewState()
+
+	assert.NoError(t, n.ForwardState64(s, input, false))
+	plain := append([]float64{}, s.Values64[0]...)
+	assert.NoError(t, n.ForwardState64(s, input, true))
+	for k, m := range s.Mask(0) {
+		assert.InDelta(t, plain[k]*float64(m), s.Values64[0][k], 1e-12)
+	}
+}
+
+func Test_DropoutInvalid(t *testing.T) {
+	config := func(dropout []float32) *Config {
+		return &Config{
+			Inputs:     3,
+			Layout:     []int{4, 2},
+			Activation: []ActivationType{ActivationSigmoid, ActivationLinear},
+			Mode:       ModeRegression,
+			Dropout:    dropout,
+		}
+	}
+
+	assert.Panics(t, func() { NewNeural(config([]float32{1})) })
+	assert.Panics(t, func() { NewNeural(config([]float32{-0.1})) })
+	assert.Panics(t, func() { NewNeural(config([]float32{0, 0.5})) })
+	assert.NotPanics(t, func() { NewNeural(config([]float32{0.5, 0})) })
+}
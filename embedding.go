@@ -0,0 +1,136 @@
+package deep
+
+import "fmt"
+
+// Embedding maps the integer category IDs in one input column to trainable
+// vectors. IDs are read from the input as float32, so they are exact up to
+// 2^24, and IDs outside [0, Categories) map to a zero vector.
+type Embedding struct {
+	// Column of the input holding the category ID
+	Column int
+	// Number of categories, with IDs from 0 to Categories-1
+	Categories int
+	// Size of the embedding vectors
+	Size int
+}
+
+// NewEmbedding creates a new embedding layer over the given number of inputs.
+// It outputs the numeric input columns in order, followed by the vector of
+// each embedding. Vectors are zero-initialized.
+func NewEmbedding(inputs int, embeddings []Embedding) *Layer {
+	categorical := make(map[int]bool, len(embeddings))
+	size, weights := 0, 0
+	for i, e := range embeddings {
+		if e.Column < 0 || e.Column >= inputs || categorical[e.Column] {
+			panic(fmt.Sprintf("Invalid embedding %d - column %d is out of range or used twice", i, e.Column))
+		}
+		if e.Categories < 1 || e.Size < 1 {
+			panic(fmt.Sprintf("Invalid embedding %d - expected categories and size, got: %d and %d", i, e.Categories, e.Size))
+		}
+		categorical[e.Column] = true
+		size += e.Size
+		weights += e.Categories * e.Size
+	}
+
+	var numeric []int
+	for k := 0; k < inputs; k++ {
+		if !categorical[k] {
+			numeric = append(numeric, k)
+		}
+	}
+	return &Layer{
+		A:          ActivationLinear,
+		Type:       LayerEmbedding,
+		Inputs:     inputs,
+		Size:       len(numeric) + size,
+		Weights:    make([]float32, weights),
+		Value:      make([]float32, len(numeric)+size),
+		Embeddings: embeddings,
+		numeric:    numeric,
+	}
+}
+
+// id returns the category ID of input, or -1 if it is unknown
+func (e Embedding) id(input []float32) int {
+	x := input[e.Column]
+	if id := int(x); id >= 0 && id < e.Categories && float32(id) == x {
+		return id
+	}
+	return -1
+}
+
+// embeddingRange returns the range of Weights holding the vector of
+// category row j, counting the categories of all embeddings in turn
+func (l *Layer) embeddingRange(j int) (from, to int) {
+	for _, e := range l.Embeddings {
+		if j < e.Categories {
+			return from + j*e.Size, from + (j+1)*e.Size
+		}
+		j -= e.Categories
+		from += e.Categories * e.Size
+	}
+	panic(fmt.Sprintf("deep: embedding row %d out of range", j))
+}
+
+// EmbeddingRows appends the weight rows an embedding layer looks up for
+// input to rows, skipping unknown IDs
+func (l *Layer) EmbeddingRows(input []float32, rows []int) []int {
+	var first int
+	for _, e := range l.Embeddings {
+		if id := e.id(input); id >= 0 {
+			rows = append(rows, first+id)
+		}
+		first += e.Categories
+	}
+	return rows
+}
+
+// lookup calls fn with the offset in the output and the weight range of the
+// vector of each embedding, or from = -1 for unknown IDs
+func (l *Layer) lookup(input []float32, fn func(out, from, to int)) {
+	out, first := len(l.numeric), 0
+	for _, e := range l.Embeddings {
+		from := -1
+		if id := e.id(input); id >= 0 {
+			from = first + id*e.Size
+		}
+		fn(out, from, from+e.Size)
+		out += e.Size
+		first += e.Categories * e.Size
+	}
+}
+
+func (j *layerJob) fireEmbedding(from, to int) {
+	l := j.l
+	for k, c := range l.numeric {
+		j.out[k] = j.in[c]
+	}
+	l.lookup(j.in, func(out, from, to int) {
+		if from < 0 {
+			for k := out; k < out+to-from; k++ {
+				j.out[k] = 0
+			}
+			return
+		}
+		copy(j.out[out:], l.Weights[from:to])
+	})
+}
+
+func (l *Layer) backwardEmbedding(input, deltas, grad, dx []float32) {
+	if dx != nil {
+		for k, c := range l.numeric {
+			dx[c] += deltas[k]
+		}
+	}
+	if grad == nil {
+		return
+	}
+	l.lookup(input, func(out, from, to int) {
+		if from < 0 {
+			return
+		}
+		for k, d := range deltas[out : out+to-from] {
+			grad[from+k] += d
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package deep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Embedding(t *testing.T) {
+	l := NewEmbedding(4, []Embedding{{Column: 1, Categories: 3, Size: 2}, {Column: 3, Categories: 2, Size: 1}})
+	assert.Equal(t, 5, l.Size)
+	assert.Equal(t, 5, l.Rows())
+	assert.Equal(t, 8, l.NumWeights())
+	for i := range l.Weights {
+		l.Weights[i] = float32(i + 1)
+	}
+	from, to := l.RowRange(3)
+	assert.Equal(t, []int{6, 7}, []int{from, to})
+
+	fire := func(input []float32) []float32 {
+		out := make([]float32, l.Size)
+		newLayerJob(l, out).fire(Sequential{}, input, false)
+		return out
+	}
+	assert.Equal(t, []float32{0.5, -1, 3, 4, 8}, fire([]float32{0.5, 1, -1, 1}))
+	assert.Equal(t, []float32{0.5, -1, 0, 0, 7}, fire([]float32{0.5, 3, -1, 0}))
+	assert.Equal(t, []float32{0.5, -1, 0, 0, 7}, fire([]float32{0.5, 0.5, -1, 0}))
+	assert.Equal(t, []int{1, 4}, l.EmbeddingRows([]float32{0.5, 1, -1, 1}, nil))
+
+	grad, dx := make([]float32, l.NumWeights()), make([]float32, 4)
+	l.Backward([]float32{0.5, 2, -1, 1}, []float32{1, 2, 3, 4, 5}, grad, dx)
+	assert.Equal(t, []float32{0, 0, 0, 0, 3, 4, 0, 5}, grad)
+	assert.Equal(t, []float32{1, 0, 2, 0}, dx)
+
+	assert.Panics(t, func() { NewEmbedding(2, []Embedding{{Column: 2, Categories: 1, Size: 1}}) })
+	assert.Panics(t, func() { NewEmbedding(2, []Embedding{{Column: 0, Categories: 0, Size: 1}}) })
+}
+
+func Test_MarshalEmbedding(t *testing.T) {
+	n := NewNeural(&Config{
+		Inputs: 3,
+		Layout: []int{0, 4, 1},
+		Layers: []LayerConfig{{Type: LayerEmbedding, Embeddings: []Embedding{{Column: 0, Categories: 10, Size: 3}}}},
+		Mode:   ModeRegression,
+		Weight: NewNormal(0.5, 0),
+		Bias:   true,
+	})
+	assert.Equal(t, 5, n.Layers[1].Inputs)
+	assert.Nil(t, n.Layers[0].Bias)
+
+	dump, err := n.Marshal()
+	assert.Nil(t, err)
+	restored, err := Unmarshal(dump)
+	assert.Nil(t, err)
+	assert.Equal(t, n.Weights(), restored.Weights())
+	assert.Equal(t, n.Predict([]float32{7, 0.5, -0.5}), restored.Predict([]float32{7, 0.5, -0.5}))
+}
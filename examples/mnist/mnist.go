@@ -49,9 +49,8 @@ func main() {
 	neural := deep.NewNeural(&deep.Config{
 		Inputs:     len(train[0].Input),
 		Layout:     []int{50, 10},
-		Activation: deep.ActivationReLU,
+		Activation: []deep.ActivationType{deep.ActivationReLU},
 		Mode:       deep.ModeMultiClass,
-		Weight:     deep.NewNormal(0.6, 0.1), // slight positive bias helps ReLU
 		Bias:       true,
 	})
 
@@ -88,14 +87,14 @@ func toExample(in []string) training.Example {
 	if err != nil {
 		panic(err)
 	}
-	resEncoded := onehot(10, res)
+	resEncoded := onehot(10, float32(res))
 	var features []float32
 	for i := 1; i < len(in); i++ {
 		res, err := strconv.ParseFloat(in[i], 64)
 		if err != nil {
 			panic(err)
 		}
-		features = append(features, res)
+		features = append(features, float32(res))
 	}
 
 	return training.Example{
@@ -33,7 +33,7 @@ func main() {
 	neural := deep.NewNeural(&deep.Config{
 		Inputs:     len(data[0].Input),
 		Layout:     []int{8, 3},
-		Activation: deep.ActivationTanh,
+		Activation: []deep.ActivationType{deep.ActivationTanh},
 		Mode:       deep.ModeMultiClass,
 		Weight:     deep.NewNormal(1, 0),
 		Bias:       true,
@@ -72,14 +72,14 @@ func toExample(in []string) training.Example {
 	if err != nil {
 		panic(err)
 	}
-	resEncoded := onehot(3, res)
+	resEncoded := onehot(3, float32(res))
 	var features []float32
 	for i := 1; i < len(in); i++ {
 		res, err := strconv.ParseFloat(in[i], 64)
 		if err != nil {
 			panic(err)
 		}
-		features = append(features, res)
+		features = append(features, float32(res))
 	}
 
 	return training.Example{
@@ -0,0 +1,139 @@
+package deep
+
+import (
+	"runtime"
+	"sync"
+)
+
+// An Executor distributes the per-neuron work of a layer
+type Executor interface {
+	// Run calls fn over disjoint ranges [from, to) covering [0, n), where
+	// each item costs about cost multiply-adds, and returns once every call
+	// has completed
+	Run(n, cost int, fn func(from, to int))
+}
+
+// DefaultGrain is the least amount of multiply-adds worth handing to
+// another goroutine
+const DefaultGrain = 4096
+
+// chunks returns the number of ranges to split n items of the given cost into
+func chunks(n, cost, grain, max int) int {
+	c := n * cost / grain
+	if c > max {
+		c = max
+	}
+	if c > n {
+		c = n
+	}
+	if c < 1 {
+		c = 1
+	}
+	return c
+}
+
+// Sequential runs all work inline on the calling goroutine
+type Sequential struct{}
+
+// Run calls fn once over [0, n)
+func (Sequential) Run(n, cost int, fn func(from, to int)) {
+	fn(0, n)
+}
+
+// Chunked splits work into ranges of neurons, each run on its own goroutine.
+// Layers with less than Grain multiply-adds in total run inline.
+type Chunked struct {
+	// Grain is the least amount of work per range, DefaultGrain if zero
+	Grain int
+	// Chunks is the most ranges to split into, GOMAXPROCS if zero
+	Chunks int
+}
+
+// NewChunked returns a Chunked executor with default parameters
+func NewChunked() *Chunked {
+	return &Chunked{}
+}
+
+// Run calls fn over up to Chunks ranges concurrently
+func (e *Chunked) Run(n, cost int, fn func(from, to int)) {
+	c := chunks(n, cost, iparam(e.Grain, DefaultGrain), iparam(e.Chunks, runtime.GOMAXPROCS(0)))
+	if c == 1 {
+		fn(0, n)
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(c - 1)
+	for i := 1; i < c; i++ {
+		go func(from, to int) {
+			fn(from, to)
+			wg.Done()
+		}(i*n/c, (i+1)*n/c)
+	}
+	fn(0, n/c)
+	wg.Wait()
+}
+
+// WorkerPool hands ranges of neurons to a fixed set of long-lived goroutines,
+// avoiding goroutine creation on every forward pass. Layers with less than
+// Grain multiply-adds in total run inline.
+type WorkerPool struct {
+	// Grain is the least amount of work per range, DefaultGrain if zero
+	Grain int
+
+	workers int
+	tasks   chan task
+}
+
+type task struct {
+	fn       func(from, to int)
+	from, to int
+	wg       *sync.WaitGroup
+}
+
+// NewWorkerPool starts a pool of workers goroutines, GOMAXPROCS if zero
+func NewWorkerPool(workers int) *WorkerPool {
+	p := &WorkerPool{
+		workers: iparam(workers, runtime.GOMAXPROCS(0)),
+		tasks:   make(chan task),
+	}
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			for t := range p.tasks {
+				t.fn(t.from, t.to)
+				t.wg.Done()
+			}
+		}()
+	}
+	return p
+}
+
+// Run calls fn over up to one range per worker, running the first range
+// on the calling goroutine
+func (p *WorkerPool) Run(n, cost int, fn func(from, to int)) {
+	c := chunks(n, cost, iparam(p.Grain, DefaultGrain), p.workers)
+	if c == 1 {
+		fn(0, n)
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(c - 1)
+	for i := 1; i < c; i++ {
+		p.tasks <- task{fn: fn, from: i * n / c, to: (i + 1) * n / c, wg: &wg}
+	}
+	fn(0, n/c)
+	wg.Wait()
+}
+
+// Close stops the workers. The pool must not be used afterwards.
+func (p *WorkerPool) Close() {
+	close(p.tasks)
+}
+
+func iparam(val, fallback int) int {
+	if val == 0 {
+		return fallback
+	}
+	return val
+}
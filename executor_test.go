@@ -0,0 +1,115 @@
+package deep
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// perNeuron reproduces the former behaviour of firing every neuron on its
+// own goroutine
+type perNeuron struct{}
+
+func (perNeuron) Run(n, cost int, fn func(from, to int)) {
+	ch := make(chan bool, n)
+	for j := 0; j < n; j++ {
+		go func(j int) {
+			fn(j, j+1)
+			ch <- false
+		}(j)
+	}
+	for j := 0; j < n; j++ {
+		<-ch
+	}
+}
+
+func Test_Executors(t *testing.T) {
+	pool := NewWorkerPool(4)
+	defer pool.Close()
+	fine := NewWorkerPool(4)
+	fine.Grain = 1
+	defer fine.Close()
+
+	executors := []Executor{
+		Sequential{},
+		NewChunked(),
+		&Chunked{Grain: 1, Chunks: 3},
+		pool,
+		fine,
+	}
+	for _, n := range []int{1, 7, 100, 1000} {
+		for _, e := range executors {
+			seen := make([]int32, n)
+			e.Run(n, 100, func(from, to int) {
+				for i := from; i < to; i++ {
+					atomic.AddInt32(&seen[i], 1)
+				}
+			})
+			for i := range seen {
+				assert.Equal(t, int32(1), seen[i], "%T n=%d i=%d", e, n, i)
+			}
+		}
+	}
+}
+
+func Test_ExecutorPredict(t *testing.T) {
+	pool := NewWorkerPool(4)
+	pool.Grain = 1
+	defer pool.Close()
+
+	n := NewNeural(&Config{
+		Inputs:     64,
+		Layout:     []int{256, 128, 10},
+		Activation: []ActivationType{ActivationReLU, ActivationTanh},
+		Mode:       ModeMultiClass,
+		Weight:     NewNormal(0.1, 0),
+		Bias:       true,
+		Executor:   Sequential{},
+	})
+	input := make([]float32, 64)
+	for i := range input {
+		input[i] = float32(i) / 64
+	}
+	expected := n.Predict(input)
+
+	for _, e := range []Executor{&Chunked{Grain: 1}, pool} {
+		n.Config.Executor = e
+		assert.Equal(t, expected, n.Predict(input), "%T", e)
+	}
+}
+
+func Benchmark_Forward(b *testing.B) {
+	pool := NewWorkerPool(0)
+	defer pool.Close()
+
+	executors := []struct {
+		name string
+		exec Executor
+	}{
+		{"PerNeuron", perNeuron{}},
+		{"Sequential", Sequential{}},
+		{"Chunked", NewChunked()},
+		{"WorkerPool", pool},
+	}
+	for _, width := range []int{10, 50, 512} {
+		for _, e := range executors {
+			n := NewNeural(&Config{
+				Inputs:     width,
+				Layout:     []int{width, width, 10},
+				Activation: []ActivationType{ActivationReLU, ActivationReLU},
+				Mode:       ModeMultiClass,
+				Weight:     NewNormal(0.1, 0),
+				Bias:       true,
+				Executor:   e.exec,
+			})
+			input := make([]float32, width)
+			b.Run(fmt.Sprintf("%s/%d", e.name, width), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					n.Forward(input, false)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package deep
+
+import (
+	math "github.com/chewxy/math32"
+)
+
+// gradientFloor keeps RelativeError finite when both gradients are 0
+const gradientFloor = 1e-6
+
+// RelativeError returns the difference between a gradient and its numeric
+// estimate relative to their summed magnitudes, which lies in [0, 1]
+func RelativeError(gradient, numeric float32) float32 {
+	return math.Abs(gradient-numeric) / math.Max(math.Abs(gradient)+math.Abs(numeric), gradientFloor)
+}
+
+// NumericGradient returns the central finite difference of step h of loss
+// with respect to x, which it restores afterwards
+func NumericGradient(loss func() float32, x *float32, h float32) float32 {
+	v := *x
+	*x = v + h
+	plus := loss()
+	*x = v - h
+	minus := loss()
+	*x = v
+	return (plus - minus) / (2 * h)
+}
+
+// CheckActivation compares the derivative of a at each of xs with the
+// central finite difference of step h, and returns the worst relative error
+// and the x it occurs at. The derivative receives F(x) as its y, as it does
+// during training.
+func CheckActivation(a Differentiable, xs []float32, h float32) (worst, at float32) {
+	for _, x := range xs {
+		numeric := (a.F(x+h, false) - a.F(x-h, false)) / (2 * h)
+		if err := RelativeError(a.Df(x, a.F(x, false)), numeric); err > worst || math.IsNaN(err) {
+			worst, at = err, x
+		}
+	}
+	return worst, at
+}
+
+// CheckLoss compares the derivative of loss with respect to the
+// pre-activations x of an output layer with activation act, given the
+// targets ideal, with the central finite differences of step h of the loss
+// of a single example. It returns the worst relative error and the output it
+// occurs at.
+func CheckLoss(loss Loss, act ActivationType, x, ideal []float32, h float32) (worst float32, at int) {
+	a := GetActivation(act)
+	y := make([]float32, len(x))
+	activate := func() {
+		if act == ActivationSoftmax {
+			SoftmaxTo(y, x)
+			return
+		}
+		for k, v := range x {
+			y[k] = a.F(v, false)
+		}
+	}
+
+	gradient := make([]float32, len(x))
+	activate()
+	for k := range x {
+		gradient[k] = loss.Df(y[k], ideal[k], a.Df(x[k], y[k]))
+	}
+
+	objective := func() float32 {
+		activate()
+		return Objective(loss, y, ideal)
+	}
+	for k := range x {
+		numeric := NumericGradient(objective, &x[k], h)
+		if err := RelativeError(gradient[k], numeric); err > worst || math.IsNaN(err) {
+			worst, at = err, k
+		}
+	}
+	return worst, at
+}
+
+// Objective returns the loss of a single example estimate against ideal
+// that trainers minimize by following loss.Df. This is loss.F, except for
+// MeanSquared, whose Df is the gradient of half the summed squared error
+// rather than of its mean.
+func Objective(loss Loss, estimate, ideal []float32) float32 {
+	f := loss.F([][]float32{estimate}, [][]float32{ideal})
+	if _, ok := loss.(MeanSquared); ok {
+		f *= float32(len(estimate)) / 2
+	}
+	return f
+}
@@ -0,0 +1,60 @@
+package deep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wrongDerivative is a square whose derivative is off by a factor of 2
+type wrongDerivative struct{}
+
+func (a wrongDerivative) F(x float32, training bool) float32 { return x * x }
+func (a wrongDerivative) Df(x, y float32) float32            { return x }
+
+func Test_CheckActivation(t *testing.T) {
+	// Kinks, where the derivatives are one-sided, and saturated inputs, where
+	// rounding dominates the tiny derivatives, are left out
+	xs := []float32{-2.5, -1.2, -0.6, -0.1, 0.2, 0.9, 2, 2.5}
+	for act := ActivationSigmoid; act <= ActivationSwishBeta; act++ {
+		if act == ActivationCustom {
+			// Custom activations are looked up by name, not by type
+			continue
+		}
+		worst, at := CheckActivation(GetActivation(act), xs, 1e-3)
+		assert.True(t, worst < 1e-3, "activation %d at %v: %v", act, at, worst)
+	}
+
+	worst, _ := CheckActivation(wrongDerivative{}, xs, 1e-3)
+	assert.InDelta(t, 1.0/3, worst, 1e-3)
+}
+
+func Test_CheckLoss(t *testing.T) {
+	x := []float32{0.3, -1.2, 0.8}
+	for _, test := range []struct {
+		loss  Loss
+		act   ActivationType
+		ideal []float32
+	}{
+		{CrossEntropy{}, ActivationSoftmax, []float32{0, 1, 0}},
+		{BinaryCrossEntropy{}, ActivationSigmoid, []float32{1, 0, 1}},
+		{MeanSquared{}, ActivationLinear, []float32{0.5, -1, 2}},
+		{MeanSquared{}, ActivationTanh, []float32{0.5, -0.2, 0.1}},
+	} {
+		worst, at := CheckLoss(test.loss, test.act, x, test.ideal, 1e-3)
+		assert.True(t, worst < 1e-3, "%T at %d: %v", test.loss, at, worst)
+	}
+
+	// Cross entropy is differentiated through a softmax, not a sigmoid
+	worst, _ := CheckLoss(CrossEntropy{}, ActivationSigmoid, x, []float32{0, 1, 0}, 1e-3)
+	assert.True(t, worst > 0.1)
+}
+
+func Test_ActivationCorrections(t *testing.T) {
+	assert.Equal(t, float32(-4), GetActivation(ActivationDoublePow).F(-2, false))
+
+	elu := GetActivation(ActivationELU)
+	assert.Equal(t, float32(0), elu.F(0, false))
+	assert.InDelta(t, 0, elu.F(-1e-4, false), 1e-3)
+	assert.InDelta(t, -1, elu.F(-20, false), 1e-6)
+}
@@ -0,0 +1,549 @@
+package deep
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// NodeType denotes the operation of a node of a Graph
+type NodeType int
+
+const (
+	// NodeInput feeds an input vector into the graph
+	NodeInput NodeType = 0
+	// NodeDense is a fully connected layer over its input node
+	NodeDense NodeType = 1
+	// NodeConcat concatenates the outputs of its input nodes
+	NodeConcat NodeType = 2
+	// NodeAdd sums the outputs of its input nodes, which must be of equal
+	// size
+	NodeAdd NodeType = 3
+	// NodeOutput is a fully connected output layer over its input node,
+	// trained on targets of its own
+	NodeOutput NodeType = 4
+)
+
+func (t NodeType) String() string {
+	switch t {
+	case NodeInput:
+		return "input"
+	case NodeDense:
+		return "dense"
+	case NodeConcat:
+		return "concat"
+	case NodeAdd:
+		return "add"
+	case NodeOutput:
+		return "output"
+	}
+	return "N/A"
+}
+
+// NodeConfig declares a named node of a Graph
+type NodeConfig struct {
+	Name string
+	Type NodeType
+	// Names of the nodes feeding the node
+	Inputs []string `json:",omitempty"`
+	// Size of an input node, or number of neurons of a dense or output node
+	Size int `json:",omitempty"`
+	// Activation of a dense node, and the name of its registered activation
+	// if it is ActivationCustom
+	Activation ActivationType `json:",omitempty"`
+	Custom     string         `json:",omitempty"`
+	// Slope of a LeakyReLU activation for negative inputs, DefaultLeakySlope
+	// if unset
+	Slope float32 `json:",omitempty"`
+	// Mode of an output node, which sets its activation, and its Loss,
+	// which defaults according to Mode
+	Mode Mode     `json:",omitempty"`
+	Loss LossType `json:",omitempty"`
+}
+
+// Input declares an input node of the given size
+func Input(name string, size int) NodeConfig {
+	return NodeConfig{Name: name, Type: NodeInput, Size: size}
+}
+
+// Dense declares a fully connected layer of n neurons over node input
+func Dense(name, input string, n int, activation ActivationType) NodeConfig {
+	return NodeConfig{Name: name, Type: NodeDense, Inputs: []string{input}, Size: n, Activation: activation}
+}
+
+// Concat declares a node concatenating the outputs of inputs in order
+func Concat(name string, inputs ...string) NodeConfig {
+	return NodeConfig{Name: name, Type: NodeConcat, Inputs: inputs}
+}
+
+// Add declares a node summing the outputs of inputs
+func Add(name string, inputs ...string) NodeConfig {
+	return NodeConfig{Name: name, Type: NodeAdd, Inputs: inputs}
+}
+
+// Output declares an output layer of n neurons over node input, whose
+// activation and loss follow mode
+func Output(name, input string, n int, mode Mode) NodeConfig {
+	return NodeConfig{Name: name, Type: NodeOutput, Inputs: []string{input}, Size: n, Mode: mode}
+}
+
+// GraphConfig defines the nodes of a Graph, in any order
+type GraphConfig struct {
+	Nodes []NodeConfig
+	// Initializer for weights: {NewNormal(σ, μ), NewUniform(σ, μ)}
+	Weight WeightInitializer `json:"-"`
+	// Apply bias nodes
+	Bias bool
+	// Executor distributing the work of each layer: {Sequential{}, NewChunked(), NewWorkerPool(n)}
+	Executor Executor `json:"-"`
+}
+
+// Node is a node of a Graph
+type Node struct {
+	Config NodeConfig
+	// Size of the output of the node
+	Size int
+	// Layer of a dense or output node
+	Layer *Layer
+
+	// index of the node and of its input nodes in Graph.Nodes
+	index   int
+	sources []int
+}
+
+// Graph is a network whose nodes form a directed acyclic graph, with any
+// number of inputs and outputs
+type Graph struct {
+	Config *GraphConfig
+	// Nodes in topological order
+	Nodes []*Node
+	// Inputs and Outputs in the order they are declared
+	Inputs, Outputs []*Node
+
+	states *sync.Pool
+}
+
+// NewGraph returns a new graph of the nodes declared in c
+func NewGraph(c *GraphConfig) (*Graph, error) {
+	if c.Weight == nil {
+		c.Weight = NewUniform(0.5, 0)
+	}
+	if c.Executor == nil {
+		c.Executor = NewChunked()
+	}
+
+	nodes, err := sortNodes(c.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{Config: c, Nodes: nodes}
+	for _, node := range nodes {
+		if err := g.initializeNode(node); err != nil {
+			return nil, err
+		}
+	}
+	for i := range c.Nodes {
+		for _, node := range nodes {
+			if node.Config.Name != c.Nodes[i].Name {
+				continue
+			}
+			switch node.Config.Type {
+			case NodeInput:
+				g.Inputs = append(g.Inputs, node)
+			case NodeOutput:
+				if node.Config.Loss == LossNone {
+					node.Config.Loss = defaultLoss(node.Config.Mode)
+					c.Nodes[i].Loss = node.Config.Loss
+				}
+				g.Outputs = append(g.Outputs, node)
+			}
+		}
+	}
+	if len(g.Inputs) == 0 || len(g.Outputs) == 0 {
+		return nil, fmt.Errorf("Invalid graph - expected input and output nodes, got: %d and %d", len(g.Inputs), len(g.Outputs))
+	}
+
+	g.states = &sync.Pool{New: func() interface{} { return g.NewState() }}
+	return g, nil
+}
+
+// sortNodes returns the nodes declared in configs in topological order,
+// keeping the declared order where the graph allows
+func sortNodes(configs []NodeConfig) ([]*Node, error) {
+	byName := make(map[string]int, len(configs))
+	for i, c := range configs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("Invalid node %d - expected a name", i)
+		}
+		if _, ok := byName[c.Name]; ok {
+			return nil, fmt.Errorf("Invalid node %s - name used twice", c.Name)
+		}
+		byName[c.Name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make([]int, len(configs))
+	index := make([]int, len(configs))
+	var nodes []*Node
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch marks[i] {
+		case visiting:
+			return fmt.Errorf("Invalid node %s - cycle", configs[i].Name)
+		case visited:
+			return nil
+		}
+		marks[i] = visiting
+		node := &Node{Config: configs[i]}
+		for _, name := range configs[i].Inputs {
+			src, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("Invalid node %s - unknown input: %s", configs[i].Name, name)
+			}
+			if configs[src].Type == NodeOutput {
+				return fmt.Errorf("Invalid node %s - output %s cannot be an input", configs[i].Name, name)
+			}
+			if err := visit(src); err != nil {
+				return err
+			}
+			node.sources = append(node.sources, index[src])
+		}
+		marks[i] = visited
+		node.index = len(nodes)
+		index[i] = node.index
+		nodes = append(nodes, node)
+		return nil
+	}
+	for i := range configs {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// initializeNode validates node against its input nodes, and creates the
+// layer of dense and output nodes
+func (g *Graph) initializeNode(node *Node) error {
+	c := node.Config
+	inputs := 0
+	for _, src := range node.sources {
+		inputs += g.Nodes[src].Size
+	}
+
+	switch c.Type {
+	case NodeInput:
+		if len(c.Inputs) > 0 || c.Size < 1 {
+			return fmt.Errorf("Invalid node %s - expected a size and no inputs", c.Name)
+		}
+		node.Size = c.Size
+	case NodeDense, NodeOutput:
+		if len(c.Inputs) != 1 || c.Size < 1 {
+			return fmt.Errorf("Invalid node %s - expected a size and 1 input, got: %d", c.Name, len(c.Inputs))
+		}
+		act := c.Activation
+		if c.Type == NodeOutput && c.Mode != ModeDefault {
+			act = OutputActivation(c.Mode)
+		}
+		if act == ActivationCustom && c.Custom == "" {
+			return fmt.Errorf("Invalid node %s - custom activation requires NodeConfig.Custom", c.Name)
+		}
+		node.Size = c.Size
+		node.Layer = NewLayer(inputs, c.Size, act)
+		if act == c.Activation {
+			if err := node.Layer.setActivation(c.Custom, c.Slope); err != nil {
+				return fmt.Errorf("Invalid node %s - %v", c.Name, err)
+			}
+		}
+		if a, ok := node.Layer.activation().(Parametric); ok {
+			node.Layer.initParams(a, false)
+		}
+		for k := range node.Layer.Weights {
+			node.Layer.Weights[k] = g.Config.Weight()
+		}
+		if g.Config.Bias && !(c.Type == NodeOutput && c.Mode == ModeRegression) {
+			node.Layer.ApplyBias(g.Config.Weight)
+		}
+	case NodeConcat:
+		if len(c.Inputs) < 1 {
+			return fmt.Errorf("Invalid node %s - expected inputs", c.Name)
+		}
+		node.Size = inputs
+	case NodeAdd:
+		if len(c.Inputs) < 2 {
+			return fmt.Errorf("Invalid node %s - expected at least 2 inputs, got: %d", c.Name, len(c.Inputs))
+		}
+		node.Size = g.Nodes[node.sources[0]].Size
+		for i, src := range node.sources {
+			if size := g.Nodes[src].Size; size != node.Size {
+				return fmt.Errorf("Invalid input %s of node %s - expected: %d outputs got: %d", c.Inputs[i], c.Name, node.Size, size)
+			}
+		}
+	default:
+		return fmt.Errorf("Invalid node %s - unknown type: %d", c.Name, c.Type)
+	}
+	return nil
+}
+
+// GraphState holds the outputs of a forward pass through a Graph, so that
+// any number of goroutines can run one Graph, each with its own GraphState
+type GraphState struct {
+	// Values holds the output of each node
+	Values [][]float32
+	// Sums holds the pre-activation sums of each node with a layer
+	Sums [][]float32
+	// Outputs holds the values of the output nodes
+	Outputs [][]float32
+	// OutputSums holds the pre-activation sums of the output nodes
+	OutputSums [][]float32
+
+	jobs []*layerJob
+	// dy holds the loss gradients with respect to the output of each node
+	// that is not an input
+	dy [][]float32
+}
+
+// NewState returns a GraphState sized for g
+func (g *Graph) NewState() *GraphState {
+	s := &GraphState{
+		Values:     make([][]float32, len(g.Nodes)),
+		Sums:       make([][]float32, len(g.Nodes)),
+		Outputs:    make([][]float32, len(g.Outputs)),
+		OutputSums: make([][]float32, len(g.Outputs)),
+		jobs:       make([]*layerJob, len(g.Nodes)),
+		dy:         make([][]float32, len(g.Nodes)),
+	}
+	for i, node := range g.Nodes {
+		if node.Config.Type == NodeInput {
+			continue
+		}
+		s.Values[i] = make([]float32, node.Size)
+		s.dy[i] = make([]float32, node.Size)
+		if node.Layer != nil {
+			s.Sums[i] = make([]float32, node.Size)
+			s.jobs[i] = newLayerJob(node.Layer, s.Values[i])
+			s.jobs[i].sum = s.Sums[i]
+		}
+	}
+	for k, node := range g.Outputs {
+		s.Outputs[k] = s.Values[node.index]
+		s.OutputSums[k] = s.Sums[node.index]
+	}
+	return s
+}
+
+// ForwardState computes a forward pass of one input for each input node of
+// g into s. It is safe for concurrent use as long as every goroutine uses a
+// separate GraphState.
+func (g *Graph) ForwardState(s *GraphState, inputs [][]float32, training bool) error {
+	if len(inputs) != len(g.Inputs) {
+		return fmt.Errorf("Invalid number of inputs - expected: %d got: %d", len(g.Inputs), len(inputs))
+	}
+	for k, node := range g.Inputs {
+		if len(inputs[k]) != node.Size {
+			return fmt.Errorf("Invalid input dimension of %s - expected: %d got: %d", node.Config.Name, node.Size, len(inputs[k]))
+		}
+		s.Values[node.index] = inputs[k]
+	}
+
+	for i, node := range g.Nodes {
+		out := s.Values[i]
+		switch node.Config.Type {
+		case NodeDense, NodeOutput:
+			s.jobs[i].fire(g.Config.Executor, s.Values[node.sources[0]], training)
+		case NodeConcat:
+			k := 0
+			for _, src := range node.sources {
+				k += copy(out[k:], s.Values[src])
+			}
+		case NodeAdd:
+			copy(out, s.Values[node.sources[0]])
+			for _, src := range node.sources[1:] {
+				add(out, s.Values[src])
+			}
+		}
+	}
+	return nil
+}
+
+// Predict computes a forward pass and returns the prediction of each output
+// node. It keeps no state in g and is safe for concurrent use.
+func (g *Graph) Predict(inputs [][]float32) ([][]float32, error) {
+	s := g.states.Get().(*GraphState)
+	defer g.states.Put(s)
+
+	if err := g.ForwardState(s, inputs, false); err != nil {
+		return nil, err
+	}
+	out := make([][]float32, len(s.Outputs))
+	for k, y := range s.Outputs {
+		out[k] = append([]float32{}, y...)
+	}
+	return out, nil
+}
+
+// Backpropagate propagates deltas, the loss gradients with respect to the
+// pre-activation outputs of each output node, back through g given the
+// forward pass in s. It adds the weight and activation parameter gradients of
+// each node with a layer into grads, indexed like Nodes, unless grads is nil.
+func (g *Graph) Backpropagate(s *GraphState, deltas [][]float32, grads [][]float32) {
+	for _, dy := range s.dy {
+		for k := range dy {
+			dy[k] = 0
+		}
+	}
+	for k, node := range g.Outputs {
+		copy(s.dy[node.index], deltas[k])
+	}
+
+	for i := len(g.Nodes) - 1; i >= 0; i-- {
+		node, dy := g.Nodes[i], s.dy[i]
+		switch node.Config.Type {
+		case NodeDense, NodeOutput:
+			var grad []float32
+			if grads != nil {
+				grad = grads[i]
+			}
+			if node.Config.Type == NodeDense {
+				for k, y := range s.Values[i] {
+					node.Layer.BackwardParam(k, s.Sums[i][k], y, dy[k], grad)
+					dy[k] *= node.Layer.DActivate(k, s.Sums[i][k], y)
+				}
+			}
+			src := node.sources[0]
+			node.Layer.Backward(s.Values[src], dy, grad, s.dy[src])
+		case NodeConcat:
+			k := 0
+			for _, src := range node.sources {
+				size := g.Nodes[src].Size
+				if s.dy[src] != nil {
+					add(s.dy[src], dy[k:k+size])
+				}
+				k += size
+			}
+		case NodeAdd:
+			for _, src := range node.sources {
+				if s.dy[src] != nil {
+					add(s.dy[src], dy)
+				}
+			}
+		}
+	}
+}
+
+// NumWeights returns the number of weights in the graph
+func (g *Graph) NumWeights() (num int) {
+	for _, node := range g.Nodes {
+		if node.Layer != nil {
+			num += node.Layer.NumWeights()
+		}
+	}
+	return
+}
+
+// Params returns the activation parameters of each node in topological
+// order, nil for nodes without
+func (g *Graph) Params() [][]float32 {
+	var params [][]float32
+	for i, node := range g.Nodes {
+		if node.Layer == nil || node.Layer.Params == nil {
+			continue
+		}
+		if params == nil {
+			params = make([][]float32, len(g.Nodes))
+		}
+		params[i] = node.Layer.Params
+	}
+	return params
+}
+
+// ApplyParams sets the activation parameters of each node from a slice laid
+// out as returned by Params
+func (g *Graph) ApplyParams(params [][]float32) {
+	for i, node := range g.Nodes {
+		if node.Layer != nil && i < len(params) {
+			copy(node.Layer.Params, params[i])
+		}
+	}
+}
+
+// GraphDump is a graph dump
+type GraphDump struct {
+	Config *GraphConfig
+	// Weights of each node in topological order, nil for nodes without a
+	// layer
+	Weights [][][]float32
+	// Params holds the activation parameters of each node, if any has
+	Params [][]float32 `json:",omitempty"`
+}
+
+// Weights returns the weights of each node in topological order
+func (g *Graph) Weights() [][][]float32 {
+	weights := make([][][]float32, len(g.Nodes))
+	for i, node := range g.Nodes {
+		if node.Layer == nil {
+			continue
+		}
+		weights[i] = make([][]float32, node.Layer.Rows())
+		for j := range weights[i] {
+			weights[i][j] = node.Layer.Neuron(j)
+		}
+	}
+	return weights
+}
+
+// ApplyWeights sets the weights of each node from a slice laid out as
+// returned by Weights
+func (g *Graph) ApplyWeights(weights [][][]float32) error {
+	if len(weights) != len(g.Nodes) {
+		return fmt.Errorf("Invalid number of nodes - expected: %d got: %d", len(g.Nodes), len(weights))
+	}
+	for i, node := range g.Nodes {
+		if node.Layer == nil {
+			continue
+		}
+		if len(weights[i]) != node.Layer.Rows() {
+			return fmt.Errorf("Invalid weights of %s - expected: %d rows got: %d", node.Config.Name, node.Layer.Rows(), len(weights[i]))
+		}
+		for j, w := range weights[i] {
+			node.Layer.SetNeuron(j, w)
+		}
+	}
+	return nil
+}
+
+// Dump generates a graph dump
+func (g *Graph) Dump() *GraphDump {
+	return &GraphDump{Config: g.Config, Weights: g.Weights(), Params: g.Params()}
+}
+
+// GraphFromDump restores a Graph from a dump
+func GraphFromDump(dump *GraphDump) (*Graph, error) {
+	g, err := NewGraph(dump.Config)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.ApplyWeights(dump.Weights); err != nil {
+		return nil, err
+	}
+	g.ApplyParams(dump.Params)
+	return g, nil
+}
+
+// Marshal marshals to JSON from graph
+func (g *Graph) Marshal() ([]byte, error) {
+	return json.Marshal(g.Dump())
+}
+
+// UnmarshalGraph restores a graph from a JSON blob
+func UnmarshalGraph(bytes []byte) (*Graph, error) {
+	var dump GraphDump
+	if err := json.Unmarshal(bytes, &dump); err != nil {
+		return nil, err
+	}
+	return GraphFromDump(&dump)
+}
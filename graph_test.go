@@ -0,0 +1,166 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGraph() *Graph {
+	g, err := NewGraph(&GraphConfig{
+		Nodes: []NodeConfig{
+			Output("score", "merged", 1, ModeRegression),
+			Output("class", "shared", 3, ModeMultiClass),
+			Input("a", 2),
+			Input("b", 3),
+			Dense("ha", "a", 3, ActivationTanh),
+			Dense("hb", "b", 3, ActivationSigmoid),
+			Add("shared", "ha", "hb"),
+			Concat("merged", "shared", "b"),
+		},
+		Weight: NewNormal(0.5, 0),
+		Bias:   true,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+func Test_Graph(t *testing.T) {
+	rand.Seed(0)
+	g := newTestGraph()
+
+	names := func(nodes []*Node) (res []string) {
+		for _, node := range nodes {
+			res = append(res, node.Config.Name)
+		}
+		return
+	}
+	assert.Equal(t, []string{"a", "ha", "b", "hb", "shared", "merged", "score", "class"}, names(g.Nodes))
+	assert.Equal(t, []string{"a", "b"}, names(g.Inputs))
+	assert.Equal(t, []string{"score", "class"}, names(g.Outputs))
+	assert.Equal(t, LossMeanSquared, g.Outputs[0].Config.Loss)
+	assert.Equal(t, LossCrossEntropy, g.Outputs[1].Config.Loss)
+	assert.Equal(t, 6, g.Nodes[5].Size)
+	assert.Equal(t, ActivationSoftmax, g.Outputs[1].Layer.A)
+	assert.Nil(t, g.Outputs[0].Layer.Bias)
+	assert.Equal(t, 2*3+3+3*3+3+6*1+3*3+3, g.NumWeights())
+
+	a, b := []float32{0.3, -0.5}, []float32{0.1, 0.7, -0.2}
+	s := g.NewState()
+	assert.NoError(t, g.ForwardState(s, [][]float32{a, b}, false))
+	shared := make([]float32, 3)
+	for j := range shared {
+		ha := Tanh{}.F(Dot(g.Nodes[1].Layer.Row(j), a)+g.Nodes[1].Layer.Bias[j], false)
+		hb := Sigmoid{}.F(Dot(g.Nodes[3].Layer.Row(j), b)+g.Nodes[3].Layer.Bias[j], false)
+		shared[j] = ha + hb
+	}
+	assert.InDeltaSlice(t, shared, s.Values[4], 1e-6)
+	assert.Equal(t, append(append([]float32{}, s.Values[4]...), b...), s.Values[5])
+	assert.InDelta(t, Dot(g.Outputs[0].Layer.Row(0), s.Values[5]), s.Outputs[0][0], 1e-6)
+	assert.InDelta(t, 1, Sum(s.Outputs[1]), 1e-6)
+
+	out, err := g.Predict([][]float32{a, b})
+	assert.NoError(t, err)
+	assert.Equal(t, s.Outputs, out)
+	_, err = g.Predict([][]float32{a})
+	assert.Error(t, err)
+	assert.Error(t, g.ForwardState(s, [][]float32{a}, false))
+	assert.Error(t, g.ForwardState(s, [][]float32{b, a}, false))
+}
+
+func Test_GraphInvalid(t *testing.T) {
+	graph := func(nodes ...NodeConfig) error {
+		_, err := NewGraph(&GraphConfig{Nodes: nodes})
+		return err
+	}
+
+	assert.NoError(t, graph(Input("x", 2), Output("y", "x", 1, ModeRegression)))
+	assert.Error(t, graph(Input("x", 2)))
+	assert.Error(t, graph(Input("x", 2), Input("x", 2), Output("y", "x", 1, ModeRegression)))
+	assert.Error(t, graph(Input("x", 2), Output("y", "z", 1, ModeRegression)))
+	assert.Error(t, graph(Input("x", 0), Output("y", "x", 1, ModeRegression)))
+	assert.Error(t, graph(Input("x", 2), Dense("h", "x", 0, ActivationReLU), Output("y", "h", 1, ModeRegression)))
+	assert.Error(t, graph(Input("x", 2), Output("y", "x", 1, ModeRegression), Dense("h", "y", 2, ActivationReLU)))
+	assert.Error(t, graph(Input("x", 2), Add("s", "x"), Output("y", "s", 1, ModeRegression)))
+	assert.Error(t, graph(Input("x", 2), Input("z", 3), Add("s", "x", "z"), Output("y", "s", 1, ModeRegression)))
+	assert.Error(t, graph(
+		Input("x", 2),
+		Dense("h1", "h2", 2, ActivationReLU),
+		Dense("h2", "h1", 2, ActivationReLU),
+		Output("y", "h2", 1, ModeRegression)))
+}
+
+func Test_GraphBackpropagate(t *testing.T) {
+	rand.Seed(0)
+	g := newTestGraph()
+	inputs := [][]float32{{0.3, -0.5}, {0.1, 0.7, -0.2}}
+
+	// loss is the sum over the outputs of the dot product of their
+	// pre-activations with fixed deltas, whose gradients Backpropagate
+	// computes. The regression output is linear and the softmax is
+	// bypassed by comparing against the class pre-activations.
+	deltas := [][]float32{{0.8}, {0.2, -0.5, 0.3}}
+	class := g.Outputs[1].Layer
+	class.A = ActivationLinear
+	loss := func() float32 {
+		out, _ := g.Predict(inputs)
+		var sum float32
+		for k := range out {
+			sum += Dot(out[k], deltas[k])
+		}
+		return sum
+	}
+
+	s := g.NewState()
+	assert.NoError(t, g.ForwardState(s, inputs, true))
+	grads := make([][]float32, len(g.Nodes))
+	for i, node := range g.Nodes {
+		if node.Layer != nil {
+			grads[i] = make([]float32, node.Layer.NumWeights())
+		}
+	}
+	g.Backpropagate(s, deltas, grads)
+
+	const eps = 1e-2
+	for i, node := range g.Nodes {
+		l := node.Layer
+		if l == nil {
+			continue
+		}
+		var params []*float32
+		for k := range l.Weights {
+			params = append(params, &l.Weights[k])
+		}
+		for k := range l.Bias {
+			params = append(params, &l.Bias[k])
+		}
+		for k, x := range params {
+			assert.InDelta(t, NumericGradient(loss, x, eps), grads[i][k], 2e-3, "%s weight %d", node.Config.Name, k)
+		}
+	}
+}
+
+func Test_MarshalGraph(t *testing.T) {
+	rand.Seed(0)
+	g := newTestGraph()
+	dump, err := g.Marshal()
+	assert.NoError(t, err)
+	restored, err := UnmarshalGraph(dump)
+	assert.NoError(t, err)
+	assert.Equal(t, g.Weights(), restored.Weights())
+	assert.Equal(t, g.Config.Nodes, restored.Config.Nodes)
+	assert.Equal(t, len(g.Nodes), len(restored.Nodes))
+
+	inputs := [][]float32{{0.3, -0.5}, {0.1, 0.7, -0.2}}
+	out, err := g.Predict(inputs)
+	assert.NoError(t, err)
+	restoredOut, err := restored.Predict(inputs)
+	assert.NoError(t, err)
+	assert.Equal(t, out, restoredOut)
+
+	_, err = UnmarshalGraph([]byte(`{"Config":{"Nodes":[{"Name":"x","Type":0,"Size":2}]}}`))
+	assert.Error(t, err)
+}
@@ -2,87 +2,543 @@ package deep
 
 import "fmt"
 
-// Layer is a set of neurons and corresponding activation
+// Layer is a set of neurons and corresponding activation, fully connected to
+// the previous layer unless Type says otherwise
 type Layer struct {
-	Neurons []*Neuron
-	A       ActivationType
+	A    ActivationType
+	Type LayerType
+	// Number of inputs feeding the layer
+	Inputs int
+	// Number of neurons, that is outputs of the layer
+	Size int
+	// Weights is a Rows() x RowSize() matrix in row-major order. Row j holds
+	// the incoming weights of neuron j of a dense layer, or the kernel of
+	// filter j of a convolution.
+	Weights []float32
+	// Bias holds one bias weight per row, or nil if the layer has no bias
+	Bias []float32
+	// Value holds the neuron activations of the most recent forward pass
+	Value []float32 `json:"-"`
+
+	// Precision of the layer. Float64 layers keep their weights, bias and
+	// activations in Weights64, Bias64 and Value64 instead.
+	Precision Precision
+	Weights64 []float64
+	Bias64    []float64
+	Value64   []float64 `json:"-"`
+
+	// Spatial shapes of the input and output of convolution and pooling
+	// layers
+	In, Out Shape
+	// Kernel, Stride and Padding of convolution and pooling layers
+	Kernel, Stride, Padding int
+	// Embeddings of the categorical input columns of an embedding layer
+	Embeddings []Embedding
+	// numeric holds the input columns an embedding layer passes through
+	numeric []int
+	// Running mean and variance of the inputs of a batch normalization
+	// layer, whose Weights and Bias hold the gain and bias of each unit
+	Mean, Variance []float32
+	// Momentum of the running statistics of a batch normalization layer and
+	// Epsilon added to the variance of a normalization layer
+	Momentum, Epsilon float32
+	// Earlier layers whose outputs are added to the pre-activations of the
+	// layer, or appended to its input, -1 being the network input
+	Residual, Concat []int
+	// Heads of an attention layer over Length positions, whether each
+	// position only attends to itself and earlier ones, and whether
+	// positional encodings are added to its input
+	Heads, Length      int
+	Causal, Positional bool
+	// Custom names the registered activation of layers whose activation is
+	// ActivationCustom, looked up into custom
+	Custom string
+	custom Differentiable
+	// Slope of a LeakyReLU activation for negative inputs, DefaultLeakySlope
+	// if unset, applied through leaky
+	Slope float32
+	leaky Differentiable
+	// Params holds the trainable parameters of a Parametric activation, one
+	// per neuron or one shared by the layer, which param applies
+	Params []float32
+	param  Parametric
 }
 
-// NewLayer creates a new layer with n nodes
-func NewLayer(n int, activation ActivationType) *Layer {
-	neurons := make([]*Neuron, n)
+// LayerType denotes how a layer connects to its input
+type LayerType int
 
-	for i := 0; i < n; i++ {
-		act := activation
-		if activation == ActivationSoftmax {
-			act = ActivationLinear
-		}
-		neurons[i] = NewNeuron(act)
+const (
+	// LayerDense is a fully connected layer, the default
+	LayerDense LayerType = 0
+	// LayerConv2D is a 2D convolution over a spatial input
+	LayerConv2D LayerType = 1
+	// LayerMaxPool takes the maximum of each window of a spatial input
+	LayerMaxPool LayerType = 2
+	// LayerAvgPool takes the average of each window of a spatial input
+	LayerAvgPool LayerType = 3
+	// LayerGlobalAvgPool averages each channel of a spatial input
+	LayerGlobalAvgPool LayerType = 4
+	// LayerLSTM is a long short-term memory recurrent layer
+	LayerLSTM LayerType = 5
+	// LayerGRU is a gated recurrent unit layer
+	LayerGRU LayerType = 6
+	// LayerEmbedding maps categorical inputs to trainable vectors
+	LayerEmbedding LayerType = 7
+	// LayerBatchNorm normalizes its inputs with batch statistics
+	LayerBatchNorm LayerType = 8
+	// LayerNorm normalizes its inputs across the layer, per example
+	LayerNorm LayerType = 9
+	// LayerAttention is multi-head self-attention over a window of steps
+	LayerAttention LayerType = 10
+)
+
+func (t LayerType) String() string {
+	switch t {
+	case LayerDense:
+		return "dense"
+	case LayerConv2D:
+		return "conv2d"
+	case LayerMaxPool:
+		return "maxpool"
+	case LayerAvgPool:
+		return "avgpool"
+	case LayerGlobalAvgPool:
+		return "globalavgpool"
+	case LayerLSTM:
+		return "lstm"
+	case LayerGRU:
+		return "gru"
+	case LayerEmbedding:
+		return "embedding"
+	case LayerBatchNorm:
+		return "batchnorm"
+	case LayerNorm:
+		return "layernorm"
+	case LayerAttention:
+		return "attention"
 	}
+	return "N/A"
+}
+
+// LayerConfig declares a layer of Config.Layout that is not fully connected
+type LayerConfig struct {
+	Type LayerType
+	// Kernel is the height and width of the convolution or pooling window
+	Kernel int
+	// Stride of the window, 1 for convolutions and Kernel for pooling if unset
+	Stride int
+	// Padding of zeros around each side of the input
+	Padding int
+	// Embeddings of the categorical input columns of an embedding layer
+	Embeddings []Embedding `json:",omitempty"`
+	// Momentum of the running statistics of a batch normalization layer
+	// and Epsilon added to the variance of a normalization layer, 0.9 and
+	// 1e-5 if unset
+	Momentum float32 `json:",omitempty"`
+	Epsilon  float32 `json:",omitempty"`
+	// Residual lists earlier layers, or -1 for the network input, whose
+	// outputs are added to the pre-activations of the layer. Their sizes
+	// must match the layer's.
+	Residual []int `json:",omitempty"`
+	// Concat lists layers before the previous one, or -1 for the network
+	// input, whose outputs are appended to the input of the layer
+	Concat []int `json:",omitempty"`
+	// Length is the number of positions of the input of an attention layer,
+	// split into Heads heads, 1 if unset. Causal masks later positions and
+	// Positional adds sinusoidal position encodings to the input.
+	Length     int  `json:",omitempty"`
+	Heads      int  `json:",omitempty"`
+	Causal     bool `json:",omitempty"`
+	Positional bool `json:",omitempty"`
+	// Init overrides Config.Init for the layer
+	Init LayerInitializer `json:"-"`
+	// Custom names an activation registered through RegisterActivation,
+	// which replaces the activation of the layer
+	Custom string `json:",omitempty"`
+	// Slope of a LeakyReLU activation for negative inputs, DefaultLeakySlope
+	// if unset
+	Slope float32 `json:",omitempty"`
+	// SharedParam makes a Parametric activation learn one parameter for the
+	// layer rather than one per neuron
+	SharedParam bool `json:",omitempty"`
+}
+
+// NewLayer creates a new layer with n nodes, each connected to the given
+// number of inputs through zero-initialized weights
+func NewLayer(inputs, n int, activation ActivationType) *Layer {
 	return &Layer{
-		Neurons: neurons,
 		A:       activation,
+		Inputs:  inputs,
+		Size:    n,
+		Weights: make([]float32, n*inputs),
+		Value:   make([]float32, n),
+	}
+}
+
+// ApplyBias creates a bias weight for each neuron in l
+func (l *Layer) ApplyBias(weight WeightInitializer) {
+	if l.Precision == PrecisionFloat64 {
+		l.Bias64 = make([]float64, l.Rows())
+		for i := range l.Bias64 {
+			l.Bias64[i] = float64(weight())
+		}
+		return
+	}
+	l.Bias = make([]float32, l.Rows())
+	for i := range l.Bias {
+		l.Bias[i] = weight()
 	}
 }
 
-func (l *Layer) fire(training bool) {
-	ch := make(chan bool, len(l.Neurons))
-	for _, n := range l.Neurons {
-		go func(n *Neuron) {
-			n.fire(training)
-			ch <- false
-		}(n)
+// Rows returns the number of weight rows, which is the number of neurons of a
+// dense or normalization layer, the number of filters of a
+// convolution, the number of gate units of a recurrent layer, the number of
+// categories of an embedding layer, the query, key, value and output
+// features of an attention layer and 0 for pooling
+func (l *Layer) Rows() int {
+	switch l.Type {
+	case LayerEmbedding:
+		var rows int
+		for _, e := range l.Embeddings {
+			rows += e.Categories
+		}
+		return rows
+	case LayerDense, LayerBatchNorm, LayerNorm:
+		return l.Size
+	case LayerConv2D:
+		return l.Out.Channels
+	case LayerLSTM:
+		return 4 * l.Size
+	case LayerGRU:
+		return 3 * l.Size
+	case LayerAttention:
+		return 4 * l.dim()
 	}
+	return 0
+}
 
-	for x := 0; x < len(l.Neurons); x++ {
-		<-ch
+// RowSize returns the number of weights in each row, excluding bias. The rows
+// of embedding layers vary in size, see RowRange.
+func (l *Layer) RowSize() int {
+	switch l.Type {
+	case LayerDense:
+		return l.Inputs
+	case LayerConv2D:
+		return l.In.Channels * l.Kernel * l.Kernel
+	case LayerLSTM, LayerGRU:
+		return l.Inputs + l.Size
+	case LayerBatchNorm, LayerNorm:
+		return 1
+	case LayerAttention:
+		return l.dim()
 	}
+	return 0
+}
 
-	if l.A == ActivationSoftmax {
+// Fan returns the number of inputs (fan-in) and outputs (fan-out) of each
+// neuron of l, or 0 for layers that are not initialized from them. Fan-in is
+// the number of weights of each row.
+func (l *Layer) Fan() (in, out int) {
+	switch l.Type {
+	case LayerDense:
+		return l.Inputs, l.Size
+	case LayerConv2D:
+		return l.RowSize(), l.Out.Channels * l.Kernel * l.Kernel
+	case LayerLSTM, LayerGRU:
+		return l.RowSize(), l.Size
+	case LayerAttention:
+		return l.dim(), l.dim()
+	}
+	return 0, 0
+}
+
+// biased reports whether l takes bias weights from Config.Bias.
+// Normalization layers always have bias.
+func (l *Layer) biased() bool {
+	switch l.Type {
+	case LayerDense, LayerConv2D, LayerLSTM, LayerGRU, LayerAttention:
+		return true
+	}
+	return false
+}
+
+// Recurrent reports whether l carries state from one step of a sequence to
+// the next
+func (l *Layer) Recurrent() bool {
+	return l.Type == LayerLSTM || l.Type == LayerGRU
+}
+
+// work returns the number of independent units of work in a forward pass
+// through l, and the cost of each
+func (l *Layer) work() (units, cost int) {
+	switch l.Type {
+	case LayerDense:
+		return l.Size, l.Inputs
+	case LayerConv2D:
+		return l.Out.Channels, l.Out.Height * l.Out.Width * l.RowSize()
+	case LayerGlobalAvgPool:
+		return l.Out.Channels, l.In.Height * l.In.Width
+	case LayerLSTM, LayerGRU:
+		return l.Rows(), l.RowSize()
+	case LayerEmbedding:
+		return 1, l.Size
+	case LayerBatchNorm:
+		return l.Size, 1
+	case LayerNorm:
+		return 1, l.Size
+	case LayerAttention:
+		d := l.dim()
+		return 1, 4*l.Length*d*d + 2*l.Length*l.Length*d
+	}
+	return l.Out.Channels, l.Out.Height * l.Out.Width * l.Kernel * l.Kernel
+}
+
+// Row returns the incoming weights of neuron j, excluding bias
+func (l *Layer) Row(j int) []float32 {
+	from, to := l.RowRange(j)
+	return l.Weights[from:to]
+}
+
+// RowRange returns the range of Weights holding row j
+func (l *Layer) RowRange(j int) (from, to int) {
+	if l.Type == LayerEmbedding {
+		return l.embeddingRange(j)
+	}
+	size := l.RowSize()
+	return j * size, (j + 1) * size
+}
+
+// NumWeights returns the number of weights in the layer, including bias and
+// activation parameters
+func (l *Layer) NumWeights() int {
+	if l.Precision == PrecisionFloat64 {
+		return len(l.Weights64) + len(l.Bias64)
+	}
+	return len(l.Weights) + len(l.Bias) + len(l.Params)
+}
 
-		outs := make([]float32, len(l.Neurons))
-		for i, neuron := range l.Neurons {
-			outs[i] = neuron.Value
+// layerJob binds the arguments of a forward pass through a layer, so that its
+// work can be handed to an Executor without allocating a closure every pass
+type layerJob struct {
+	l        *Layer
+	act      Differentiable
+	param    Parametric
+	in, out  []float32
+	training bool
+	run      func(from, to int)
+	// sum records the pre-activation sums of out, unless it is nil
+	sum []float32
+
+	// sparse replaces in while isSparse is set
+	sparse   Sparse
+	isSparse bool
+
+	// Recurrent layers continue from the hidden and cell state prevH and
+	// prevC of the previous step, or from zero if they are nil, and record
+	// their gate activations and cell state. hidden is the recurrent input
+	// of the gates being fired, which for GRU candidates is the hidden state
+	// through the reset gate kept in rh.
+	prevH, prevC            []float32
+	gates, cell, hidden, rh []float32
+
+	// residual holds the outputs added to the pre-activations
+	residual [][]float32
+	// attn holds the projections and attention weights of an attention
+	// layer
+	attn *attention
+}
+
+func newLayerJob(l *Layer, out []float32) *layerJob {
+	j := &layerJob{l: l, out: out}
+	switch l.Type {
+	case LayerEmbedding:
+		j.run = j.fireEmbedding
+	case LayerLSTM, LayerGRU:
+		j.run = j.fireGates
+	case LayerConv2D:
+		j.run = j.fireConv
+	case LayerMaxPool, LayerAvgPool, LayerGlobalAvgPool:
+		j.run = j.firePool
+	case LayerBatchNorm:
+		j.run = j.fireNorm
+	case LayerNorm:
+		j.run = j.fireLayerNorm
+	case LayerAttention:
+		j.attn = newAttention(l)
+		j.run = j.fireAttention
+	default:
+		j.run = j.fireRange
+	}
+	return j
+}
+
+func (j *layerJob) fire(exec Executor, input []float32, training bool) {
+	l := j.l
+	act := l.activation()
+	j.act, j.param, j.in, j.training = act, l.param, input, training
+	if l.Recurrent() {
+		j.fireRecurrent(exec)
+		j.in = nil
+		return
+	}
+
+	// Residual connections are added before the activation
+	if len(j.residual) > 0 {
+		j.act, j.param = GetActivation(ActivationLinear), nil
+	}
+	units, cost := l.work()
+	if j.isSparse {
+		cost = len(j.sparse.Indices)
+	}
+	exec.Run(units, cost, j.run)
+	j.in = nil
+	if len(j.residual) > 0 {
+		j.act, j.param = act, l.param
+		for k := range j.out {
+			for _, r := range j.residual {
+				j.out[k] += r[k]
+			}
+			j.out[k] = j.activate(k, j.out[k])
 		}
+	}
+	if l.Type == LayerEmbedding && j.sum != nil {
+		copy(j.sum, j.out)
+	}
+
+	if l.A == ActivationSoftmax {
+		SoftmaxTo(j.out, j.out)
+	}
+}
 
-		sm := Softmax(outs)
-		for i, neuron := range l.Neurons {
-			neuron.Value = sm[i]
+func (j *layerJob) fireRange(from, to int) {
+	if j.isSparse {
+		j.fireRangeSparse(from, to)
+		return
+	}
+	l := j.l
+	for k := from; k < to; k++ {
+		sum := Dot(l.Row(k), j.in)
+		if l.Bias != nil {
+			sum += l.Bias[k]
 		}
+		j.out[k] = j.activate(k, sum)
+	}
+}
 
+// activate records the pre-activation sum x of output k and returns its
+// activation
+func (j *layerJob) activate(k int, x float32) float32 {
+	if j.sum != nil {
+		j.sum[k] = x
 	}
+	if j.param != nil {
+		return j.param.FParam(x, j.l.Params[j.l.paramIndex(k)])
+	}
+	return j.act.F(x, j.training)
 }
 
-// Connect fully connects layer l to next, and initializes each
-// synapse with the given weight function
-func (l *Layer) Connect(next *Layer, weight WeightInitializer) {
-	for i := range l.Neurons {
-		for j := range next.Neurons {
-			syn := NewSynapse(weight())
-			l.Neurons[i].Out = append(l.Neurons[i].Out, syn)
-			next.Neurons[j].In = append(next.Neurons[j].In, syn)
+// Backward propagates deltas, the loss gradients with respect to the
+// pre-activation outputs of l, back through the layer given the input of the
+// forward pass. It adds the weight gradients into grad, laid out as the
+// weights followed by the bias, and the input gradients into dx. Either may
+// be nil to skip it.
+func (l *Layer) Backward(input, deltas, grad, dx []float32) {
+	switch l.Type {
+	case LayerConv2D:
+		l.backwardConv(input, deltas, grad, dx)
+		return
+	case LayerMaxPool, LayerAvgPool, LayerGlobalAvgPool:
+		l.backwardPool(input, deltas, dx)
+		return
+	case LayerLSTM, LayerGRU:
+		panic(fmt.Sprintf("deep: %s layers backpropagate through BackwardSequence", l.Type))
+	case LayerEmbedding:
+		l.backwardEmbedding(input, deltas, grad, dx)
+		return
+	case LayerBatchNorm:
+		l.backwardNorm(input, deltas, grad, dx)
+		return
+	case LayerNorm:
+		l.backwardLayerNorm(input, deltas, grad, dx)
+		return
+	case LayerAttention:
+		l.backwardAttention(input, deltas, grad, dx)
+		return
+	}
+
+	var bias []float32
+	if grad != nil {
+		bias = grad[len(l.Weights):]
+	}
+	for j, d := range deltas {
+		row := l.Row(j)
+		if grad != nil {
+			g := grad[j*l.Inputs : (j+1)*l.Inputs]
+			for k, x := range input {
+				g[k] += d * x
+			}
+			if l.Bias != nil {
+				bias[j] += d
+			}
+		}
+		if dx != nil {
+			for k, w := range row {
+				dx[k] += w * d
+			}
 		}
 	}
 }
 
-// ApplyBias creates and returns a bias synapse for each neuron in l
-func (l *Layer) ApplyBias(weight WeightInitializer) []*Synapse {
-	biases := make([]*Synapse, len(l.Neurons))
-	for i := range l.Neurons {
-		biases[i] = NewSynapse(weight())
-		biases[i].IsBias = true
-		l.Neurons[i].In = append(l.Neurons[i].In, biases[i])
+// DActivate returns the derivative of the activation of output k of l at
+// the pre-activation sum x, whose activation is y
+func (l *Layer) DActivate(k int, x, y float32) float32 {
+	if l.param != nil {
+		dx, _ := l.param.DfParam(x, y, l.Params[l.paramIndex(k)])
+		return dx
+	}
+	return l.activation().Df(x, y)
+}
+
+// Neuron returns the incoming weights of neuron j, with the bias weight
+// appended last if the layer has bias
+func (l *Layer) Neuron(j int) []float32 {
+	if l.Precision == PrecisionFloat64 {
+		return toFloat32(l.Neuron64(j))
+	}
+	row := l.Row(j)
+	w := make([]float32, len(row), len(row)+1)
+	copy(w, row)
+	if l.Bias != nil {
+		w = append(w, l.Bias[j])
+	}
+	return w
+}
+
+// SetNeuron sets the incoming weights of neuron j from a slice laid out as
+// returned by Neuron
+func (l *Layer) SetNeuron(j int, weights []float32) {
+	if l.Precision == PrecisionFloat64 {
+		l.SetNeuron64(j, toFloat64(weights))
+		return
+	}
+	row := l.Row(j)
+	copy(row, weights[:len(row)])
+	if l.Bias != nil {
+		l.Bias[j] = weights[len(row)]
 	}
-	return biases
 }
 
 func (l Layer) String() string {
-	weights := make([][]float32, len(l.Neurons))
-	for i, n := range l.Neurons {
-		weights[i] = make([]float32, len(n.In))
-		for j, s := range n.In {
-			weights[i][j] = s.Weight
+	if l.Precision == PrecisionFloat64 {
+		weights := make([][]float64, l.Size)
+		for j := range weights {
+			weights[j] = l.Neuron64(j)
 		}
+		return fmt.Sprintf("%+v", weights)
+	}
+	weights := make([][]float32, l.Rows())
+	for j := range weights {
+		weights[j] = l.Neuron(j)
 	}
 	return fmt.Sprintf("%+v", weights)
 }
@@ -0,0 +1,121 @@
+package deep
+
+// NewLayer64 creates a new float64 layer with n nodes, each connected to the
+// given number of inputs through zero-initialized weights
+func NewLayer64(inputs, n int, activation ActivationType) *Layer {
+	return &Layer{
+		A:         activation,
+		Inputs:    inputs,
+		Size:      n,
+		Precision: PrecisionFloat64,
+		Weights64: make([]float64, n*inputs),
+		Value64:   make([]float64, n),
+	}
+}
+
+// toFloat64 converts the float32 storage of l to float64
+func (l *Layer) toFloat64() {
+	if l.Precision == PrecisionFloat64 {
+		return
+	}
+	l.Precision = PrecisionFloat64
+	l.Weights64 = toFloat64(l.Weights)
+	if l.Bias != nil {
+		l.Bias64 = toFloat64(l.Bias)
+	}
+	l.Value64 = make([]float64, l.Size)
+	l.Weights, l.Bias, l.Value = nil, nil, nil
+}
+
+// Row64 returns the incoming weights of neuron j of a float64 layer,
+// excluding bias
+func (l *Layer) Row64(j int) []float64 {
+	return l.Weights64[j*l.Inputs : (j+1)*l.Inputs]
+}
+
+// Neuron64 returns the incoming weights of neuron j of a float64 layer, with
+// the bias weight appended last if the layer has bias
+func (l *Layer) Neuron64(j int) []float64 {
+	w := make([]float64, l.Inputs, l.Inputs+1)
+	copy(w, l.Row64(j))
+	if l.Bias64 != nil {
+		w = append(w, l.Bias64[j])
+	}
+	return w
+}
+
+// SetNeuron64 sets the incoming weights of neuron j of a float64 layer from a
+// slice laid out as returned by Neuron64
+func (l *Layer) SetNeuron64(j int, weights []float64) {
+	copy(l.Row64(j), weights[:l.Inputs])
+	if l.Bias64 != nil {
+		l.Bias64[j] = weights[l.Inputs]
+	}
+}
+
+// DActivate64 returns the derivative of the layer activation at the
+// pre-activation sum x, whose activation is y
+func (l *Layer) DActivate64(x, y float64) float64 {
+	return l.activation64().Df64(x, y)
+}
+
+// layerJob64 is the float64 counterpart of layerJob
+type layerJob64 struct {
+	l        *Layer
+	act      Differentiable64
+	in, out  []float64
+	training bool
+	run      func(from, to int)
+	// sum records the pre-activation sums of out, unless it is nil
+	sum []float64
+
+	// sparse replaces in while isSparse is set
+	sparse   Sparse
+	isSparse bool
+}
+
+// activate records the pre-activation sum x of output k and returns its
+// activation
+func (j *layerJob64) activate(k int, x float64) float64 {
+	if j.sum != nil {
+		j.sum[k] = x
+	}
+	return j.act.F64(x, j.training)
+}
+
+func newLayerJob64(l *Layer, out []float64) *layerJob64 {
+	j := &layerJob64{l: l, out: out}
+	j.run = j.fireRange
+	return j
+}
+
+func (j *layerJob64) fire(exec Executor, input []float64, training bool) {
+	l := j.l
+	j.act, j.in, j.training = l.activation64(), input, training
+
+	cost := l.Inputs
+	if j.isSparse {
+		cost = len(j.sparse.Indices)
+	}
+	exec.Run(l.Size, cost, j.run)
+	j.in = nil
+
+	if l.A == ActivationSoftmax {
+		SoftmaxTo64(j.out, j.out)
+	}
+}
+
+func (j *layerJob64) fireRange(from, to int) {
+	if j.isSparse {
+		j.fireRangeSparse(from, to)
+		return
+	}
+	l := j.l
+	for k := from; k < to; k++ {
+		sum := Dot64(l.Row64(k), j.in)
+		if l.Bias64 != nil {
+			sum += l.Bias64[k]
+		}
+		j.out[k] = j.activate(k, sum)
+	}
+}
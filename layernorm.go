@@ -0,0 +1,75 @@
+package deep
+
+import math "github.com/chewxy/math32"
+
+// NewLayerNorm creates a new layer normalization layer, which normalizes the
+// inputs of each example to zero mean and unit variance across the layer
+// before scaling each by a learned gain and shifting it by a learned bias.
+// Unlike batch normalization it does not depend on the other examples of a
+// batch, so it trains the same way online. Epsilon defaults to 1e-5 if unset.
+func NewLayerNorm(inputs int, epsilon float32, activation ActivationType) *Layer {
+	if epsilon == 0 {
+		epsilon = 1e-5
+	}
+	l := &Layer{
+		A:       activation,
+		Type:    LayerNorm,
+		Inputs:  inputs,
+		Size:    inputs,
+		Weights: make([]float32, inputs),
+		Bias:    make([]float32, inputs),
+		Value:   make([]float32, inputs),
+		Epsilon: epsilon,
+	}
+	for k := range l.Weights {
+		l.Weights[k] = 1
+	}
+	return l
+}
+
+// layerStatistics returns the mean and inverse standard deviation of input
+func (l *Layer) layerStatistics(input []float32) (mean, inv float32) {
+	for _, x := range input {
+		mean += x
+	}
+	mean /= float32(len(input))
+	var variance float32
+	for _, x := range input {
+		variance += (x - mean) * (x - mean)
+	}
+	variance /= float32(len(input))
+	return mean, 1 / math.Sqrt(variance+l.Epsilon)
+}
+
+// fireLayerNorm normalizes the whole input, as a single unit of work
+func (j *layerJob) fireLayerNorm(from, to int) {
+	l := j.l
+	mean, inv := l.layerStatistics(j.in)
+	for k, x := range j.in {
+		j.out[k] = j.activate(k, l.normalize(k, x, mean, inv))
+	}
+}
+
+// backwardLayerNorm backpropagates through the normalization, accounting
+// for the dependence of the mean and variance on every input
+func (l *Layer) backwardLayerNorm(input, deltas, grad, dx []float32) {
+	mean, inv := l.layerStatistics(input)
+	var sum, dot float32
+	for k, d := range deltas {
+		xhat := (input[k] - mean) * inv
+		if grad != nil {
+			grad[k] += d * xhat
+			grad[len(l.Weights)+k] += d
+		}
+		sum += d * l.Weights[k]
+		dot += d * l.Weights[k] * xhat
+	}
+	if dx == nil {
+		return
+	}
+	size := float32(len(input))
+	for k, d := range deltas {
+		xhat := (input[k] - mean) * inv
+		dx[k] += inv / size * (size*d*l.Weights[k] - sum - xhat*dot)
+	}
+}
@@ -0,0 +1,88 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LayerNorm(t *testing.T) {
+	rand.Seed(0)
+	n := NewNeural(&Config{
+		Inputs:     2,
+		Layout:     []int{4, 0, 1},
+		Layers:     []LayerConfig{{}, {Type: LayerNorm}},
+		Activation: []ActivationType{ActivationLinear, ActivationLinear},
+		Mode:       ModeRegression,
+		Weight:     NewNormal(0.5, 0),
+		Bias:       true,
+	})
+	l := n.Layers[1]
+	assert.Equal(t, "layernorm", l.Type.String())
+	assert.Equal(t, 4, l.Size)
+	assert.Equal(t, 4, n.Layers[2].Inputs)
+	assert.Equal(t, 8, l.NumWeights())
+
+	s := n.NewState()
+	assert.NoError(t, n.ForwardState(s, []float32{0.7, -1.2}, false))
+	var mean, variance float32
+	for _, y := range s.Values[1] {
+		mean += y / 4
+		variance += y * y / 4
+	}
+	assert.InDelta(t, 0, mean, 1e-5)
+	assert.InDelta(t, 1, variance, 1e-3)
+}
+
+func Test_LayerNormBackward(t *testing.T) {
+	rand.Seed(0)
+	l := NewLayerNorm(5, 0, ActivationLinear)
+	for k := range l.Weights {
+		l.Weights[k], l.Bias[k] = rand.Float32()+0.5, rand.Float32()-0.5
+	}
+	input := []float32{0.3, -1.1, 2, 0.8, -0.4}
+	deltas := []float32{0.2, -0.5, 0.1, 0.4, -0.3}
+	j := newLayerJob(l, make([]float32, 5))
+
+	// loss = sum(deltas * y) has the given deltas as output gradients
+	loss := func() float32 {
+		j.fire(Sequential{}, input, false)
+		return Dot(deltas, j.out)
+	}
+
+	grad := make([]float32, l.NumWeights())
+	dx := make([]float32, 5)
+	l.Backward(input, deltas, grad, dx)
+
+	const eps = 1e-2
+	for k := range l.Weights {
+		assert.InDelta(t, NumericGradient(loss, &l.Weights[k], eps), grad[k], 2e-3)
+		assert.InDelta(t, NumericGradient(loss, &l.Bias[k], eps), grad[len(l.Weights)+k], 2e-3)
+		assert.InDelta(t, NumericGradient(loss, &input[k], eps), dx[k], 2e-3)
+	}
+}
+
+func Test_MarshalLayerNorm(t *testing.T) {
+	rand.Seed(0)
+	n := NewNeural(&Config{
+		Inputs:     2,
+		Layout:     []int{4, 0, 1},
+		Layers:     []LayerConfig{{}, {Type: LayerNorm, Epsilon: 1e-3}},
+		Activation: []ActivationType{ActivationLinear, ActivationTanh},
+		Mode:       ModeRegression,
+		Weight:     NewNormal(0.5, 0),
+		Bias:       true,
+	})
+	for k := range n.Layers[1].Weights {
+		n.Layers[1].Weights[k], n.Layers[1].Bias[k] = rand.Float32(), rand.Float32()
+	}
+
+	dump, err := n.Marshal()
+	assert.Nil(t, err)
+	restored, err := Unmarshal(dump)
+	assert.Nil(t, err)
+	assert.Equal(t, n.Weights(), restored.Weights())
+	assert.Equal(t, float32(1e-3), restored.Layers[1].Epsilon)
+	assert.Equal(t, n.Predict([]float32{0.5, -0.5}), restored.Predict([]float32{0.5, -0.5}))
+}
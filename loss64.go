@@ -0,0 +1,72 @@
+package deep
+
+import "math"
+
+// Loss64 is the float64 counterpart of Loss, used by float64 networks
+type Loss64 interface {
+	F64(estimate, ideal [][]float64) float64
+	Df64(estimate, ideal, activation float64) float64
+}
+
+// GetLoss64 returns a float64 loss function given a LossType
+func GetLoss64(loss LossType) Loss64 {
+	if l, ok := GetLoss(loss).(Loss64); ok {
+		return l
+	}
+	return CrossEntropy{}
+}
+
+// F64 is CE(...)
+func (l CrossEntropy) F64(estimate, ideal [][]float64) float64 {
+	var sum float64
+	for i := range estimate {
+		ce := 0.0
+		for j := range estimate[i] {
+			ce += ideal[i][j] * math.Log(estimate[i][j])
+		}
+
+		sum -= ce
+	}
+	return sum / float64(len(estimate))
+}
+
+// Df64 is CE'(...)
+func (l CrossEntropy) Df64(estimate, ideal, activation float64) float64 {
+	return estimate - ideal
+}
+
+// F64 is CE(...)
+func (l BinaryCrossEntropy) F64(estimate, ideal [][]float64) float64 {
+	epsilon := 1e-16
+	var sum float64
+	for i := range estimate {
+		ce := 0.0
+		for j := range estimate[i] {
+			ce += ideal[i][j]*math.Log(estimate[i][j]+epsilon) + (1.0-ideal[i][j])*math.Log(1.0-estimate[i][j]+epsilon)
+		}
+		sum -= ce
+	}
+	return sum / float64(len(estimate))
+}
+
+// Df64 is CE'(...)
+func (l BinaryCrossEntropy) Df64(estimate, ideal, activation float64) float64 {
+	return estimate - ideal
+}
+
+// F64 is MSE(...)
+func (l MeanSquared) F64(estimate, ideal [][]float64) float64 {
+	var sum float64
+	for i := 0; i < len(estimate); i++ {
+		for j := 0; j < len(estimate[i]); j++ {
+			d := estimate[i][j] - ideal[i][j]
+			sum += d * d
+		}
+	}
+	return sum / float64(len(estimate)*len(estimate[0]))
+}
+
+// Df64 is MSE'(...)
+func (l MeanSquared) Df64(estimate, ideal, activation float64) float64 {
+	return activation * (estimate - ideal)
+}
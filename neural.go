@@ -2,6 +2,7 @@ package deep
 
 import (
 	"fmt"
+	"sync"
 )
 
 // Neural is a neural network
@@ -9,8 +10,12 @@ type Neural struct {
 // 	Shift        []float32
 // 	Significance []float32
 	Layers       []*Layer
-	Biases       [][]*Synapse
 	Config       *Config
+
+	state  *State
+	states *sync.Pool
+
+	seeds *seeder
 }
 
 // Config defines the network topology, activations, losses etc
@@ -29,46 +34,72 @@ type Config struct {
 	Mode Mode
 	// Initializer for weights: {NewNormal(σ, μ), NewUniform(σ, μ)}
 	Weight WeightInitializer `json:"-"`
+	// Initializer for the weights of each layer from its fan-in and fan-out:
+	// {GlorotUniform, HeNormal, LeCunNormal, NewOrthogonal(gain), ...}
+	// Layers may override it. Without Init or Weight, each layer defaults to
+	// DefaultInitializer of its activation and bias starts at zero.
+	Init LayerInitializer `json:"-"`
 	// Loss functions: {LossCrossEntropy, LossBinaryCrossEntropy, LossMeanSquared}
 	Loss LossType
 	// Apply bias nodes
 	Bias bool
+	// Executor distributing the work of each layer: {Sequential{}, NewChunked(), NewWorkerPool(n)}
+	Executor Executor `json:"-"`
+	// Precision of weights and computations: {PrecisionFloat32, PrecisionFloat64}
+	Precision Precision
+	// Shape of the input to spatial layers, whose Len() must equal Inputs
+	Shape *Shape `json:",omitempty"`
+	// Layers optionally declares the type of each layer in Layout, which is
+	// dense if left out. The Layout entry of a convolution is its number of
+	// filters, while pooling keeps the channels of its input and ignores it:
+	// {{Type: LayerConv2D, Kernel: 3, Padding: 1}, {Type: LayerMaxPool, Kernel: 2}}
+	// Recurrent layers {LayerLSTM, LayerGRU} use their gate activations and
+	// ignore Activation. An embedding layer replaces the category IDs in
+	// its input by vectors: {{Type: LayerEmbedding, Embeddings: ...}}
+	// Normalization layers keep the size and shape of their input and
+	// ignore Layout: {{Type: LayerBatchNorm, Momentum: 0.9}, {Type: LayerNorm}}
+	// Attention layers keep the size of their input, a window of Length
+	// steps, and ignore Layout: {{Type: LayerAttention, Length: 8, Heads: 2}}
+	// Any layer but recurrent ones can take skip connections from earlier
+	// layers: {{}, {}, {Residual: []int{0}}, {Concat: []int{-1}}}
+	// Layers can use activations registered by name:
+	// RegisterActivation("gauss", Gauss{}); {{Custom: "gauss"}}
+	Layers []LayerConfig `json:",omitempty"`
+	// Dropout rate of each layer in Layout, applied to its outputs when
+	// training. Kept outputs are scaled by 1/(1-rate), so inference is
+	// unaffected. The output layer cannot drop out.
+	Dropout []float32 `json:",omitempty"`
+	// Seed of the dropout masks, so that training runs are reproducible with
+	// any parallelism
+	Seed int64 `json:",omitempty"`
 }
 
+// Precision denotes the floating point precision a network computes in
+type Precision int
+
+const (
+	// PrecisionFloat32 computes in float32, the default
+	PrecisionFloat32 Precision = 0
+	// PrecisionFloat64 stores weights and computes in float64
+	PrecisionFloat64 Precision = 1
+)
+
 // NewNeural returns a new neural network
 func NewNeural(c *Config) *Neural {
 
-	if c.Weight == nil {
-		c.Weight = NewUniform(0.5, 0)
+	if c.Executor == nil {
+		c.Executor = NewChunked()
 	}
 	// if c.Activation == ActivationNone {
 	// taking this out...
 	// 	c.Activation = ActivationSigmoid
 	// }
 	if c.Loss == LossNone {
-		switch c.Mode {
-		case ModeMultiClass, ModeMultiLabel:
-			c.Loss = LossCrossEntropy
-		case ModeBinary:
-			c.Loss = LossBinaryCrossEntropy
-		default:
-			c.Loss = LossMeanSquared
-		}
+		c.Loss = defaultLoss(c.Mode)
 	}
 
 	layers := initializeLayers(c)
 
-	var biases [][]*Synapse
-	if c.Bias {
-		biases = make([][]*Synapse, len(layers))
-		for i := 0; i < len(layers); i++ {
-			if c.Mode == ModeRegression && i == len(layers)-1 {
-				continue
-			}
-			biases[i] = layers[i].ApplyBias(c.Weight)
-		}
-	}
-
 // 	significance := make([]float32, c.Inputs)
 // 	shift := make([]float32, c.Inputs)
 
@@ -77,99 +108,240 @@ func NewNeural(c *Config) *Neural {
 // 		shift[i] = 0.0
 // 	}
 
-	return &Neural{
+	n := &Neural{
 // 		Shift:        shift,
 // 		Significance: significance,
 		Layers:       layers,
-		Biases:       biases,
 		Config:       c,
+		seeds:        newSeeder(c.Seed),
 	}
+	n.state = newState(n, true)
+	n.states = &sync.Pool{New: func() interface{} { return n.NewState() }}
+	return n
+}
+
+// layerInitializer returns the initializer of layer i, or nil if its weights
+// are drawn from Weight or set by its constructor. Embedding vectors are
+// drawn from Weight, or N(0, 1) without it.
+func layerInitializer(c *Config, i int, l *Layer) LayerInitializer {
+	if l.Type == LayerEmbedding {
+		if c.Weight != nil {
+			return nil
+		}
+		return func(weights []float32, _, _ int) { fill(weights, NewNormal(1, 0)) }
+	}
+	if in, _ := l.Fan(); in == 0 {
+		return nil
+	}
+	if i < len(c.Layers) && c.Layers[i].Init != nil {
+		return c.Layers[i].Init
+	}
+	if c.Init != nil {
+		return c.Init
+	}
+	if c.Weight != nil {
+		return nil
+	}
+	return DefaultInitializer(l.A)
+}
+
+// defaultLoss returns the loss a network trains with in the given mode,
+// unless configured otherwise
+func defaultLoss(mode Mode) LossType {
+	switch mode {
+	case ModeMultiClass, ModeMultiLabel:
+		return LossCrossEntropy
+	case ModeBinary:
+		return LossBinaryCrossEntropy
+	}
+	return LossMeanSquared
 }
 
 func initializeLayers(c *Config) []*Layer {
+	if c.Shape != nil && c.Shape.Len() != c.Inputs {
+		panic(fmt.Sprintf("Invalid input shape - expected: %d values got: %d", c.Inputs, c.Shape.Len()))
+	}
+
 	layers := make([]*Layer, len(c.Layout))
+	inputs, shape := c.Inputs, c.Shape
 	for i := range layers {
 		act := ActivationLinear
 		if i == (len(layers)-1) && c.Mode != ModeDefault {
 			act = OutputActivation(c.Mode)
-		} else {
+		} else if i < len(c.Activation) {
 			act = c.Activation[i]
 		}
-		layers[i] = NewLayer(c.Layout[i], act)
-	}
-
-	for i := 0; i < len(layers)-1; i++ {
-		layers[i].Connect(layers[i+1], c.Weight)
-	}
-
-	for _, neuron := range layers[0].Neurons {
-		neuron.In = make([]*Synapse, c.Inputs)
-		for i := range neuron.In {
-			neuron.In[i] = NewSynapse(c.Weight())
+		var spec LayerConfig
+		if i < len(c.Layers) {
+			spec = c.Layers[i]
 		}
-	}
-
-	return layers
-}
 
-func (n *Neural) fire(training bool) {
+		if spec.Type != LayerDense && c.Precision == PrecisionFloat64 {
+			panic(fmt.Sprintf("Invalid layer %d - %s supports float32 only", i, spec.Type))
+		}
 
-	for _, b := range n.Biases {
+		switch spec.Type {
+		case LayerConv2D, LayerMaxPool, LayerAvgPool, LayerGlobalAvgPool:
+			if shape == nil {
+				panic(fmt.Sprintf("Invalid layer %d - %s requires a spatial input", i, spec.Type))
+			}
+			if spec.Type != LayerGlobalAvgPool {
+				checkWindow(i, spec, *shape)
+			}
+		case LayerLSTM, LayerGRU:
+			if i == len(layers)-1 && act != ActivationLinear {
+				panic(fmt.Sprintf("Invalid layer %d - %s cannot be the output layer in this mode", i, spec.Type))
+			}
+		}
 
-		for _, s := range b {
-			s.fire(1)
+		concat := checkSkips(c, i, spec, layers)
+		if concat > 0 {
+			inputs += concat
+			shape = nil
+		}
 
+		switch spec.Type {
+		case LayerConv2D:
+			layers[i] = NewConv2D(*shape, c.Layout[i], spec.Kernel, spec.Stride, spec.Padding, act)
+			shape = &layers[i].Out
+		case LayerMaxPool, LayerAvgPool, LayerGlobalAvgPool:
+			layers[i] = NewPooling(*shape, spec.Type, spec.Kernel, spec.Stride, spec.Padding, act)
+			shape = &layers[i].Out
+		case LayerLSTM:
+			layers[i] = NewLSTM(inputs, c.Layout[i])
+			shape = nil
+		case LayerGRU:
+			layers[i] = NewGRU(inputs, c.Layout[i])
+			shape = nil
+		case LayerEmbedding:
+			layers[i] = NewEmbedding(inputs, spec.Embeddings)
+			shape = nil
+		case LayerBatchNorm:
+			layers[i] = NewBatchNorm(inputs, spec.Momentum, spec.Epsilon, act)
+		case LayerNorm:
+			layers[i] = NewLayerNorm(inputs, spec.Epsilon, act)
+		case LayerAttention:
+			layers[i] = NewAttention(inputs, spec.Length, spec.Heads, spec.Causal, spec.Positional, act)
+			shape = nil
+		default:
+			layers[i] = NewLayer(inputs, c.Layout[i], act)
+			shape = nil
 		}
+		layers[i].Residual, layers[i].Concat = spec.Residual, spec.Concat
+		bindActivation(i, spec, layers[i])
+		bindParams(c, i, spec, layers[i])
+		checkResidual(c, i, layers)
+		checkDropout(c, i, layers[i])
+		inputs = layers[i].Size
+	}
+	checkAttention(layers)
 
+	inits := make([]LayerInitializer, len(layers))
+	for i, l := range layers {
+		inits[i] = layerInitializer(c, i, l)
 	}
 
-	for _, l := range n.Layers {
+	// Weights drawn from Weight are drawn in the same order as the former
+	// synapse graph was connected, so that seeded networks initialize
+	// identically
+	for i := 0; i < len(layers)-1; i++ {
+		next := layers[i+1]
+		if next.Type == LayerBatchNorm || next.Type == LayerNorm || inits[i+1] != nil {
+			continue
+		}
+		if next.Type != LayerDense || next.Concat != nil {
+			for k := range next.Weights {
+				next.Weights[k] = c.Weight()
+			}
+			continue
+		}
+		for j := 0; j < layers[i].Size; j++ {
+			for k := 0; k < next.Size; k++ {
+				next.Weights[k*next.Inputs+j] = c.Weight()
+			}
+		}
+	}
+	for i := range layers[0].Weights {
+		if layers[0].Type == LayerBatchNorm || layers[0].Type == LayerNorm || inits[0] != nil {
+			break
+		}
+		layers[0].Weights[i] = c.Weight()
+	}
+	for i, init := range inits {
+		if init != nil {
+			in, out := layers[i].Fan()
+			init(layers[i].Weights, in, out)
+		}
+	}
 
-		l.fire(training)
+	if c.Bias {
+		bias := c.Weight
+		if bias == nil {
+			bias = func() float32 { return 0 }
+		}
+		for i := 0; i < len(layers); i++ {
+			if c.Mode == ModeRegression && i == len(layers)-1 || !layers[i].biased() {
+				continue
+			}
+			layers[i].ApplyBias(bias)
+		}
+	}
 
+	if c.Precision == PrecisionFloat64 {
+		for _, l := range layers {
+			l.toFloat64()
+		}
 	}
 
+	return layers
 }
 
-// Forward computes a forward pass
+// Forward computes a forward pass, storing the activations in each
+// Layer.Value, or Layer.Value64 for float64 networks. It is not safe for
+// concurrent use; see ForwardState.
 func (n *Neural) Forward(input []float32, training bool) error {
-	if len(input) != n.Config.Inputs {
-		return fmt.Errorf("Invalid input dimension - expected: %d got: %d", n.Config.Inputs, len(input))
-	}
-
-	for _, nrn := range n.Layers[0].Neurons {
-
-		for i := 0; i < len(input); i++ {
-			nrn.In[i].fire(input[i])
-// 			nrn.In[i].fire((input[i] + n.Shift[i]) * n.Significance[i])
-
-		}
+	return n.ForwardState(n.state, input, training)
+}
 
+// Predict computes a forward pass and returns a prediction. It keeps no
+// state in n and is safe for concurrent use.
+func (n *Neural) Predict(input []float32) []float32 {
+	out := make([]float32, n.Layers[len(n.Layers)-1].Size)
+	if err := n.PredictInto(out, input); err != nil {
+		return nil
 	}
-
-	n.fire(training)
-	return nil
+	return out
 }
 
-// Predict computes a forward pass and returns a prediction
-func (n *Neural) Predict(input []float32) []float32 {
+// PredictInto computes a forward pass and writes the prediction into dst.
+// Activations are kept in pooled buffers, so that once warmed up it does not
+// allocate as long as layers run inline on the Executor. It keeps no state in
+// n and is safe for concurrent use.
+func (n *Neural) PredictInto(dst, input []float32) error {
+	if size := n.Layers[len(n.Layers)-1].Size; len(dst) != size {
+		return fmt.Errorf("Invalid output dimension - expected: %d got: %d", size, len(dst))
+	}
 
-	n.Forward(input, false)
+	s := n.states.Get().(*State)
+	defer n.states.Put(s)
 
-	outLayer := n.Layers[len(n.Layers)-1]
-	out := make([]float32, len(outLayer.Neurons))
-	for i, neuron := range outLayer.Neurons {
-		out[i] = neuron.Value
+	if err := n.ForwardState(s, input, false); err != nil {
+		return err
 	}
-	return out
+	if n.Config.Precision == PrecisionFloat64 {
+		for i, y := range s.Output64() {
+			dst[i] = float32(y)
+		}
+		return nil
+	}
+	copy(dst, s.Output())
+	return nil
 }
 
 // NumWeights returns the number of weights in the network
 func (n *Neural) NumWeights() (num int) {
 	for _, l := range n.Layers {
-		for _, n := range l.Neurons {
-			num += len(n.In)
-		}
+		num += l.NumWeights()
 	}
 	return
 }
@@ -0,0 +1,114 @@
+package deep
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errNotFloat64 = errors.New("Invalid precision - expected a float64 network")
+
+// ForwardState64 computes a forward pass of a float64 network into s,
+// leaving n untouched. It is safe for concurrent use as long as every
+// goroutine uses a separate State.
+func (n *Neural) ForwardState64(s *State, input []float64, training bool) error {
+	if n.Config.Precision != PrecisionFloat64 {
+		return errNotFloat64
+	}
+	if len(input) != n.Config.Inputs {
+		return fmt.Errorf("Invalid input dimension - expected: %d got: %d", n.Config.Inputs, len(input))
+	}
+
+	for i, j := range s.jobs64 {
+		j.fire(n.Config.Executor, input, training)
+		if training && s.masks[i] != nil {
+			s.drop64(i, n.Config.Dropout[i], s.Values64[i])
+		}
+		input = s.Values64[i]
+	}
+	return nil
+}
+
+// Forward64 computes a forward pass of a float64 network, storing the
+// activations in each Layer.Value64. It is not safe for concurrent use.
+func (n *Neural) Forward64(input []float64, training bool) error {
+	return n.ForwardState64(n.state, input, training)
+}
+
+// Predict64 computes a forward pass of a float64 network and returns a
+// prediction. It is safe for concurrent use.
+func (n *Neural) Predict64(input []float64) []float64 {
+	out := make([]float64, n.Layers[len(n.Layers)-1].Size)
+	if err := n.PredictInto64(out, input); err != nil {
+		return nil
+	}
+	return out
+}
+
+// PredictInto64 computes a forward pass of a float64 network and writes the
+// prediction into dst. It is safe for concurrent use.
+func (n *Neural) PredictInto64(dst, input []float64) error {
+	if size := n.Layers[len(n.Layers)-1].Size; len(dst) != size {
+		return fmt.Errorf("Invalid output dimension - expected: %d got: %d", size, len(dst))
+	}
+
+	s := n.states.Get().(*State)
+	defer n.states.Put(s)
+
+	if err := n.ForwardState64(s, input, false); err != nil {
+		return err
+	}
+	copy(dst, s.Output64())
+	return nil
+}
+
+// PredictBatch64 computes a forward pass of a float64 network for a
+// mini-batch of inputs and returns a prediction for each input
+func (n *Neural) PredictBatch64(inputs [][]float64) ([][]float64, error) {
+	if n.Config.Precision != PrecisionFloat64 {
+		return nil, errNotFloat64
+	}
+	x := make([]float64, len(inputs)*n.Config.Inputs)
+	for i, input := range inputs {
+		if len(input) != n.Config.Inputs {
+			return nil, fmt.Errorf("Invalid input dimension at %d - expected: %d got: %d", i, n.Config.Inputs, len(input))
+		}
+		copy(x[i*n.Config.Inputs:], input)
+	}
+
+	for _, l := range n.Layers {
+		y := make([]float64, len(inputs)*l.Size)
+		l.fireBatch64(n.Config.Executor, x, y, len(inputs))
+		x = y
+	}
+
+	size := n.Layers[len(n.Layers)-1].Size
+	out := make([][]float64, len(inputs))
+	for i := range out {
+		out[i] = x[i*size : (i+1)*size : (i+1)*size]
+	}
+	return out, nil
+}
+
+func (l *Layer) fireBatch64(exec Executor, in, out []float64, batch int) {
+	act := l.activation64()
+	exec.Run(batch, l.Size*l.Inputs, func(from, to int) {
+		l.fireRows64(act, in[from*l.Inputs:to*l.Inputs], out[from*l.Size:to*l.Size], to-from)
+	})
+}
+
+func (l *Layer) fireRows64(act Differentiable64, in, out []float64, batch int) {
+	MulT64(out, in, l.Weights64, batch, l.Size, l.Inputs)
+
+	for b := 0; b < batch; b++ {
+		row := out[b*l.Size : (b+1)*l.Size]
+		for j := range row {
+			if l.Bias64 != nil {
+				row[j] += l.Bias64[j]
+			}
+			row[j] = act.F64(row[j], false)
+		}
+		if l.A == ActivationSoftmax {
+			SoftmaxTo64(row, row)
+		}
+	}
+}
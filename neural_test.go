@@ -1,6 +1,7 @@
 package deep
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,7 +11,7 @@ func Test_Init(t *testing.T) {
 	n := NewNeural(&Config{
 		Inputs:     3,
 		Layout:     []int{4, 4, 2},
-		Activation: ActivationTanh,
+		Activation: []ActivationType{ActivationTanh, ActivationTanh},
 		Mode:       ModeBinary,
 		Weight:     NewUniform(0.5, 0),
 		Bias:       true,
@@ -18,7 +19,8 @@ func Test_Init(t *testing.T) {
 
 	assert.Len(t, n.Layers, len(n.Config.Layout))
 	for i, l := range n.Layers {
-		assert.Len(t, l.Neurons, n.Config.Layout[i])
+		assert.Equal(t, n.Config.Layout[i], l.Size)
+		assert.Len(t, l.Value, n.Config.Layout[i])
 	}
 }
 
@@ -26,7 +28,7 @@ func Test_Forward(t *testing.T) {
 	n := NewNeural(&Config{
 		Inputs:     3,
 		Layout:     []int{3, 3, 3},
-		Activation: ActivationReLU,
+		Activation: []ActivationType{ActivationReLU, ActivationReLU},
 		Mode:       ModeMultiClass,
 		Weight:     NewNormal(1.0, 0),
 		Bias:       true,
@@ -48,23 +50,15 @@ func Test_Forward(t *testing.T) {
 			{0.5, 0.2, 0.9},
 		},
 	}
-	for _, n := range n.Layers[1].Neurons {
-		n.A = ActivationSigmoid
-	}
+	n.Layers[1].A = ActivationSigmoid
 	for i, l := range n.Layers {
-		for j, n := range l.Neurons {
-			for k := 0; k < 3; k++ {
-				n.In[k].Weight = weights[i][j][k]
-			}
-		}
-	}
-	for _, biases := range n.Biases {
-		for _, bias := range biases {
-			bias.Weight = 1
+		for j := 0; j < l.Size; j++ {
+			copy(l.Row(j), weights[i][j])
+			l.Bias[j] = 1
 		}
 	}
 
-	err := n.Forward([]float32{0.1, 0.2, 0.7})
+	err := n.Forward([]float32{0.1, 0.2, 0.7}, false)
 	assert.Nil(t, err)
 
 	expected := [][]float32{
@@ -72,13 +66,13 @@ func Test_Forward(t *testing.T) {
 		{0.9320110830223464, 0.9684462334302945, 0.9785427102823965},
 		{0.31106226665743886, 0.27860738455524936, 0.4103303487873119},
 	}
-	for i := range n.Layers {
-		for j, n := range n.Layers[i].Neurons {
-			assert.InEpsilon(t, expected[i][j], n.Value, 1e-12)
+	for i, l := range n.Layers {
+		for j, v := range l.Value {
+			assert.InEpsilon(t, expected[i][j], v, 1e-6)
 		}
 	}
 
-	err = n.Forward([]float32{0.1, 0.2})
+	err = n.Forward([]float32{0.1, 0.2}, false)
 	assert.Error(t, err)
 }
 
@@ -86,3 +80,135 @@ func Test_NumWeights(t *testing.T) {
 	n := NewNeural(&Config{Layout: []int{5, 5, 3}})
 	assert.Equal(t, n.NumWeights(), 5*5+3*5)
 }
+
+func Test_PredictBatch(t *testing.T) {
+	n := NewNeural(&Config{
+		Inputs:     3,
+		Layout:     []int{4, 4, 3},
+		Activation: []ActivationType{ActivationTanh, ActivationSigmoid},
+		Mode:       ModeMultiClass,
+		Weight:     NewNormal(1.0, 0),
+		Bias:       true,
+	})
+
+	inputs := [][]float32{
+		{0.1, 0.2, 0.7},
+		{-1, 0, 1},
+		{0.5, 0.5, 0.5},
+	}
+	out, err := n.PredictBatch(inputs)
+	assert.Nil(t, err)
+	assert.Len(t, out, len(inputs))
+	for i, input := range inputs {
+		expected := n.Predict(input)
+		for j := range expected {
+			assert.InEpsilon(t, expected[j], out[i][j], 1e-5)
+		}
+	}
+
+	_, err = n.PredictBatch([][]float32{{0.1, 0.2, 0.7}, {0.1, 0.2}})
+	assert.Error(t, err)
+}
+
+func Test_PredictConcurrent(t *testing.T) {
+	n := NewNeural(&Config{
+		Inputs:     2,
+		Layout:     []int{8, 8, 2},
+		Activation: []ActivationType{ActivationReLU, ActivationTanh},
+		Mode:       ModeMultiClass,
+		Weight:     NewNormal(1.0, 0),
+		Bias:       true,
+	})
+
+	inputs := make([][]float32, 64)
+	expected := make([][]float32, len(inputs))
+	for i := range inputs {
+		inputs[i] = []float32{float32(i) / 10, float32(-i) / 20}
+		expected[i] = n.Predict(inputs[i])
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, input := range inputs {
+				assert.Equal(t, expected[i], n.Predict(input))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// raceEnabled is set when testing with the race detector, which makes
+// sync.Pool drop items at random
+var raceEnabled bool
+
+func Test_PredictIntoAllocs(t *testing.T) {
+	n := NewNeural(&Config{
+		Inputs:     4,
+		Layout:     []int{16, 16, 16, 3},
+		Layers:     []LayerConfig{{}, {}, {Slope: 0.3}},
+		Activation: []ActivationType{ActivationReLU, ActivationSigmoid, ActivationLeakyReLU},
+		Mode:       ModeMultiClass,
+		Weight:     NewNormal(1.0, 0),
+		Bias:       true,
+	})
+	input := []float32{0.1, 0.2, 0.3, 0.4}
+	dst := make([]float32, 3)
+
+	assert.Nil(t, n.PredictInto(dst, input))
+	assert.Equal(t, n.Predict(input), dst)
+	assert.Error(t, n.PredictInto(make([]float32, 2), input))
+
+	if raceEnabled {
+		t.Skip("allocations are not deterministic under the race detector")
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		n.PredictInto(dst, input)
+	})
+	assert.Equal(t, 0.0, allocs, "PredictInto")
+
+	allocs = testing.AllocsPerRun(100, func() {
+		n.Forward(input, false)
+	})
+	assert.Equal(t, 0.0, allocs, "Forward")
+}
+
+func Test_Float64(t *testing.T) {
+	c := &Config{
+		Inputs:     3,
+		Layout:     []int{4, 4, 3},
+		Activation: []ActivationType{ActivationReLU, ActivationSigmoid},
+		Mode:       ModeMultiClass,
+		Weight:     NewNormal(1.0, 0),
+		Bias:       true,
+	}
+	n32 := NewNeural(c)
+	c64 := *c
+	c64.Precision = PrecisionFloat64
+	n64 := NewNeural(&c64)
+	n64.ApplyWeights(n32.Weights())
+
+	assert.Equal(t, n32.NumWeights(), n64.NumWeights())
+	for _, l := range n64.Layers {
+		assert.Nil(t, l.Weights)
+		assert.Len(t, l.Weights64, l.Size*l.Inputs)
+	}
+
+	input := []float32{0.1, 0.2, 0.7}
+	expected := n32.Predict(input)
+	out64 := n64.Predict64([]float64{0.1, 0.2, 0.7})
+	out := n64.Predict(input)
+	batch, err := n64.PredictBatch64([][]float64{{0.1, 0.2, 0.7}})
+	assert.Nil(t, err)
+	for i := range expected {
+		assert.InEpsilon(t, expected[i], out64[i], 1e-5)
+		assert.InEpsilon(t, expected[i], out[i], 1e-5)
+		assert.Equal(t, out64[i], batch[0][i])
+	}
+
+	assert.Nil(t, n32.Predict64([]float64{0.1, 0.2, 0.7}))
+	_, err = n32.PredictBatch64([][]float64{{0.1, 0.2, 0.7}})
+	assert.Error(t, err)
+}
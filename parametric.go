@@ -0,0 +1,158 @@
+package deep
+
+import "fmt"
+
+// Parametric is an activation with a trainable parameter, learned next to
+// the weights for each neuron of a layer, or once for the whole layer. Its F
+// and Df are those at the initial parameter.
+type Parametric interface {
+	Differentiable
+	// Param returns the initial parameter
+	Param() float32
+	// FParam is the activation of x with parameter p
+	FParam(x, p float32) float32
+	// DfParam returns the derivatives with respect to x and p, where
+	// y = FParam(x, p)
+	DfParam(x, y, p float32) (dx, dp float32)
+}
+
+// PReLU is a rectified linear unit with a learned slope for negative inputs
+type PReLU struct{}
+
+// Param is the initial slope
+func (a PReLU) Param() float32 { return 0.25 }
+
+// F is PReLU(x) at the initial slope
+func (a PReLU) F(x float32, training bool) float32 { return a.FParam(x, a.Param()) }
+
+// Df is PReLU'(x) at the initial slope
+func (a PReLU) Df(x, y float32) float32 {
+	dx, _ := a.DfParam(x, y, a.Param())
+	return dx
+}
+
+// FParam is PReLU(x) with slope p
+func (a PReLU) FParam(x, p float32) float32 {
+	if x > 0 {
+		return x
+	}
+	return p * x
+}
+
+// DfParam returns the derivatives of PReLU(x) with slope p
+func (a PReLU) DfParam(x, y, p float32) (dx, dp float32) {
+	if x > 0 {
+		return 1, 0
+	}
+	return p, x
+}
+
+// SwishBeta is x·Sigmoid(βx) with a learned β
+type SwishBeta struct{}
+
+// Param is the initial β, for which SwishBeta is Swish
+func (a SwishBeta) Param() float32 { return 1 }
+
+// F is SwishBeta(x) at the initial β
+func (a SwishBeta) F(x float32, training bool) float32 { return a.FParam(x, a.Param()) }
+
+// Df is SwishBeta'(x) at the initial β
+func (a SwishBeta) Df(x, y float32) float32 {
+	dx, _ := a.DfParam(x, y, a.Param())
+	return dx
+}
+
+// FParam is SwishBeta(x) with β = p
+func (a SwishBeta) FParam(x, p float32) float32 {
+	return x * Logistic(x, p)
+}
+
+// DfParam returns the derivatives of SwishBeta(x) with β = p
+func (a SwishBeta) DfParam(x, y, p float32) (dx, dp float32) {
+	s := Logistic(x, p)
+	return s + p*y*(1-s), x * y * (1 - s)
+}
+
+// bindParams creates the activation parameters of layer i of c if its
+// activation is Parametric, one per neuron unless spec shares one. Recurrent
+// and embedding layers, which do not apply their activation per neuron, have
+// none.
+func bindParams(c *Config, i int, spec LayerConfig, l *Layer) {
+	a, ok := l.activation().(Parametric)
+	if !ok || l.Recurrent() || l.Type == LayerEmbedding {
+		return
+	}
+	if c.Precision == PrecisionFloat64 {
+		panic(fmt.Sprintf("Invalid layer %d - parametric activations support float32 only", i))
+	}
+	l.initParams(a, spec.SharedParam)
+}
+
+// initParams sets the parametric activation of l, with one parameter per
+// neuron or a shared one
+func (l *Layer) initParams(a Parametric, shared bool) {
+	size := l.Size
+	if shared {
+		size = 1
+	}
+	l.param, l.Params = a, make([]float32, size)
+	for k := range l.Params {
+		l.Params[k] = a.Param()
+	}
+}
+
+// paramIndex returns the index in Params of the parameter of output k
+func (l *Layer) paramIndex(k int) int {
+	if len(l.Params) == 1 {
+		return 0
+	}
+	return k
+}
+
+// activate returns the activation of output k of l at x through act, or
+// through its parametric activation if l has parameters
+func (l *Layer) activate(act Differentiable, k int, x float32, training bool) float32 {
+	if l.param != nil {
+		return l.param.FParam(x, l.Params[l.paramIndex(k)])
+	}
+	return act.F(x, training)
+}
+
+// BackwardParam adds the gradient of the activation parameter of output k
+// into grad, laid out as the weights, the bias and then Params, given the
+// loss gradient dy with respect to its activation y at the pre-activation
+// sum x. Layers without activation parameters have none.
+func (l *Layer) BackwardParam(k int, x, y, dy float32, grad []float32) {
+	if l.param == nil || grad == nil {
+		return
+	}
+	p := l.paramIndex(k)
+	_, dp := l.param.DfParam(x, y, l.Params[p])
+	grad[len(l.Weights)+len(l.Bias)+p] += dy * dp
+}
+
+// Params returns the activation parameters of each layer, nil for layers
+// without
+func (n Neural) Params() [][]float32 {
+	var params [][]float32
+	for i, l := range n.Layers {
+		if l.Params == nil {
+			continue
+		}
+		if params == nil {
+			params = make([][]float32, len(n.Layers))
+		}
+		params[i] = l.Params
+	}
+	return params
+}
+
+// ApplyParams sets the activation parameters of each layer from a slice laid
+// out as returned by Params
+func (n *Neural) ApplyParams(params [][]float32) {
+	for i, l := range n.Layers {
+		if i < len(params) {
+			copy(l.Params, params[i])
+		}
+	}
+}
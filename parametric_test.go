@@ -0,0 +1,113 @@
+package deep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParametricDerivatives(t *testing.T) {
+	for _, a := range []Parametric{PReLU{}, SwishBeta{}} {
+		for _, p := range []float32{-0.5, 0.1, 1, 2} {
+			for _, x := range []float32{-2.5, -0.7, 0.3, 1.5} {
+				f := func() float32 { return a.FParam(x, p) }
+				dx, dp := a.DfParam(x, f(), p)
+				errX := RelativeError(dx, NumericGradient(f, &x, 1e-3))
+				errP := RelativeError(dp, NumericGradient(f, &p, 1e-3))
+				assert.True(t, errX < 1e-3, "%T at %v, %v: %v", a, x, p, errX)
+				assert.True(t, errP < 1e-3, "%T at %v, %v: %v", a, x, p, errP)
+			}
+		}
+	}
+
+	assert.Equal(t, Swish{}.F(1.5, false), SwishBeta{}.F(1.5, false))
+	assert.Equal(t, float32(-0.5), PReLU{}.F(-2, false))
+}
+
+func newParametricNet(shared bool) *Neural {
+	return NewNeural(&Config{
+		Inputs:     3,
+		Layout:     []int{4, 4, 1},
+		Layers:     []LayerConfig{{}, {SharedParam: shared}},
+		Activation: []ActivationType{ActivationPReLU, ActivationSwishBeta},
+		Mode:       ModeRegression,
+		Weight:     NewNormal(1, 0),
+		Bias:       true,
+	})
+}
+
+func Test_ParametricLayers(t *testing.T) {
+	n := newParametricNet(false)
+	assert.Equal(t, []float32{0.25, 0.25, 0.25, 0.25}, n.Layers[0].Params)
+	assert.Equal(t, []float32{1, 1, 1, 1}, n.Layers[1].Params)
+	assert.Nil(t, n.Layers[2].Params)
+	assert.Equal(t, 3*4+4+4, n.Layers[0].NumWeights())
+	assert.Equal(t, (3*4+4+4)+(4*4+4+4)+4, n.NumWeights())
+
+	shared := newParametricNet(true)
+	assert.Equal(t, []float32{1}, shared.Layers[1].Params)
+	assert.Equal(t, 4*4+4+1, shared.Layers[1].NumWeights())
+
+	// Each neuron activates with its own parameter
+	input := []float32{0.5, -1, 2}
+	n.Layers[0].Params = []float32{0, 0.1, 0.2, 0.3}
+	s := n.NewState()
+	assert.NoError(t, n.ForwardState(s, input, false))
+	for k, x := range s.Sums[0] {
+		assert.Equal(t, PReLU{}.FParam(x, n.Layers[0].Params[k]), s.Values[0][k])
+		dx, _ := PReLU{}.DfParam(x, s.Values[0][k], n.Layers[0].Params[k])
+		assert.Equal(t, dx, n.Layers[0].DActivate(k, x, s.Values[0][k]))
+	}
+	batch, err := n.PredictBatch([][]float32{input})
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, n.Predict(input), batch[0], 1e-5)
+
+	grad := make([]float32, n.Layers[0].NumWeights())
+	x, y := s.Sums[0][1], s.Values[0][1]
+	n.Layers[0].BackwardParam(1, x, y, 2, grad)
+	_, dp := PReLU{}.DfParam(x, y, 0.1)
+	assert.Equal(t, 2*dp, grad[3*4+4+1])
+
+	// Params are kept in Dump
+	n.Layers[1].Params[2] = 1.5
+	dump, err := n.Marshal()
+	assert.NoError(t, err)
+	m, err := Unmarshal(dump)
+	assert.NoError(t, err)
+	assert.Equal(t, n.Layers[0].Params, m.Layers[0].Params)
+	assert.Equal(t, n.Layers[1].Params, m.Layers[1].Params)
+	assert.Equal(t, n.Predict(input), m.Predict(input))
+
+	_, err = Quantize(n, [][]float32{input})
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		NewNeural(&Config{
+			Inputs:     1,
+			Layout:     []int{2, 1},
+			Activation: []ActivationType{ActivationPReLU},
+			Precision:  PrecisionFloat64,
+		})
+	})
+}
+
+func Test_ParametricGraph(t *testing.T) {
+	g, err := NewGraph(&GraphConfig{
+		Nodes: []NodeConfig{
+			Input("x", 2),
+			Dense("h", "x", 3, ActivationPReLU),
+			Output("y", "h", 1, ModeRegression),
+		},
+		Weight: NewNormal(1, 0),
+	})
+	assert.NoError(t, err)
+	h := g.Nodes[1].Layer
+	assert.Equal(t, []float32{0.25, 0.25, 0.25}, h.Params)
+	assert.Equal(t, 2*3+3, h.NumWeights())
+
+	h.Params[0] = 0.5
+	dump, err := g.Marshal()
+	assert.NoError(t, err)
+	restored, err := UnmarshalGraph(dump)
+	assert.NoError(t, err)
+	assert.Equal(t, h.Params, restored.Nodes[1].Layer.Params)
+}
@@ -8,6 +8,9 @@ import (
 type Dump struct {
 	Config       *Config
 	Weights      [][][]float32
+	Weights64    [][][]float64 `json:",omitempty"`
+	Statistics   [][][]float32 `json:",omitempty"`
+	Params       [][]float32   `json:",omitempty"`
 // 	Significance []float32
 // 	Shift        []float32
 }
@@ -15,10 +18,8 @@ type Dump struct {
 // ApplyWeights sets the weights from a three-dimensional slice
 func (n *Neural) ApplyWeights(weights [][][]float32) {
 	for i, l := range n.Layers {
-		for j := range l.Neurons {
-			for k := range l.Neurons[j].In {
-				n.Layers[i].Neurons[j].In[k].Weight = weights[i][j][k]
-			}
+		for j := 0; j < l.Rows(); j++ {
+			l.SetNeuron(j, weights[i][j])
 		}
 	}
 }
@@ -27,22 +28,50 @@ func (n *Neural) ApplyWeights(weights [][][]float32) {
 func (n Neural) Weights() [][][]float32 {
 	weights := make([][][]float32, len(n.Layers))
 	for i, l := range n.Layers {
-		weights[i] = make([][]float32, len(l.Neurons))
-		for j, n := range l.Neurons {
-			weights[i][j] = make([]float32, len(n.In))
-			for k, in := range n.In {
-				weights[i][j][k] = in.Weight
-			}
+		weights[i] = make([][]float32, l.Rows())
+		for j := range weights[i] {
+			weights[i][j] = l.Neuron(j)
 		}
 	}
 	return weights
 }
 
-// Dump generates a network dump
+// ApplyWeights64 sets the weights of a float64 network from a
+// three-dimensional slice
+func (n *Neural) ApplyWeights64(weights [][][]float64) {
+	for i, l := range n.Layers {
+		for j := 0; j < l.Rows(); j++ {
+			l.SetNeuron64(j, weights[i][j])
+		}
+	}
+}
+
+// Weights64 returns all weights of a float64 network in sequence
+func (n Neural) Weights64() [][][]float64 {
+	weights := make([][][]float64, len(n.Layers))
+	for i, l := range n.Layers {
+		weights[i] = make([][]float64, l.Rows())
+		for j := range weights[i] {
+			weights[i][j] = l.Neuron64(j)
+		}
+	}
+	return weights
+}
+
+// Dump generates a network dump. Float64 networks dump their weights into
+// Weights64 at full precision.
 func (n Neural) Dump() *Dump {
+	if n.Config.Precision == PrecisionFloat64 {
+		return &Dump{
+			Config:    n.Config,
+			Weights64: n.Weights64(),
+		}
+	}
 	return &Dump{
 		Config:       n.Config,
 		Weights:      n.Weights(),
+		Statistics:   n.Statistics(),
+		Params:       n.Params(),
 // 		Significance: n.Significance,
 // 		Shift:        n.Shift,
 	}
@@ -51,7 +80,13 @@ func (n Neural) Dump() *Dump {
 // FromDump restores a Neural from a dump
 func FromDump(dump *Dump) *Neural {
 	n := NewNeural(dump.Config)
-	n.ApplyWeights(dump.Weights)
+	if dump.Weights64 != nil {
+		n.ApplyWeights64(dump.Weights64)
+	} else {
+		n.ApplyWeights(dump.Weights)
+	}
+	n.ApplyStatistics(dump.Statistics)
+	n.ApplyParams(dump.Params)
 // 	n.Significance = dump.Significance
 // 	n.Shift = dump.Shift
 	return n
@@ -68,5 +103,8 @@ func Unmarshal(bytes []byte) (*Neural, error) {
 	if err := json.Unmarshal(bytes, &dump); err != nil {
 		return nil, err
 	}
+	if err := dump.Config.checkActivations(); err != nil {
+		return nil, err
+	}
 	return FromDump(&dump), nil
 }
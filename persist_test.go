@@ -13,7 +13,7 @@ func Test_RestoreFromDump(t *testing.T) {
 	n := NewNeural(&Config{
 		Inputs:     1,
 		Layout:     []int{5, 3, 1},
-		Activation: ActivationSigmoid,
+		Activation: []ActivationType{ActivationSigmoid, ActivationSigmoid, ActivationSigmoid},
 		Weight:     NewUniform(0.5, 0),
 		Bias:       true,
 	})
@@ -21,10 +21,9 @@ func Test_RestoreFromDump(t *testing.T) {
 	dump := n.Dump()
 	new := FromDump(dump)
 
-	for i, biases := range n.Biases {
-		for j, bias := range biases {
-			assert.Equal(t, bias.Weight, new.Biases[i][j].Weight)
-		}
+	for i, l := range n.Layers {
+		assert.Equal(t, l.Weights, new.Layers[i].Weights)
+		assert.Equal(t, l.Bias, new.Layers[i].Bias)
 	}
 	assert.Equal(t, n.String(), new.String())
 	assert.Equal(t, n.Predict([]float32{0}), new.Predict([]float32{0}))
@@ -36,7 +35,7 @@ func Test_Marshal(t *testing.T) {
 	n := NewNeural(&Config{
 		Inputs:     1,
 		Layout:     []int{3, 3, 1},
-		Activation: ActivationSigmoid,
+		Activation: []ActivationType{ActivationSigmoid, ActivationSigmoid, ActivationSigmoid},
 		Weight:     NewUniform(0.5, 0),
 		Bias:       true,
 	})
@@ -47,11 +46,39 @@ func Test_Marshal(t *testing.T) {
 	new, err := Unmarshal(dump)
 	assert.Nil(t, err)
 
-	for i, biases := range n.Biases {
-		for j, bias := range biases {
-			assert.Equal(t, bias.Weight, new.Biases[i][j].Weight)
-		}
+	for i, l := range n.Layers {
+		assert.Equal(t, l.Weights, new.Layers[i].Weights)
+		assert.Equal(t, l.Bias, new.Layers[i].Bias)
 	}
 	assert.Equal(t, n.String(), new.String())
 	assert.Equal(t, n.Predict([]float32{0}), new.Predict([]float32{0}))
 }
+
+func Test_MarshalFloat64(t *testing.T) {
+	rand.Seed(0)
+
+	n := NewNeural(&Config{
+		Inputs:     2,
+		Layout:     []int{3, 3, 1},
+		Activation: []ActivationType{ActivationTanh, ActivationTanh},
+		Mode:       ModeRegression,
+		Weight:     NewUniform(0.5, 0),
+		Bias:       true,
+		Precision:  PrecisionFloat64,
+	})
+	n.Layers[0].Weights64[0] = 0.1234567890123
+
+	dump, err := n.Marshal()
+	assert.Nil(t, err)
+
+	new, err := Unmarshal(dump)
+	assert.Nil(t, err)
+
+	assert.Equal(t, PrecisionFloat64, new.Config.Precision)
+	for i, l := range n.Layers {
+		assert.Equal(t, l.Weights64, new.Layers[i].Weights64)
+		assert.Equal(t, l.Bias64, new.Layers[i].Bias64)
+	}
+	assert.Equal(t, n.String(), new.String())
+	assert.Equal(t, n.Predict64([]float64{0, 1}), new.Predict64([]float64{0, 1}))
+}
@@ -0,0 +1,110 @@
+package deep
+
+// NewPooling creates a new pooling layer of the given type, which slides a
+// kernel x kernel window over each channel of the input. Global average
+// pooling ignores kernel, stride and padding.
+func NewPooling(in Shape, t LayerType, kernel, stride, padding int, activation ActivationType) *Layer {
+	if stride < 1 {
+		stride = kernel
+	}
+	out := Shape{Height: 1, Width: 1, Channels: in.Channels}
+	if t == LayerGlobalAvgPool {
+		kernel, stride, padding = 0, 0, 0
+	} else {
+		out.Height = (in.Height+2*padding-kernel)/stride + 1
+		out.Width = (in.Width+2*padding-kernel)/stride + 1
+	}
+	return &Layer{
+		A:       activation,
+		Type:    t,
+		Inputs:  in.Len(),
+		Size:    out.Len(),
+		Value:   make([]float32, out.Len()),
+		In:      in,
+		Out:     out,
+		Kernel:  kernel,
+		Stride:  stride,
+		Padding: padding,
+	}
+}
+
+// bounds returns the input rows and columns [y0, y1) x [x0, x1) covered by
+// the pooling window of output pixel (oy, ox)
+func (l *Layer) bounds(oy, ox int) (y0, y1, x0, x1 int) {
+	if l.Type == LayerGlobalAvgPool {
+		return 0, l.In.Height, 0, l.In.Width
+	}
+	y0, x0 = oy*l.Stride-l.Padding, ox*l.Stride-l.Padding
+	y1, x1 = min(y0+l.Kernel, l.In.Height), min(x0+l.Kernel, l.In.Width)
+	return max(y0, 0), y1, max(x0, 0), x1
+}
+
+// pool returns the pooled value of output pixel (oy, ox) of a channel, and
+// for max pooling the index of the maximum within the channel
+func (l *Layer) pool(in []float32, oy, ox int) (float32, int) {
+	y0, y1, x0, x1 := l.bounds(oy, ox)
+	if l.Type == LayerMaxPool {
+		arg := y0*l.In.Width + x0
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				if in[y*l.In.Width+x] > in[arg] {
+					arg = y*l.In.Width + x
+				}
+			}
+		}
+		return in[arg], arg
+	}
+
+	var sum float32
+	for y := y0; y < y1; y++ {
+		for _, v := range in[y*l.In.Width+x0 : y*l.In.Width+x1] {
+			sum += v
+		}
+	}
+	return sum / float32((y1-y0)*(x1-x0)), -1
+}
+
+// firePool pools channels [from, to)
+func (j *layerJob) firePool(from, to int) {
+	l := j.l
+	area, outArea := l.In.Height*l.In.Width, l.Out.Height*l.Out.Width
+	for c := from; c < to; c++ {
+		in := j.in[c*area : (c+1)*area]
+		out := j.out[c*outArea : (c+1)*outArea]
+		for oy := 0; oy < l.Out.Height; oy++ {
+			for ox := 0; ox < l.Out.Width; ox++ {
+				v, _ := l.pool(in, oy, ox)
+				out[oy*l.Out.Width+ox] = j.activate(c*outArea+oy*l.Out.Width+ox, v)
+			}
+		}
+	}
+}
+
+// backwardPool routes the deltas of max pooling to the maximum of each
+// window, and spreads those of average pooling evenly over the window
+func (l *Layer) backwardPool(input, deltas, dx []float32) {
+	if dx == nil {
+		return
+	}
+	area, outArea := l.In.Height*l.In.Width, l.Out.Height*l.Out.Width
+	for c := 0; c < l.Out.Channels; c++ {
+		in, d := input[c*area:(c+1)*area], dx[c*area:(c+1)*area]
+		for oy := 0; oy < l.Out.Height; oy++ {
+			for ox := 0; ox < l.Out.Width; ox++ {
+				delta := deltas[c*outArea+oy*l.Out.Width+ox]
+				if l.Type == LayerMaxPool {
+					_, arg := l.pool(in, oy, ox)
+					d[arg] += delta
+					continue
+				}
+				y0, y1, x0, x1 := l.bounds(oy, ox)
+				delta /= float32((y1 - y0) * (x1 - x0))
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						d[y*l.In.Width+x] += delta
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package deep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Pooling(t *testing.T) {
+	in := Shape{Height: 4, Width: 4, Channels: 1}
+	input := []float32{
+		1, 2, 0, 1,
+		3, 4, 1, 5,
+		0, 0, 2, 2,
+		8, 0, 2, 2,
+	}
+	fire := func(l *Layer) []float32 {
+		out := make([]float32, l.Size)
+		newLayerJob(l, out).fire(Sequential{}, input, false)
+		return out
+	}
+
+	max := NewPooling(in, LayerMaxPool, 2, 0, 0, ActivationLinear)
+	assert.Equal(t, Shape{Height: 2, Width: 2, Channels: 1}, max.Out)
+	assert.Equal(t, 0, max.NumWeights())
+	assert.Equal(t, []float32{4, 5, 8, 2}, fire(max))
+
+	avg := NewPooling(in, LayerAvgPool, 2, 0, 0, ActivationLinear)
+	assert.Equal(t, []float32{2.5, 1.75, 2, 2}, fire(avg))
+
+	padded := NewPooling(in, LayerAvgPool, 3, 2, 1, ActivationLinear)
+	assert.Equal(t, Shape{Height: 2, Width: 2, Channels: 1}, padded.Out)
+	assert.Equal(t, []float32{2.5, 13.0 / 6, 2.5, 2}, fire(padded))
+
+	global := NewPooling(in, LayerGlobalAvgPool, 0, 0, 0, ActivationLinear)
+	assert.Equal(t, Shape{Height: 1, Width: 1, Channels: 1}, global.Out)
+	assert.Equal(t, []float32{33.0 / 16}, fire(global))
+}
+
+func Test_PoolingBackward(t *testing.T) {
+	in := Shape{Height: 2, Width: 4, Channels: 2}
+	input := []float32{
+		1, 2, 0, 1,
+		3, 4, 1, 5,
+
+		0, 9, 2, 2,
+		8, 0, 2, 3,
+	}
+	deltas := []float32{1, 2, 3, 4}
+
+	dx := make([]float32, in.Len())
+	NewPooling(in, LayerMaxPool, 2, 0, 0, ActivationLinear).Backward(input, deltas, nil, dx)
+	assert.Equal(t, []float32{
+		0, 0, 0, 0,
+		0, 1, 0, 2,
+
+		0, 3, 0, 0,
+		0, 0, 0, 4,
+	}, dx)
+
+	dx = make([]float32, in.Len())
+	NewPooling(in, LayerAvgPool, 2, 0, 0, ActivationLinear).Backward(input, deltas, nil, dx)
+	assert.Equal(t, []float32{
+		0.25, 0.25, 0.5, 0.5,
+		0.25, 0.25, 0.5, 0.5,
+
+		0.75, 0.75, 1, 1,
+		0.75, 0.75, 1, 1,
+	}, dx)
+
+	dx = make([]float32, in.Len())
+	NewPooling(in, LayerGlobalAvgPool, 0, 0, 0, ActivationLinear).Backward(input, deltas[:2], nil, dx)
+	assert.Equal(t, []float32{
+		0.125, 0.125, 0.125, 0.125,
+		0.125, 0.125, 0.125, 0.125,
+
+		0.25, 0.25, 0.25, 0.25,
+		0.25, 0.25, 0.25, 0.25,
+	}, dx)
+}
+
+func Test_MarshalPooling(t *testing.T) {
+	n := NewNeural(&Config{
+		Inputs: 36,
+		Shape:  &Shape{Height: 6, Width: 6, Channels: 1},
+		Layout: []int{2, 0, 0, 3},
+		Layers: []LayerConfig{
+			{Type: LayerConv2D, Kernel: 3, Padding: 1},
+			{Type: LayerMaxPool, Kernel: 2},
+			{Type: LayerGlobalAvgPool},
+		},
+		Activation: []ActivationType{ActivationReLU},
+		Mode:       ModeMultiClass,
+		Weight:     NewNormal(0.5, 0),
+		Bias:       true,
+	})
+	assert.Equal(t, Shape{Height: 3, Width: 3, Channels: 2}, n.Layers[1].Out)
+	assert.Equal(t, 2, n.Layers[3].Inputs)
+	assert.Nil(t, n.Layers[1].Bias)
+
+	input := make([]float32, 36)
+	for i := range input {
+		input[i] = float32(i%7) / 7
+	}
+
+	dump, err := n.Marshal()
+	assert.Nil(t, err)
+	restored, err := Unmarshal(dump)
+	assert.Nil(t, err)
+	assert.Equal(t, n.Config.Layers, restored.Config.Layers)
+	assert.Equal(t, n.Weights(), restored.Weights())
+	assert.Equal(t, n.Predict(input), restored.Predict(input))
+}
+
+func Test_PoolingInvalid(t *testing.T) {
+	pool := func(kernel int) func() {
+		return func() {
+			NewNeural(&Config{
+				Inputs: 4,
+				Shape:  &Shape{Height: 2, Width: 2, Channels: 1},
+				Layout: []int{1},
+				Layers: []LayerConfig{{Type: LayerMaxPool, Kernel: kernel}},
+			})
+		}
+	}
+	assert.NotPanics(t, pool(2))
+	assert.Panics(t, pool(0))
+	assert.Panics(t, pool(3))
+}
@@ -0,0 +1,238 @@
+package deep
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	math "github.com/chewxy/math32"
+)
+
+// Quantized is an int8 copy of a trained network for inference. Weights are
+// quantized symmetrically per layer, while the inputs of each layer are
+// quantized with a per-layer scale and zero-point calibrated on sample inputs.
+type Quantized struct {
+	Config *Config
+	Layers []*QuantizedLayer
+}
+
+// QuantizedLayer is a fully connected layer with int8 weights
+type QuantizedLayer struct {
+	A ActivationType
+	// Slope is the negative slope of a LeakyReLU activation, or 0 for the
+	// default
+	Slope  float32 `json:",omitempty"`
+	Inputs int
+	Size   int
+	// Weights is a Size x Inputs matrix in row-major order, where a weight w
+	// is stored as round(w / WeightScale)
+	Weights     []int8
+	WeightScale float32
+	// Bias holds one bias per neuron in units of InputScale * WeightScale, or
+	// nil if the layer has no bias
+	Bias []int32
+	// Inputs x are quantized as round(x / InputScale) + InputZero
+	InputScale float32
+	InputZero  int32
+
+	rowSums []int32
+}
+
+// Quantize returns an int8 copy of n, calibrating the input range of each
+// layer on the given sample inputs
+func Quantize(n *Neural, calibration [][]float32) (*Quantized, error) {
+	if len(calibration) == 0 {
+		return nil, errors.New("Invalid calibration - no sample inputs")
+	}
+
+	for i, l := range n.Layers {
+		if l.Type != LayerDense {
+			return nil, fmt.Errorf("Invalid layer %d - quantization supports dense layers only, got: %s", i, l.Type)
+		}
+		if l.skips() {
+			return nil, fmt.Errorf("Invalid layer %d - quantization does not support skip connections", i)
+		}
+		if l.custom != nil {
+			return nil, fmt.Errorf("Invalid layer %d - quantization does not support custom activations", i)
+		}
+		if l.param != nil {
+			return nil, fmt.Errorf("Invalid layer %d - quantization does not support parametric activations", i)
+		}
+	}
+
+	mins, maxs := make([]float32, len(n.Layers)), make([]float32, len(n.Layers))
+	s := n.NewState()
+	for _, input := range calibration {
+		if err := n.ForwardState(s, input, false); err != nil {
+			return nil, err
+		}
+		for i := range n.Layers {
+			if i == 0 {
+				observe(&mins[0], &maxs[0], input)
+			} else if n.Config.Precision == PrecisionFloat64 {
+				observe(&mins[i], &maxs[i], toFloat32(s.Values64[i-1]))
+			} else {
+				observe(&mins[i], &maxs[i], s.Values[i-1])
+			}
+		}
+	}
+
+	weights := n.Weights()
+	q := &Quantized{Config: n.Config, Layers: make([]*QuantizedLayer, len(n.Layers))}
+	for i, l := range n.Layers {
+		q.Layers[i] = quantizeLayer(l, weights[i], mins[i], maxs[i])
+	}
+	return q, nil
+}
+
+// observe widens [min, max] to cover xx
+func observe(min, max *float32, xx []float32) {
+	for _, x := range xx {
+		if x < *min {
+			*min = x
+		}
+		if x > *max {
+			*max = x
+		}
+	}
+}
+
+func quantizeLayer(l *Layer, neurons [][]float32, min, max float32) *QuantizedLayer {
+	q := &QuantizedLayer{
+		A:       l.A,
+		Slope:   l.Slope,
+		Inputs:  l.Inputs,
+		Size:    l.Size,
+		Weights: make([]int8, l.Size*l.Inputs),
+	}
+
+	// The input range [min, max] always contains 0, which therefore maps
+	// exactly onto InputZero
+	q.InputScale = (max - min) / 255
+	if q.InputScale == 0 {
+		q.InputScale = 1
+	}
+	q.InputZero = int32(Round(-128 - min/q.InputScale))
+
+	var absMax float32
+	for _, w := range neurons {
+		for _, x := range w[:l.Inputs] {
+			absMax = math.Max(absMax, math.Abs(x))
+		}
+	}
+	q.WeightScale = absMax / 127
+	if q.WeightScale == 0 {
+		q.WeightScale = 1
+	}
+
+	for j, w := range neurons {
+		for k, x := range w[:l.Inputs] {
+			q.Weights[j*l.Inputs+k] = int8(clamp(Round(x/q.WeightScale), -127, 127))
+		}
+	}
+	if len(neurons) > 0 && len(neurons[0]) > l.Inputs {
+		q.Bias = make([]int32, l.Size)
+		for j, w := range neurons {
+			q.Bias[j] = int32(Round(w[l.Inputs] / (q.InputScale * q.WeightScale)))
+		}
+	}
+	q.init()
+	return q
+}
+
+// init precomputes the weight row sums used to remove the input zero-point
+func (q *QuantizedLayer) init() {
+	q.rowSums = make([]int32, q.Size)
+	for j := range q.rowSums {
+		for _, w := range q.Weights[j*q.Inputs : (j+1)*q.Inputs] {
+			q.rowSums[j] += int32(w)
+		}
+	}
+}
+
+func clamp(x, min, max float32) float32 {
+	return math.Min(math.Max(x, min), max)
+}
+
+// quantize maps x onto the int8 input grid of q
+func (q *QuantizedLayer) quantize(dst []int8, x []float32) {
+	for i, v := range x {
+		dst[i] = int8(clamp(Round(v/q.InputScale)+float32(q.InputZero), -128, 127))
+	}
+}
+
+// fire computes the layer activations of quantized inputs with int32
+// accumulation
+func (q *QuantizedLayer) fire(in []int8, out []float32) {
+	act := GetActivation(q.A)
+	if q.A == ActivationLeakyReLU && q.Slope != 0 {
+		act = LeakyReLU{Slope: q.Slope}
+	}
+	scale := q.InputScale * q.WeightScale
+	for j := range out {
+		var acc int32
+		for k, w := range q.Weights[j*q.Inputs : (j+1)*q.Inputs] {
+			acc += int32(in[k]) * int32(w)
+		}
+		acc -= q.InputZero * q.rowSums[j]
+		if q.Bias != nil {
+			acc += q.Bias[j]
+		}
+		out[j] = act.F(float32(acc)*scale, false)
+	}
+	if q.A == ActivationSoftmax {
+		SoftmaxTo(out, out)
+	}
+}
+
+// Predict computes a quantized forward pass and returns a prediction. It is
+// safe for concurrent use.
+func (q *Quantized) Predict(input []float32) []float32 {
+	if len(input) != q.Config.Inputs {
+		return nil
+	}
+
+	var out []float32
+	for _, l := range q.Layers {
+		in := make([]int8, l.Inputs)
+		l.quantize(in, input)
+		out = make([]float32, l.Size)
+		l.fire(in, out)
+		input = out
+	}
+	return out
+}
+
+// NumWeights returns the number of weights in the network
+func (q *Quantized) NumWeights() (num int) {
+	for _, l := range q.Layers {
+		num += len(l.Weights) + len(l.Bias)
+	}
+	return
+}
+
+// Marshal marshals to JSON from a quantized network
+func (q *Quantized) Marshal() ([]byte, error) {
+	return json.Marshal(q)
+}
+
+// UnmarshalQuantized restores a quantized network from a JSON blob
+func UnmarshalQuantized(bytes []byte) (*Quantized, error) {
+	var q Quantized
+	if err := json.Unmarshal(bytes, &q); err != nil {
+		return nil, err
+	}
+	if q.Config == nil || len(q.Layers) == 0 {
+		return nil, errors.New("Invalid quantized network - no layers")
+	}
+	for i, l := range q.Layers {
+		if len(l.Weights) != l.Size*l.Inputs {
+			return nil, fmt.Errorf("Invalid quantized layer %d - expected: %d weights got: %d", i, l.Size*l.Inputs, len(l.Weights))
+		}
+		if l.Bias != nil && len(l.Bias) != l.Size {
+			return nil, fmt.Errorf("Invalid quantized layer %d - expected: %d biases got: %d", i, l.Size, len(l.Bias))
+		}
+		l.init()
+	}
+	return &q, nil
+}
@@ -0,0 +1,100 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Quantize(t *testing.T) {
+	rand.Seed(0)
+	for _, precision := range []Precision{PrecisionFloat32, PrecisionFloat64} {
+		n := NewNeural(&Config{
+			Inputs:     4,
+			Layout:     []int{8, 3},
+			Activation: []ActivationType{ActivationTanh},
+			Mode:       ModeMultiClass,
+			Weight:     NewNormal(1.0, 0),
+			Bias:       true,
+			Precision:  precision,
+		})
+
+		inputs := make([][]float32, 100)
+		for i := range inputs {
+			inputs[i] = []float32{rand.Float32(), rand.Float32()*2 - 1, rand.Float32() * 4, -rand.Float32()}
+		}
+		q, err := Quantize(n, inputs)
+		assert.Nil(t, err)
+		assert.Equal(t, n.NumWeights(), q.NumWeights())
+
+		for _, input := range inputs {
+			expected, out := n.Predict(input), q.Predict(input)
+			for i := range expected {
+				assert.InDelta(t, expected[i], out[i], 0.05)
+			}
+		}
+		assert.Nil(t, q.Predict([]float32{1}))
+	}
+
+	_, err := Quantize(NewNeural(&Config{Inputs: 1, Layout: []int{1}}), nil)
+	assert.Error(t, err)
+}
+
+func Test_MarshalQuantized(t *testing.T) {
+	rand.Seed(0)
+	n := NewNeural(&Config{
+		Inputs:     3,
+		Layout:     []int{5, 1},
+		Activation: []ActivationType{ActivationReLU},
+		Mode:       ModeRegression,
+		Weight:     NewUniform(0.5, 0),
+		Bias:       true,
+	})
+	inputs := [][]float32{{0, 1, 2}, {-1, 0.5, 3}, {2, -2, 0}}
+	q, err := Quantize(n, inputs)
+	assert.Nil(t, err)
+
+	dump, err := q.Marshal()
+	assert.Nil(t, err)
+	restored, err := UnmarshalQuantized(dump)
+	assert.Nil(t, err)
+	for _, input := range inputs {
+		assert.Equal(t, q.Predict(input), restored.Predict(input))
+	}
+
+	_, err = UnmarshalQuantized([]byte(`{"Config":{},"Layers":[{"Inputs":2,"Size":2,"Weights":[1]}]}`))
+	assert.Error(t, err)
+	_, err = UnmarshalQuantized([]byte(`{"Config":{},"Layers":[{"Inputs":1,"Size":2,"Weights":[1,2],"Bias":[3]}]}`))
+	assert.EqualError(t, err, "Invalid quantized layer 0 - expected: 2 biases got: 1")
+}
+
+func Test_QuantizeLeakySlope(t *testing.T) {
+	rand.Seed(0)
+	n := NewNeural(&Config{
+		Inputs:     2,
+		Layout:     []int{6, 1},
+		Layers:     []LayerConfig{{Slope: 0.5}},
+		Activation: []ActivationType{ActivationLeakyReLU},
+		Mode:       ModeRegression,
+		Weight:     NewNormal(1.0, 0),
+		Bias:       true,
+	})
+	inputs := make([][]float32, 50)
+	for i := range inputs {
+		inputs[i] = []float32{rand.Float32()*4 - 2, rand.Float32()*4 - 2}
+	}
+	q, err := Quantize(n, inputs)
+	assert.Nil(t, err)
+	assert.Equal(t, float32(0.5), q.Layers[0].Slope)
+
+	dump, err := q.Marshal()
+	assert.Nil(t, err)
+	restored, err := UnmarshalQuantized(dump)
+	assert.Nil(t, err)
+	for _, input := range inputs {
+		expected := n.Predict(input)
+		assert.InDelta(t, expected[0], q.Predict(input)[0], 0.2)
+		assert.Equal(t, q.Predict(input), restored.Predict(input))
+	}
+}
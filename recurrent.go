@@ -0,0 +1,284 @@
+package deep
+
+import math "github.com/chewxy/math32"
+
+// NewLSTM creates a new LSTM layer with n units over the given number of
+// inputs. Its weight rows hold the input, forget, candidate and output gates
+// of each unit in turn, each connected to the inputs followed by the hidden
+// state of the previous step.
+func NewLSTM(inputs, n int) *Layer {
+	return newRecurrent(LayerLSTM, inputs, n)
+}
+
+// NewGRU creates a new GRU layer with n units over the given number of
+// inputs. Its weight rows hold the reset, update and candidate gates of each
+// unit in turn, each connected to the inputs followed by the hidden state of
+// the previous step, which the candidate sees through the reset gate.
+func NewGRU(inputs, n int) *Layer {
+	return newRecurrent(LayerGRU, inputs, n)
+}
+
+func newRecurrent(t LayerType, inputs, n int) *Layer {
+	l := &Layer{
+		A:      ActivationLinear,
+		Type:   t,
+		Inputs: inputs,
+		Size:   n,
+		Value:  make([]float32, n),
+	}
+	l.Weights = make([]float32, l.Rows()*l.RowSize())
+	return l
+}
+
+// candidate reports whether gate row r is the tanh candidate rather than a
+// sigmoid gate, which is the third block of rows of both LSTM and GRU
+func (l *Layer) candidate(r int) bool {
+	return r/l.Size == 2
+}
+
+// fireRecurrent computes one step of a recurrent layer
+func (j *layerJob) fireRecurrent(exec Executor) {
+	l := j.l
+	h := l.Size
+	j.hidden = j.prevH
+
+	switch l.Type {
+	case LayerLSTM:
+		exec.Run(4*h, l.RowSize(), j.run)
+		in, f, g, o := j.gates[:h], j.gates[h:2*h], j.gates[2*h:3*h], j.gates[3*h:]
+		for k := range j.out {
+			c := in[k] * g[k]
+			if j.prevC != nil {
+				c += f[k] * j.prevC[k]
+			}
+			j.cell[k] = c
+			j.out[k] = o[k] * math.Tanh(c)
+		}
+	case LayerGRU:
+		exec.Run(2*h, l.RowSize(), j.run)
+		if j.prevH != nil {
+			for k, r := range j.gates[:h] {
+				j.rh[k] = r * j.prevH[k]
+			}
+			j.hidden = j.rh
+		}
+		j.run(2*h, 3*h)
+		z, n := j.gates[h:2*h], j.gates[2*h:]
+		for k := range j.out {
+			y := (1 - z[k]) * n[k]
+			if j.prevH != nil {
+				y += z[k] * j.prevH[k]
+			}
+			j.out[k] = y
+		}
+	}
+	if j.sum != nil {
+		copy(j.sum, j.out)
+	}
+	j.prevH, j.prevC, j.hidden = nil, nil, nil
+}
+
+// fireGates computes the activations of gate rows [from, to)
+func (j *layerJob) fireGates(from, to int) {
+	l := j.l
+	for r := from; r < to; r++ {
+		row := l.Row(r)
+		sum := Dot(row[:l.Inputs], j.in)
+		if j.hidden != nil {
+			sum += Dot(row[l.Inputs:], j.hidden)
+		}
+		if l.Bias != nil {
+			sum += l.Bias[r]
+		}
+		if l.candidate(r) {
+			j.gates[r] = math.Tanh(sum)
+		} else {
+			j.gates[r] = Logistic(sum, 1)
+		}
+	}
+}
+
+// BackwardSequence propagates deltas through layer i over the steps of a
+// sequence, whose forward passes are recorded in states, given the input
+// inputs[t] of the layer at each step t. prev is the state preceding the
+// first step, or nil at the start of a sequence. Recurrent layers
+// backpropagate through time up to the first step, so that splitting a
+// sequence into windows truncates the gradients at prev. Weight gradients
+// are added into grad, and input gradients into dx[t] unless dx is nil.
+func (l *Layer) BackwardSequence(i int, prev *State, states []*State, inputs, deltas [][]float32, grad []float32, dx [][]float32) {
+	dxt := func(t int) []float32 {
+		if dx == nil {
+			return nil
+		}
+		return dx[t]
+	}
+	if !l.Recurrent() {
+		for t := range states {
+			l.Backward(inputs[t], deltas[t], grad, dxt(t))
+		}
+		return
+	}
+
+	h := l.Size
+	dh, dc := make([]float32, h), make([]float32, h)
+	dz, rh := make([]float32, l.Rows()), make([]float32, h)
+	for t := len(states) - 1; t >= 0; t-- {
+		s, p := states[t], prev
+		if t > 0 {
+			p = states[t-1]
+		}
+		var prevH, prevC []float32
+		if p != nil {
+			prevH, prevC = p.Values[i], p.cells[i]
+		}
+		for k, d := range deltas[t] {
+			dh[k] += d
+		}
+
+		gates := s.gates[i]
+		switch l.Type {
+		case LayerLSTM:
+			in, f, g, o := gates[:h], gates[h:2*h], gates[2*h:3*h], gates[3*h:]
+			for k, c := range s.cells[i] {
+				tc := math.Tanh(c)
+				dck := dh[k]*o[k]*(1-tc*tc) + dc[k]
+				dz[k] = dck * g[k] * in[k] * (1 - in[k])
+				dz[h+k] = 0
+				if prevC != nil {
+					dz[h+k] = dck * prevC[k] * f[k] * (1 - f[k])
+				}
+				dz[2*h+k] = dck * in[k] * (1 - g[k]*g[k])
+				dz[3*h+k] = dh[k] * tc * o[k] * (1 - o[k])
+				dc[k] = dck * f[k]
+				dh[k] = 0
+			}
+			l.backwardGates(dz, 0, 4*h, inputs[t], prevH, grad, dxt(t), dh)
+		case LayerGRU:
+			r, z, n := gates[:h], gates[h:2*h], gates[2*h:]
+			var hidden []float32
+			if prevH != nil {
+				hidden = rh
+			}
+			for k := range dh {
+				var hp float32
+				if prevH != nil {
+					hp = prevH[k]
+				}
+				rh[k] = r[k] * hp
+				dz[2*h+k] = dh[k] * (1 - z[k]) * (1 - n[k]*n[k])
+				dz[h+k] = dh[k] * (hp - n[k]) * z[k] * (1 - z[k])
+				dh[k] *= z[k]
+				dc[k] = 0
+			}
+
+			// dc collects the gradients of the reset hidden state
+			l.backwardGates(dz, 2*h, 3*h, inputs[t], hidden, grad, dxt(t), dc)
+			for k := range dz[:h] {
+				var hp float32
+				if prevH != nil {
+					hp = prevH[k]
+				}
+				dz[k] = dc[k] * hp * r[k] * (1 - r[k])
+				dh[k] += dc[k] * r[k]
+			}
+			l.backwardGates(dz, 0, 2*h, inputs[t], prevH, grad, dxt(t), dh)
+		}
+	}
+}
+
+// backwardGates propagates dz, the gradients of the pre-activations of gate
+// rows [from, to), given the layer input x and recurrent input hidden. It adds
+// the weight gradients into grad, the input gradients into dx and the hidden
+// state gradients into dh, skipping nil slices.
+func (l *Layer) backwardGates(dz []float32, from, to int, x, hidden, grad, dx, dh []float32) {
+	size := l.RowSize()
+	for r := from; r < to; r++ {
+		d := dz[r]
+		if d == 0 {
+			continue
+		}
+		row := l.Row(r)
+		if grad != nil {
+			g := grad[r*size : (r+1)*size]
+			for k, v := range x {
+				g[k] += d * v
+			}
+			for k, v := range hidden {
+				g[l.Inputs+k] += d * v
+			}
+			if l.Bias != nil {
+				grad[len(l.Weights)+r] += d
+			}
+		}
+		if dx != nil {
+			for k, w := range row[:l.Inputs] {
+				dx[k] += w * d
+			}
+		}
+		if hidden != nil {
+			for k, w := range row[l.Inputs:] {
+				dh[k] += w * d
+			}
+		}
+	}
+}
+
+// Recurrent reports whether n has any recurrent layers
+func (n *Neural) Recurrent() bool {
+	for _, l := range n.Layers {
+		if l.Recurrent() {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset clears the recurrent state carried by s, so that the next Step
+// starts a new sequence
+func (s *State) Reset() {
+	for i := range s.gates {
+		if s.gates[i] == nil {
+			continue
+		}
+		for k := range s.Values[i] {
+			s.Values[i][k] = 0
+		}
+		for k := range s.cells[i] {
+			s.cells[i][k] = 0
+		}
+	}
+}
+
+// Step feeds the next input of a sequence to n, continuing from the
+// recurrent state kept in s, and returns the prediction for it. Streaming
+// predictions can be served concurrently with a State per stream.
+func (n *Neural) Step(s *State, input []float32) ([]float32, error) {
+	if err := n.ForwardStep(s, s, input, false); err != nil {
+		return nil, err
+	}
+	if n.Config.Precision == PrecisionFloat64 {
+		return toFloat32(s.Output64()), nil
+	}
+	out := make([]float32, len(s.Output()))
+	copy(out, s.Output())
+	return out, nil
+}
+
+// PredictSequence runs a sequence of inputs through n from a fresh state and
+// returns the prediction at every step. Networks with attention predict each
+// step from the window of steps ending at it. It is safe for concurrent use.
+func (n *Neural) PredictSequence(inputs [][]float32) ([][]float32, error) {
+	if n.Window() > 0 {
+		return n.predictWindows(inputs)
+	}
+	s := n.NewState()
+	out := make([][]float32, len(inputs))
+	for t, input := range inputs {
+		y, err := n.Step(s, input)
+		if err != nil {
+			return nil, err
+		}
+		out[t] = y
+	}
+	return out, nil
+}
@@ -0,0 +1,125 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRecurrentNet(t LayerType) *Neural {
+	return NewNeural(&Config{
+		Inputs: 2,
+		Layout: []int{3, 2},
+		Layers: []LayerConfig{{Type: t}},
+		Mode:   ModeRegression,
+		Weight: NewNormal(0.5, 0),
+		Bias:   true,
+	})
+}
+
+func Test_RecurrentBackward(t *testing.T) {
+	rand.Seed(0)
+	for _, typ := range []LayerType{LayerLSTM, LayerGRU} {
+		n := newRecurrentNet(typ)
+		l := n.Layers[0]
+		assert.Equal(t, 3, l.Size)
+		assert.Equal(t, 5, l.RowSize())
+
+		inputs := [][]float32{{0.5, -1}, {1, 0.2}, {-0.3, 0.8}, {0.1, 0.1}}
+		deltas := make([][]float32, len(inputs))
+		for i := range deltas {
+			deltas[i] = []float32{rand.Float32() - 0.5, rand.Float32() - 0.5, rand.Float32() - 0.5}
+		}
+		states := make([]*State, len(inputs))
+		for i := range states {
+			states[i] = n.NewState()
+		}
+
+		// loss = sum(deltas * h) has the given deltas as output gradients
+		loss := func() float32 {
+			var sum float32
+			for i, s := range states {
+				var prev *State
+				if i > 0 {
+					prev = states[i-1]
+				}
+				n.ForwardStep(s, prev, inputs[i], false)
+				sum += Dot(deltas[i], s.Values[0])
+			}
+			return sum
+		}
+		loss()
+
+		grad := make([]float32, l.NumWeights())
+		dx := make([][]float32, len(inputs))
+		for i := range dx {
+			dx[i] = make([]float32, 2)
+		}
+		l.BackwardSequence(0, nil, states, inputs, deltas, grad, dx)
+
+		const eps = 1e-2
+		for k := range l.Weights {
+			assert.InDelta(t, NumericGradient(loss, &l.Weights[k], eps), grad[k], 2e-3, typ.String())
+		}
+		for k := range l.Bias {
+			assert.InDelta(t, NumericGradient(loss, &l.Bias[k], eps), grad[len(l.Weights)+k], 2e-3, typ.String())
+		}
+		for i := range inputs {
+			for k := range inputs[i] {
+				assert.InDelta(t, NumericGradient(loss, &inputs[i][k], eps), dx[i][k], 2e-3, typ.String())
+			}
+		}
+	}
+}
+
+func Test_Step(t *testing.T) {
+	rand.Seed(0)
+	for _, typ := range []LayerType{LayerLSTM, LayerGRU} {
+		n := newRecurrentNet(typ)
+		inputs := [][]float32{{0.5, -1}, {1, 0.2}, {-0.3, 0.8}}
+
+		seq, err := n.PredictSequence(inputs)
+		assert.Nil(t, err)
+		assert.Equal(t, n.Predict(inputs[0]), seq[0])
+		assert.NotEqual(t, n.Predict(inputs[1]), seq[1])
+
+		s := n.NewState()
+		for i, input := range inputs {
+			out, err := n.Step(s, input)
+			assert.Nil(t, err)
+			assert.Equal(t, seq[i], out)
+		}
+		s.Reset()
+		out, _ := n.Step(s, inputs[0])
+		assert.Equal(t, seq[0], out)
+
+		_, err = n.Step(s, []float32{1})
+		assert.Error(t, err)
+
+		batch, err := n.PredictBatch(inputs)
+		assert.Nil(t, err)
+		assert.Equal(t, n.Predict(inputs[2]), batch[2])
+	}
+}
+
+func Test_MarshalRecurrent(t *testing.T) {
+	for _, typ := range []LayerType{LayerLSTM, LayerGRU} {
+		n := newRecurrentNet(typ)
+		inputs := [][]float32{{0.5, -1}, {1, 0.2}, {-0.3, 0.8}}
+
+		dump, err := n.Marshal()
+		assert.Nil(t, err)
+		restored, err := Unmarshal(dump)
+		assert.Nil(t, err)
+		assert.Equal(t, n.Weights(), restored.Weights())
+
+		expected, _ := n.PredictSequence(inputs)
+		out, _ := restored.PredictSequence(inputs)
+		assert.Equal(t, expected, out)
+	}
+
+	assert.Panics(t, func() {
+		NewNeural(&Config{Inputs: 2, Layout: []int{2}, Layers: []LayerConfig{{Type: LayerLSTM}}, Mode: ModeMultiClass})
+	})
+}
@@ -0,0 +1,111 @@
+package deep
+
+import (
+	"fmt"
+	"sync"
+)
+
+// registry holds the activations registered by name
+var registry = struct {
+	sync.RWMutex
+	activations map[string]Differentiable
+}{activations: map[string]Differentiable{}}
+
+// RegisterActivation registers a under name, so that layers can refer to it
+// through LayerConfig.Custom. Networks look their activations up when they
+// are created, so registering a name again only affects later networks.
+func RegisterActivation(name string, a Differentiable) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.activations[name] = a
+}
+
+// LookupActivation returns the activation registered under name
+func LookupActivation(name string) (Differentiable, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	a, ok := registry.activations[name]
+	if !ok {
+		return nil, fmt.Errorf("Invalid activation %q - not registered", name)
+	}
+	return a, nil
+}
+
+// checkActivations returns an error if a layer of c uses a custom activation
+// that is not registered
+func (c *Config) checkActivations() error {
+	for i, spec := range c.Layers {
+		if spec.Custom == "" {
+			continue
+		}
+		if _, err := LookupActivation(spec.Custom); err != nil {
+			return fmt.Errorf("Invalid layer %d - %v", i, err)
+		}
+	}
+	return nil
+}
+
+// activation returns the activation of l
+func (l *Layer) activation() Differentiable {
+	if l.custom != nil {
+		return l.custom
+	}
+	if l.leaky != nil {
+		return l.leaky
+	}
+	return GetActivation(l.A)
+}
+
+// activation64 returns the float64 activation of l. Custom activations
+// without a float64 implementation compute in float32.
+func (l *Layer) activation64() Differentiable64 {
+	a := l.activation()
+	if a64, ok := a.(Differentiable64); ok {
+		return a64
+	}
+	return narrowActivation{a}
+}
+
+// narrowActivation computes a float32 activation for float64 networks
+type narrowActivation struct {
+	Differentiable
+}
+
+// F64 is F(x) in float32
+func (a narrowActivation) F64(x float64, training bool) float64 {
+	return float64(a.F(float32(x), training))
+}
+
+// Df64 is Df(x, y) in float32
+func (a narrowActivation) Df64(x, y float64) float64 {
+	return float64(a.Df(float32(x), float32(y)))
+}
+
+// bindActivation looks up the custom activation of layer i declared in spec,
+// or sets the slope of its LeakyReLU
+func bindActivation(i int, spec LayerConfig, l *Layer) {
+	if l.A == ActivationCustom && spec.Custom == "" {
+		panic(fmt.Sprintf("Invalid layer %d - custom activation requires LayerConfig.Custom", i))
+	}
+	if err := l.setActivation(spec.Custom, spec.Slope); err != nil {
+		panic(fmt.Sprintf("Invalid layer %d - %v", i, err))
+	}
+}
+
+// setActivation looks up the activation of l registered as custom, if any,
+// and sets the slope of its LeakyReLU
+func (l *Layer) setActivation(custom string, slope float32) error {
+	l.Slope = slope
+	if l.A == ActivationLeakyReLU && l.Slope != 0 {
+		l.leaky = LeakyReLU{Slope: l.Slope}
+	}
+	if custom == "" {
+		return nil
+	}
+	a, err := LookupActivation(custom)
+	if err != nil {
+		return err
+	}
+	l.A, l.Custom, l.custom = ActivationCustom, custom, a
+	return nil
+}
@@ -0,0 +1,138 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// square is x²
+type square struct{}
+
+func (square) F(x float32, training bool) float32 { return x * x }
+func (square) Df(x, y float32) float32            { return 2 * x }
+
+// double is 2x
+type double struct{}
+
+func (double) F(x float32, training bool) float32 { return 2 * x }
+func (double) Df(x, y float32) float32            { return 2 }
+
+func newCustomNet(name string, precision Precision) *Neural {
+	return NewNeural(&Config{
+		Inputs:    2,
+		Layout:    []int{3, 1},
+		Layers:    []LayerConfig{{Custom: name}},
+		Mode:      ModeRegression,
+		Weight:    NewUniform(1, 0.5),
+		Precision: precision,
+	})
+}
+
+func Test_RegisterActivation(t *testing.T) {
+	rand.Seed(0)
+	RegisterActivation("test-square", square{})
+	RegisterActivation("test-double", double{})
+	a, err := LookupActivation("test-square")
+	assert.NoError(t, err)
+	assert.Equal(t, square{}, a)
+
+	// Networks in one process use their own activations
+	sq, db := newCustomNet("test-square", PrecisionFloat32), newCustomNet("test-double", PrecisionFloat32)
+	db.ApplyWeights(sq.Weights())
+	assert.Equal(t, ActivationCustom, sq.Layers[0].A)
+	assert.Equal(t, "test-double", db.Layers[0].Custom)
+
+	input := []float32{0.3, 0.6}
+	s := sq.NewState()
+	assert.NoError(t, sq.ForwardState(s, input, false))
+	for j := 0; j < 3; j++ {
+		sum := Dot(sq.Layers[0].Row(j), input)
+		assert.InDelta(t, sum*sum, s.Values[0][j], 1e-6)
+		assert.Equal(t, sum, s.Sums[0][j])
+		assert.InDelta(t, 2*sum, sq.Layers[0].DActivate(j, s.Sums[0][j], s.Values[0][j]), 1e-5)
+	}
+	s = db.NewState()
+	assert.NoError(t, db.ForwardState(s, input, false))
+	for j := 0; j < 3; j++ {
+		assert.InDelta(t, 2*Dot(db.Layers[0].Row(j), input), s.Values[0][j], 1e-6)
+	}
+
+	// Float64 networks compute custom activations in float32
+	sq64 := newCustomNet("test-square", PrecisionFloat64)
+	sq64.ApplyWeights(sq.Weights())
+	assert.InDeltaSlice(t, sq.Predict(input), sq64.Predict(input), 1e-5)
+
+	_, err = Quantize(sq, [][]float32{input})
+	assert.Error(t, err)
+}
+
+func Test_RegisterActivationInvalid(t *testing.T) {
+	assert.Panics(t, func() { newCustomNet("test-unregistered", PrecisionFloat32) })
+	assert.Panics(t, func() {
+		NewNeural(&Config{
+			Inputs:     2,
+			Layout:     []int{3, 1},
+			Activation: []ActivationType{ActivationCustom},
+			Mode:       ModeRegression,
+		})
+	})
+	_, err := LookupActivation("test-unregistered")
+	assert.EqualError(t, err, `Invalid activation "test-unregistered" - not registered`)
+}
+
+func Test_MarshalCustomActivation(t *testing.T) {
+	rand.Seed(0)
+	RegisterActivation("test-marshal", square{})
+	n := newCustomNet("test-marshal", PrecisionFloat32)
+	dump, err := n.Marshal()
+	assert.NoError(t, err)
+	assert.Contains(t, string(dump), `"Custom":"test-marshal"`)
+
+	restored, err := Unmarshal(dump)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-marshal", restored.Layers[0].Custom)
+	input := []float32{0.3, 0.6}
+	assert.Equal(t, n.Predict(input), restored.Predict(input))
+
+	registry.Lock()
+	delete(registry.activations, "test-marshal")
+	registry.Unlock()
+	_, err = Unmarshal(dump)
+	assert.EqualError(t, err, `Invalid layer 0 - Invalid activation "test-marshal" - not registered`)
+}
+
+func Test_GraphCustomActivation(t *testing.T) {
+	rand.Seed(0)
+	RegisterActivation("test-graph", square{})
+	hidden := Dense("h", "x", 3, ActivationCustom)
+	hidden.Custom = "test-graph"
+	leaky := Dense("l", "h", 3, ActivationLeakyReLU)
+	leaky.Slope = 0.3
+	g, err := NewGraph(&GraphConfig{Nodes: []NodeConfig{
+		Input("x", 2), hidden, leaky, Output("y", "l", 1, ModeRegression),
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, square{}, g.Nodes[1].Layer.activation())
+	assert.InDelta(t, -0.3, g.Nodes[2].Layer.activation().F(-1, false), 1e-6)
+
+	dump, err := g.Marshal()
+	assert.NoError(t, err)
+	restored, err := UnmarshalGraph(dump)
+	assert.NoError(t, err)
+	inputs := [][]float32{{0.3, -0.6}}
+	out, err := g.Predict(inputs)
+	assert.NoError(t, err)
+	restoredOut, err := restored.Predict(inputs)
+	assert.NoError(t, err)
+	assert.Equal(t, out, restoredOut)
+
+	graph := func(node NodeConfig) error {
+		_, err := NewGraph(&GraphConfig{Nodes: []NodeConfig{Input("x", 2), node, Output("y", "h", 1, ModeRegression)}})
+		return err
+	}
+	assert.EqualError(t, graph(Dense("h", "x", 3, ActivationCustom)), "Invalid node h - custom activation requires NodeConfig.Custom")
+	hidden.Custom = "test-graph-unregistered"
+	assert.EqualError(t, graph(hidden), `Invalid node h - Invalid activation "test-graph-unregistered" - not registered`)
+}
@@ -0,0 +1,229 @@
+package deep
+
+import "fmt"
+
+// skips reports whether l takes skip connections from earlier layers
+func (l *Layer) skips() bool {
+	return len(l.Residual) > 0 || len(l.Concat) > 0
+}
+
+// Skips reports whether any layer of n takes skip connections
+func (n *Neural) Skips() bool {
+	for _, l := range n.Layers {
+		if l.skips() {
+			return true
+		}
+	}
+	return false
+}
+
+// skipsInput reports whether the network input feeds a layer of n through a
+// skip connection
+func (n *Neural) skipsInput() bool {
+	for _, l := range n.Layers {
+		for _, sources := range [][]int{l.Residual, l.Concat} {
+			for _, src := range sources {
+				if src < 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// checkSkips panics if the skip connections of layer i of c are invalid,
+// given the layers before it, and returns the number of inputs it receives
+// through concatenation
+func checkSkips(c *Config, i int, spec LayerConfig, layers []*Layer) int {
+	if len(spec.Residual) == 0 && len(spec.Concat) == 0 {
+		return 0
+	}
+	if c.Precision == PrecisionFloat64 {
+		panic(fmt.Sprintf("Invalid layer %d - skip connections support float32 only", i))
+	}
+	switch spec.Type {
+	case LayerLSTM, LayerGRU:
+		panic(fmt.Sprintf("Invalid layer %d - %s layers cannot take skip connections", i, spec.Type))
+	case LayerConv2D, LayerMaxPool, LayerAvgPool, LayerGlobalAvgPool, LayerEmbedding:
+		if len(spec.Concat) > 0 {
+			panic(fmt.Sprintf("Invalid layer %d - %s layers cannot take concatenated inputs", i, spec.Type))
+		}
+	}
+	for _, l := range layers[:i] {
+		if l.Recurrent() {
+			panic(fmt.Sprintf("Invalid layer %d - recurrent networks cannot take skip connections", i))
+		}
+	}
+
+	for _, src := range spec.Residual {
+		if src < -1 || src >= i {
+			panic(fmt.Sprintf("Invalid residual connection to layer %d - expected a layer from -1 to %d got: %d", i, i-1, src))
+		}
+	}
+	var inputs int
+	for _, src := range spec.Concat {
+		if src < -1 || src >= i-1 {
+			panic(fmt.Sprintf("Invalid concatenation to layer %d - expected a layer from -1 to %d got: %d", i, i-2, src))
+		}
+		inputs += sourceSize(c, layers, src)
+	}
+	return inputs
+}
+
+// checkResidual panics if the outputs added to layer i differ in size from
+// its own
+func checkResidual(c *Config, i int, layers []*Layer) {
+	for _, src := range layers[i].Residual {
+		if size := sourceSize(c, layers, src); size != layers[i].Size {
+			panic(fmt.Sprintf("Invalid residual connection from %d to %d - expected: %d outputs got: %d", src, i, layers[i].Size, size))
+		}
+	}
+}
+
+// sourceSize returns the number of outputs of layer src, -1 being the
+// network input
+func sourceSize(c *Config, layers []*Layer, src int) int {
+	if src < 0 {
+		return c.Inputs
+	}
+	return layers[src].Size
+}
+
+// output returns the outputs of layer src in a forward pass into s from
+// input, -1 being the input itself
+func (s *State) output(src int, input []float32) []float32 {
+	if src < 0 {
+		return input
+	}
+	return s.Values[src]
+}
+
+// Input returns the input of layer i in the most recent forward pass into s
+// from input, which is the output of the previous layer followed by those of
+// the layers concatenated to it
+func (s *State) Input(i int, input []float32) []float32 {
+	if s.inputs[i] != nil {
+		return s.inputs[i]
+	}
+	if i == 0 {
+		return input
+	}
+	return s.Values[i-1]
+}
+
+// merge concatenates the input of layer i of a forward pass into s from
+// input, and binds the outputs added to its pre-activations
+func (n *Neural) merge(s *State, i int, input []float32) []float32 {
+	l, j := n.Layers[i], s.jobs[i]
+	j.residual = j.residual[:0]
+	for _, src := range l.Residual {
+		j.residual = append(j.residual, s.output(src, input))
+	}
+
+	if l.Concat == nil {
+		return s.Input(i, input)
+	}
+	in := s.inputs[i]
+	k := copy(in, s.output(i-1, input))
+	for _, src := range l.Concat {
+		k += copy(in[k:], s.output(src, input))
+	}
+	return in
+}
+
+// fireLayer computes the activations of layer i of a forward pass into s
+// from input, and drops out some of them when training
+func (n *Neural) fireLayer(exec Executor, s *State, i int, input []float32, training bool) {
+	s.jobs[i].fire(exec, n.merge(s, i, input), training)
+	if training && s.masks[i] != nil {
+		s.drop(i, n.Config.Dropout[i], s.Values[i])
+	}
+}
+
+// Backpropagate propagates deltas[i], the loss gradients with respect to the
+// pre-activation outputs of layer i, back through the layer given the forward
+// pass in s from input. It adds the weight gradients into grad unless it is
+// nil, and the gradients with respect to the outputs of the layers feeding
+// layer i into their deltas. Once every later layer is backpropagated, these
+// hold the sum over all outgoing paths of a layer, ready for the derivative
+// of its activation.
+func (n *Neural) Backpropagate(s *State, i int, input []float32, deltas [][]float32, grad []float32) {
+	dx := s.inputGradient(i, deltas)
+	n.Layers[i].Backward(s.Input(i, input), deltas[i], grad, dx)
+	n.route(s, i, deltas)
+}
+
+// BackpropagateBatch propagates the deltas of layer i for a mini-batch of
+// forward passes through ForwardBatch, as Backpropagate does for a single
+// one, where deltas[b] holds the deltas of each layer for example b
+func (n *Neural) BackpropagateBatch(states []*State, i int, inputs [][]float32, deltas [][][]float32, grad []float32) {
+	l := n.Layers[i]
+	if l.Type != LayerBatchNorm {
+		for b, s := range states {
+			n.Backpropagate(s, i, inputs[b], deltas[b], grad)
+		}
+		return
+	}
+
+	xs, ds := make([][]float32, len(states)), make([][]float32, len(states))
+	var dxs [][]float32
+	if i > 0 {
+		dxs = make([][]float32, len(states))
+	}
+	for b, s := range states {
+		xs[b], ds[b] = s.Input(i, inputs[b]), deltas[b][i]
+		if dxs != nil {
+			dxs[b] = s.inputGradient(i, deltas[b])
+		}
+	}
+	l.BackwardBatch(xs, ds, grad, dxs)
+	for b, s := range states {
+		n.route(s, i, deltas[b])
+	}
+}
+
+// inputGradient returns the slice the gradients with respect to the input of
+// layer i are added into, which are the deltas of the previous layer unless
+// the input is concatenated
+func (s *State) inputGradient(i int, deltas [][]float32) []float32 {
+	if dx := s.dx[i]; dx != nil {
+		for k := range dx {
+			dx[k] = 0
+		}
+		return dx
+	}
+	if i == 0 {
+		return nil
+	}
+	return deltas[i-1]
+}
+
+// route adds the input gradients of concatenated layers and the deltas of
+// residual connections of layer i into the deltas of their sources
+func (n *Neural) route(s *State, i int, deltas [][]float32) {
+	l := n.Layers[i]
+	if dx := s.dx[i]; dx != nil {
+		k := len(deltas[i-1])
+		add(deltas[i-1], dx[:k])
+		for _, src := range l.Concat {
+			size := sourceSize(n.Config, n.Layers, src)
+			if src >= 0 {
+				add(deltas[src], dx[k:k+size])
+			}
+			k += size
+		}
+	}
+	for _, src := range l.Residual {
+		if src >= 0 {
+			add(deltas[src], deltas[i])
+		}
+	}
+}
+
+// add adds x into dst
+func add(dst, x []float32) {
+	for k, v := range x {
+		dst[k] += v
+	}
+}
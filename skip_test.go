@@ -0,0 +1,83 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSkipNet() *Neural {
+	return NewNeural(&Config{
+		Inputs:     2,
+		Layout:     []int{3, 3, 3, 1},
+		Layers:     []LayerConfig{{}, {}, {Residual: []int{0}}, {Concat: []int{-1, 0}}},
+		Activation: []ActivationType{ActivationTanh, ActivationTanh, ActivationSigmoid},
+		Mode:       ModeRegression,
+		Weight:     NewNormal(0.5, 0),
+		Bias:       true,
+	})
+}
+
+func Test_Skip(t *testing.T) {
+	rand.Seed(0)
+	n := newSkipNet()
+	assert.Equal(t, 3, n.Layers[2].Inputs)
+	assert.Equal(t, 3+2+3, n.Layers[3].Inputs)
+	assert.Equal(t, []int{0}, n.Layers[2].Residual)
+	assert.Equal(t, []int{-1, 0}, n.Layers[3].Concat)
+	assert.True(t, n.Skips())
+
+	// Residual connections are added to the pre-activations and
+	// concatenations appended to the input
+	input := []float32{0.4, -0.7}
+	s := n.NewState()
+	assert.NoError(t, n.ForwardState(s, input, false))
+	l := n.Layers[2]
+	for j := 0; j < l.Size; j++ {
+		sum := Dot(l.Row(j), s.Values[1]) + l.Bias[j] + s.Values[0][j]
+		assert.InDelta(t, Sigmoid{}.F(sum, false), s.Values[2][j], 1e-6)
+	}
+	concat := append(append(append([]float32{}, s.Values[2]...), input...), s.Values[0]...)
+	assert.Equal(t, concat, s.Input(3, input))
+	assert.InDelta(t, Dot(n.Layers[3].Row(0), concat), s.Output()[0], 1e-6)
+
+	assert.Equal(t, s.Output(), n.Predict(input))
+	batch, err := n.PredictBatch([][]float32{input})
+	assert.NoError(t, err)
+	assert.Equal(t, s.Output(), batch[0])
+
+	assert.Error(t, n.ForwardStateSparse(s, NewSparse(input), false))
+}
+
+func Test_SkipInvalid(t *testing.T) {
+	config := func(layers ...LayerConfig) *Config {
+		return &Config{
+			Inputs: 2,
+			Layout: []int{3, 3, 4},
+			Layers: layers,
+			Mode:   ModeRegression,
+		}
+	}
+
+	assert.NotPanics(t, func() { NewNeural(config(LayerConfig{}, LayerConfig{Residual: []int{0}})) })
+	assert.Panics(t, func() { NewNeural(config(LayerConfig{}, LayerConfig{Residual: []int{1}})) })
+	assert.Panics(t, func() { NewNeural(config(LayerConfig{}, LayerConfig{Residual: []int{-1}})) })
+	assert.Panics(t, func() { NewNeural(config(LayerConfig{}, LayerConfig{}, LayerConfig{Residual: []int{0}})) })
+	assert.Panics(t, func() { NewNeural(config(LayerConfig{}, LayerConfig{Concat: []int{0}})) })
+	assert.Panics(t, func() { NewNeural(config(LayerConfig{}, LayerConfig{Concat: []int{-2}})) })
+	assert.Panics(t, func() { NewNeural(config(LayerConfig{Type: LayerGRU}, LayerConfig{}, LayerConfig{Concat: []int{0}})) })
+}
+
+func Test_MarshalSkip(t *testing.T) {
+	rand.Seed(0)
+	n := newSkipNet()
+	dump, err := n.Marshal()
+	assert.Nil(t, err)
+	restored, err := Unmarshal(dump)
+	assert.Nil(t, err)
+	assert.Equal(t, n.Weights(), restored.Weights())
+	assert.Equal(t, n.Layers[3].Concat, restored.Layers[3].Concat)
+	assert.Equal(t, n.Layers[2].Residual, restored.Layers[2].Residual)
+	assert.Equal(t, n.Predict([]float32{0.1, 0.2}), restored.Predict([]float32{0.1, 0.2}))
+}
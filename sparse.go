@@ -0,0 +1,151 @@
+package deep
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sparse is a sparse input vector, holding the values of its non-zero
+// elements at the given indices
+type Sparse struct {
+	Indices []int
+	Values  []float32
+}
+
+// NewSparse returns the non-zero elements of a dense vector
+func NewSparse(dense []float32) Sparse {
+	var s Sparse
+	for i, x := range dense {
+		if x != 0 {
+			s.Indices = append(s.Indices, i)
+			s.Values = append(s.Values, x)
+		}
+	}
+	return s
+}
+
+// Dense expands s into a dense vector of the given size
+func (s Sparse) Dense(size int) []float32 {
+	dense := make([]float32, size)
+	for i, idx := range s.Indices {
+		dense[idx] = s.Values[i]
+	}
+	return dense
+}
+
+func (s Sparse) validate(size int) error {
+	if len(s.Indices) != len(s.Values) {
+		return fmt.Errorf("Invalid sparse input - %d indices for %d values", len(s.Indices), len(s.Values))
+	}
+	for _, idx := range s.Indices {
+		if idx < 0 || idx >= size {
+			return fmt.Errorf("Invalid sparse input index - expected: [0, %d) got: %d", size, idx)
+		}
+	}
+	return nil
+}
+
+// ForwardSparse computes a forward pass of a sparse input, storing the
+// activations in each Layer.Value, or Layer.Value64 for float64 networks.
+// Only the weights of the non-zero inputs are read. It is not safe for
+// concurrent use; see ForwardStateSparse.
+func (n *Neural) ForwardSparse(input Sparse, training bool) error {
+	return n.ForwardStateSparse(n.state, input, training)
+}
+
+// ForwardStateSparse computes a forward pass of a sparse input into s,
+// leaving n untouched. It is safe for concurrent use as long as every
+// goroutine uses a separate State.
+func (n *Neural) ForwardStateSparse(s *State, input Sparse, training bool) error {
+	if err := input.validate(n.Config.Inputs); err != nil {
+		return err
+	}
+	if t := n.Layers[0].Type; t != LayerDense {
+		return fmt.Errorf("Invalid sparse input - first layer is %s, expected dense", t)
+	}
+	if n.skipsInput() {
+		return errors.New("Invalid sparse input - skip connections from the input require a dense input")
+	}
+
+	if n.Config.Precision == PrecisionFloat64 {
+		s.jobs64[0].fireSparse(n.Config.Executor, input, training)
+		if training && s.masks[0] != nil {
+			s.drop64(0, n.Config.Dropout[0], s.Values64[0])
+		}
+		for i := 1; i < len(s.jobs64); i++ {
+			s.jobs64[i].fire(n.Config.Executor, s.Values64[i-1], training)
+			if training && s.masks[i] != nil {
+				s.drop64(i, n.Config.Dropout[i], s.Values64[i])
+			}
+		}
+		return nil
+	}
+
+	s.jobs[0].fireSparse(n.Config.Executor, input, training)
+	if training && s.masks[0] != nil {
+		s.drop(0, n.Config.Dropout[0], s.Values[0])
+	}
+	for i := 1; i < len(s.jobs); i++ {
+		n.fireLayer(n.Config.Executor, s, i, nil, training)
+	}
+	return nil
+}
+
+// PredictSparse computes a forward pass of a sparse input and returns a
+// prediction. It is safe for concurrent use.
+func (n *Neural) PredictSparse(input Sparse) []float32 {
+	s := n.states.Get().(*State)
+	defer n.states.Put(s)
+
+	if err := n.ForwardStateSparse(s, input, false); err != nil {
+		return nil
+	}
+	if n.Config.Precision == PrecisionFloat64 {
+		return toFloat32(s.Output64())
+	}
+	out := make([]float32, len(s.Output()))
+	copy(out, s.Output())
+	return out
+}
+
+func (j *layerJob) fireSparse(exec Executor, input Sparse, training bool) {
+	j.sparse, j.isSparse = input, true
+	j.fire(exec, nil, training)
+	j.sparse, j.isSparse = Sparse{}, false
+}
+
+func (j *layerJob) fireRangeSparse(from, to int) {
+	l := j.l
+	for k := from; k < to; k++ {
+		row := l.Row(k)
+		var sum float32
+		for i, idx := range j.sparse.Indices {
+			sum += row[idx] * j.sparse.Values[i]
+		}
+		if l.Bias != nil {
+			sum += l.Bias[k]
+		}
+		j.out[k] = j.activate(k, sum)
+	}
+}
+
+func (j *layerJob64) fireSparse(exec Executor, input Sparse, training bool) {
+	j.sparse, j.isSparse = input, true
+	j.fire(exec, nil, training)
+	j.sparse, j.isSparse = Sparse{}, false
+}
+
+func (j *layerJob64) fireRangeSparse(from, to int) {
+	l := j.l
+	for k := from; k < to; k++ {
+		row := l.Row64(k)
+		var sum float64
+		for i, idx := range j.sparse.Indices {
+			sum += row[idx] * float64(j.sparse.Values[i])
+		}
+		if l.Bias64 != nil {
+			sum += l.Bias64[k]
+		}
+		j.out[k] = j.activate(k, sum)
+	}
+}
@@ -0,0 +1,39 @@
+package deep
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Sparse(t *testing.T) {
+	dense := []float32{0, 1.5, 0, 0, -2, 0}
+	s := NewSparse(dense)
+	assert.Equal(t, []int{1, 4}, s.Indices)
+	assert.Equal(t, []float32{1.5, -2}, s.Values)
+	assert.Equal(t, dense, s.Dense(len(dense)))
+}
+
+func Test_ForwardSparse(t *testing.T) {
+	for _, precision := range []Precision{PrecisionFloat32, PrecisionFloat64} {
+		n := NewNeural(&Config{
+			Inputs:     6,
+			Layout:     []int{4, 3},
+			Activation: []ActivationType{ActivationTanh},
+			Mode:       ModeMultiClass,
+			Weight:     NewNormal(1.0, 0),
+			Bias:       true,
+			Precision:  precision,
+		})
+
+		dense := []float32{0, 1.5, 0, 0, -2, 0}
+		expected := n.Predict(dense)
+		out := n.PredictSparse(NewSparse(dense))
+		for i := range expected {
+			assert.InEpsilon(t, expected[i], out[i], 1e-6)
+		}
+
+		assert.Error(t, n.ForwardSparse(Sparse{Indices: []int{6}, Values: []float32{1}}, false))
+		assert.Error(t, n.ForwardSparse(Sparse{Indices: []int{1, 2}, Values: []float32{1}}, false))
+	}
+}
@@ -0,0 +1,142 @@
+package deep
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// State holds the activations of a forward pass outside of the network, so
+// that any number of goroutines can run inference on one Neural, each with
+// its own State
+type State struct {
+	// Values holds the activations of each layer
+	Values [][]float32
+	// Values64 holds the activations of each layer of a float64 network
+	Values64 [][]float64
+	// Sums and Sums64 hold the pre-activation sums of each layer, from
+	// which the derivatives of the activations are taken
+	Sums   [][]float32
+	Sums64 [][]float64
+
+	jobs    []*layerJob
+	jobs64  []*layerJob64
+	input64 []float64
+
+	// gates and cells hold the gate activations of recurrent layers and the
+	// cell state of LSTM layers
+	gates [][]float32
+	cells [][]float32
+
+	// masks holds the dropout masks of each layer, drawn from rng
+	masks [][]float32
+	rng   *rand.Rand
+
+	// inputs holds the input of layers that concatenate skip connections,
+	// and dx the gradients with respect to it
+	inputs [][]float32
+	dx     [][]float32
+}
+
+// NewState returns a State sized for n
+func (n *Neural) NewState() *State {
+	return newState(n, false)
+}
+
+// newState returns a State sized for n, which writes into the Value of each
+// layer if shared is set
+func newState(n *Neural, shared bool) *State {
+	s := &State{}
+	s.newMasks(n)
+	if n.Config.Precision == PrecisionFloat64 {
+		s.Values64 = make([][]float64, len(n.Layers))
+		s.Sums64 = make([][]float64, len(n.Layers))
+		s.jobs64 = make([]*layerJob64, len(n.Layers))
+		s.input64 = make([]float64, n.Config.Inputs)
+		for i, l := range n.Layers {
+			s.Values64[i] = l.Value64
+			if !shared {
+				s.Values64[i] = make([]float64, l.Size)
+			}
+			s.Sums64[i] = make([]float64, l.Size)
+			s.jobs64[i] = newLayerJob64(l, s.Values64[i])
+			s.jobs64[i].sum = s.Sums64[i]
+		}
+		return s
+	}
+
+	s.Values = make([][]float32, len(n.Layers))
+	s.Sums = make([][]float32, len(n.Layers))
+	s.jobs = make([]*layerJob, len(n.Layers))
+	s.gates = make([][]float32, len(n.Layers))
+	s.cells = make([][]float32, len(n.Layers))
+	s.inputs = make([][]float32, len(n.Layers))
+	s.dx = make([][]float32, len(n.Layers))
+	for i, l := range n.Layers {
+		if l.Concat != nil {
+			s.inputs[i] = make([]float32, l.Inputs)
+			s.dx[i] = make([]float32, l.Inputs)
+		}
+		s.Values[i] = l.Value
+		if !shared {
+			s.Values[i] = make([]float32, l.Size)
+		}
+		s.Sums[i] = make([]float32, l.Size)
+		j := newLayerJob(l, s.Values[i])
+		j.sum = s.Sums[i]
+		switch l.Type {
+		case LayerLSTM:
+			s.cells[i] = make([]float32, l.Size)
+			j.cell = s.cells[i]
+		case LayerGRU:
+			j.rh = make([]float32, l.Size)
+		}
+		if l.Recurrent() {
+			s.gates[i] = make([]float32, l.Rows())
+			j.gates = s.gates[i]
+		}
+		s.jobs[i] = j
+	}
+	return s
+}
+
+// Output returns the activations of the output layer
+func (s *State) Output() []float32 {
+	return s.Values[len(s.Values)-1]
+}
+
+// Output64 returns the activations of the output layer of a float64 network
+func (s *State) Output64() []float64 {
+	return s.Values64[len(s.Values64)-1]
+}
+
+// ForwardState computes a forward pass into s, leaving n untouched. It is safe
+// for concurrent use as long as every goroutine uses a separate State. Float64
+// networks widen the input and keep their activations in s.Values64.
+// Recurrent layers treat the input as a sequence of its own.
+func (n *Neural) ForwardState(s *State, input []float32, training bool) error {
+	return n.ForwardStep(s, nil, input, training)
+}
+
+// ForwardStep computes a forward pass of the next step of a sequence into s,
+// where recurrent layers continue from the state recorded in prev, or start
+// the sequence if prev is nil. prev may be s itself.
+func (n *Neural) ForwardStep(s, prev *State, input []float32, training bool) error {
+	if len(input) != n.Config.Inputs {
+		return fmt.Errorf("Invalid input dimension - expected: %d got: %d", n.Config.Inputs, len(input))
+	}
+
+	if n.Config.Precision == PrecisionFloat64 {
+		for i, x := range input {
+			s.input64[i] = float64(x)
+		}
+		return n.ForwardState64(s, s.input64, training)
+	}
+
+	for i, j := range s.jobs {
+		if prev != nil && j.gates != nil {
+			j.prevH, j.prevC = prev.Values[i], prev.cells[i]
+		}
+		n.fireLayer(n.Config.Executor, s, i, input, training)
+	}
+	return nil
+}
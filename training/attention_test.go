@@ -0,0 +1,73 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRecallData returns sequences of random values and their negation,
+// whose target at each step is the value two steps before
+func newRecallData(size, length int) SequenceExamples {
+	data := make(SequenceExamples, size)
+	for i := range data {
+		e := SequenceExample{}
+		for t := 0; t < length; t++ {
+			x := rand.Float32()*2 - 1
+			e.Input = append(e.Input, []float32{x, -x})
+			var ideal float32
+			if t >= 2 {
+				ideal = e.Input[t-2][0]
+			}
+			e.Response = append(e.Response, []float32{ideal})
+		}
+		data[i] = e
+	}
+	return data
+}
+
+func newAttentionNet() *deep.Neural {
+	return deep.NewNeural(&deep.Config{
+		Inputs: 4 * 2,
+		Layout: []int{0, 8, 1},
+		Layers: []deep.LayerConfig{
+			{Type: deep.LayerAttention, Length: 4, Heads: 2, Causal: true, Positional: true},
+		},
+		Activation: []deep.ActivationType{deep.ActivationLinear, deep.ActivationTanh},
+		Mode:       deep.ModeRegression,
+		Weight:     deep.NewNormal(0.3, 0),
+		Bias:       true,
+	})
+}
+
+func Test_TrainAttention(t *testing.T) {
+	rand.Seed(0)
+	data, validation := newRecallData(200, 6), newRecallData(50, 6)
+
+	for _, trainer := range []SequenceTrainer{
+		NewTrainer(NewAdam(0.005, 0.9, 0.999, 1e-8), 0),
+		NewBatchTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0, 10, 2),
+	} {
+		n := newAttentionNet()
+		windows := validation.Windows(n.Window())
+		before, err := CalculateLoss(n, windows)
+		assert.NoError(t, err)
+		assert.NoError(t, trainer.TrainSequences(n, data, nil, 60, 0))
+		after, err := CalculateLoss(n, windows)
+		assert.NoError(t, err)
+		assert.True(t, after < before/4, "loss %f -> %f", before, after)
+	}
+}
+
+func Test_SequenceWindows(t *testing.T) {
+	e := SequenceExamples{{
+		Input:    [][]float32{{1}, {2}, {3}},
+		Response: [][]float32{{0}},
+	}}
+	windows := e.Windows(2)
+	assert.Len(t, windows, 1)
+	assert.Equal(t, []float32{2, 3}, windows[0].Input)
+	assert.Equal(t, []float32{0}, windows[0].Response)
+}
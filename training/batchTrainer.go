@@ -13,6 +13,7 @@ var random = rand.New(rand.NewSource(0))
 // BatchTrainer implements parallelized batch training
 type BatchTrainer struct {
 	*internalb
+	internalb64 *internalb64
 	verbosity   int
 	batchSize   int
 	parallelism int
@@ -22,33 +23,45 @@ type BatchTrainer struct {
 
 type internalb struct {
 	deltas            [][][]float32
-	partialDeltas     [][][][]float32
-	accumulatedDeltas [][][]float32
+	partialDeltas     [][][]float32
+	accumulatedDeltas [][]float32
 	moments           [][][]float32
+	states            []*deep.State
+
+	// sparse marks training on sparse inputs, where the first layer is only
+	// updated in the columns of the inputs that were non-zero in the batch
+	sparse  bool
+	touched [][]bool
+	columns [][]int
+	active  []bool
+
+	// rows holds the embedding rows each worker looked up in each layer
+	// during the batch, when only those are updated
+	rows [][][]int
+	seen [][]bool
 }
 
-func newBatchTraining(layers []*deep.Layer, parallelism int) *internalb {
+func newBatchTraining(n *deep.Neural, parallelism int) *internalb {
 	deltas := make([][][]float32, parallelism)
-	partialDeltas := make([][][][]float32, parallelism)
-	accumulatedDeltas := make([][][]float32, len(layers))
+	partialDeltas := make([][][]float32, parallelism)
+	accumulatedDeltas := make([][]float32, len(n.Layers))
+	states := make([]*deep.State, parallelism)
 	for w := 0; w < parallelism; w++ {
-		deltas[w] = make([][]float32, len(layers))
-		partialDeltas[w] = make([][][]float32, len(layers))
-
-		for i, l := range layers {
-			deltas[w][i] = make([]float32, len(l.Neurons))
-			accumulatedDeltas[i] = make([][]float32, len(l.Neurons))
-			partialDeltas[w][i] = make([][]float32, len(l.Neurons))
-			for j, n := range l.Neurons {
-				partialDeltas[w][i][j] = make([]float32, len(n.In))
-				accumulatedDeltas[i][j] = make([]float32, len(n.In))
-			}
+		deltas[w] = make([][]float32, len(n.Layers))
+		partialDeltas[w] = make([][]float32, len(n.Layers))
+		states[w] = n.NewState()
+
+		for i, l := range n.Layers {
+			deltas[w][i] = make([]float32, l.Size)
+			partialDeltas[w][i] = make([]float32, l.NumWeights())
+			accumulatedDeltas[i] = make([]float32, l.NumWeights())
 		}
 	}
 	return &internalb{
 		deltas:            deltas,
 		partialDeltas:     partialDeltas,
 		accumulatedDeltas: accumulatedDeltas,
+		states:            states,
 	}
 }
 
@@ -63,7 +76,9 @@ func NewBatchTrainer(solver Solver, verbosity, batchSize, parallelism int) *Batc
 	}
 }
 
-func CalculateLoss(n *deep.Neural, examples Examples) float32 {
+// CalculateLoss returns the loss of n on examples, or an error if an example
+// does not match the network
+func CalculateLoss(n *deep.Neural, examples Examples) (float32, error) {
 
 	train := make(Examples, len(examples))
 	copy(train, examples)
@@ -120,166 +135,186 @@ func CalculateLoss(n *deep.Neural, examples Examples) float32 {
 // 	return -1.0
 // }
 
-// Train trains n
+// Train trains n. Float64 networks are trained through Train64, recurrent
+// networks on sequences of one step, and networks with batch normalization
+// one mini-batch at a time.
 func (t *BatchTrainer) Train(n *deep.Neural, examples, validation Examples, iterations int) {
+	if n.Config.Precision == deep.PrecisionFloat64 {
+		t.Train64(n, examples.Float64(), validation.Float64(), iterations)
+		return
+	}
+	if n.Recurrent() {
+		t.TrainSequences(n, examples.Sequences(), validation.Sequences(), iterations, 0)
+		return
+	}
+	if n.BatchNormalized() {
+		t.trainBatchNorm(n, examples, validation, iterations)
+		return
+	}
 
-	t.internalb = newBatchTraining(n.Layers, t.parallelism)
+	t.internalb = newBatchTraining(n, t.parallelism)
+	t.trackRows(n)
 
-	train := make(Examples, len(examples))
-	copy(train, examples)
+	t.printer.Init(n)
+	t.run(n.NumWeights(), len(examples), iterations, func(i, wid int) error {
+		e := examples[i]
+		n.ForwardState(t.states[wid], e.Input, true)
+		t.calculateDeltas(n, e.Input, e.Response, wid)
+		t.lookupRows(n, e.Input, wid)
+		return nil
+	}, func(it int) {
+		t.update(n, it)
+	}, func(elapsed time.Duration, it int) {
+		if len(validation) > 0 {
+			t.printer.PrintProgress(n, validation, elapsed, it)
+		}
+	})
+}
 
-	workCh := make(chan Example, t.parallelism)
-	nets := make([]*deep.Neural, t.parallelism)
+// run performs iterations passes over size examples in shuffled
+// mini-batches, where learn accumulates the deltas of example i on worker
+// wid, and update applies the accumulated deltas to numWeights weights
+// after every batch. It stops at the first batch where learn fails, without
+// updating the weights, and returns the error.
+func (t *BatchTrainer) run(numWeights, size, iterations int, learn func(i, wid int) error, update func(it int), progress func(elapsed time.Duration, it int)) error {
+	train := make([]int, size)
+	for i := range train {
+		train[i] = i
+	}
 
 	wg := sync.WaitGroup{}
-
-	for i := 0; i < t.parallelism; i++ {
-		nets[i] = deep.NewNeural(n.Config)
-
-		go func(id int, workCh <-chan Example) {
-			n := nets[id]
+	errs := make([]error, t.parallelism)
+
+	// Workers share the network, each forwarding into its own state. Weights are
+	// only updated between batches, while no worker is running. Each worker
+	// learns the same slots of every batch, so that its dropout masks and its
+	// share of the deltas do not depend on scheduling.
+	workChs := make([]chan int, t.parallelism)
+	for i := range workChs {
+		workChs[i] = make(chan int, (t.batchSize+t.parallelism-1)/t.parallelism)
+		defer close(workChs[i])
+
+		go func(id int, workCh <-chan int) {
 			for e := range workCh {
-				n.Forward(e.Input, true)
-				t.calculateDeltas(n, e.Response, id)
+				if err := learn(e, id); err != nil && errs[id] == nil {
+					errs[id] = err
+				}
 				wg.Done()
 			}
-		}(i, workCh)
+		}(i, workChs[i])
 	}
 
-	t.printer.Init(n)
-	t.solver.Init(n.NumWeights())
+	t.solver.Init(numWeights)
 
 	ts := time.Now()
 	for it := 1; it <= iterations; it++ {
 
-		train.Shuffle()
-		batches := train.SplitSize(t.batchSize)
-
-		for _, b := range batches {
-			currentWeights := n.Weights()
-			for _, n := range nets {
-				n.ApplyWeights(currentWeights)
-			}
+		for i := range train {
+			j := rand.Intn(i + 1)
+			train[i], train[j] = train[j], train[i]
+		}
 
-			wg.Add(len(b))
+		for b := 0; b < len(train); b += t.batchSize {
+			batch := train[b:min(b+t.batchSize, len(train))]
+			wg.Add(len(batch))
 
-			for _, item := range b {
-				workCh <- item
+			for k, item := range batch {
+				workChs[k%t.parallelism] <- item
 			}
 			wg.Wait()
-
-			ch := make(chan bool, len(t.partialDeltas))
+			for _, err := range errs {
+				if err != nil {
+					return err
+				}
+			}
 
 			for _, wPD := range t.partialDeltas {
-
-				go func(wPD [][][]float32) {
-
-					for i, iPD := range wPD {
-						iAD := t.accumulatedDeltas[i]
-						for j, jPD := range iPD {
-							jAD := iAD[j]
-							for k, v := range jPD {
-								jAD[k] += v
-								jPD[k] = 0
-							}
-						}
+				for i, iPD := range wPD {
+					if t.rows != nil && t.seen[i] != nil {
+						// merged row by row in updateRows
+						continue
 					}
-					ch <- false
-				}(wPD)
-			}
-
-			for _, _ = range t.partialDeltas {
-				<-ch
+					if i == 0 && t.sparse {
+						// merged column by column in updateColumns
+						continue
+					}
+					iAD := t.accumulatedDeltas[i]
+					for k, v := range iPD {
+						iAD[k] += v
+						iPD[k] = 0
+					}
+				}
 			}
 
-			t.update(n, it)
+			update(it)
 
 		}
 
-		if t.verbosity > 0 && it%t.verbosity == 0 && len(validation) > 0 {
-			t.printer.PrintProgress(n, validation, time.Since(ts), it)
+		if t.verbosity > 0 && it%t.verbosity == 0 {
+			progress(time.Since(ts), it)
 		}
 	}
+	return nil
 }
 
-func (t *BatchTrainer) calculateDeltas(n *deep.Neural, ideal []float32, wid int) {
-	loss := deep.GetLoss(n.Config.Loss)
+func (t *BatchTrainer) calculateDeltas(n *deep.Neural, input, ideal []float32, wid int) {
+	t.backpropagate(n, input, ideal, wid)
+	n.Layers[0].Backward(input, t.deltas[wid][0], t.partialDeltas[wid][0], nil)
+}
+
+// backpropagate computes the deltas of worker wid and accumulates the
+// gradients of all layers but the first, whose gradients depend on how the
+// input is represented
+func (t *BatchTrainer) backpropagate(n *deep.Neural, input, ideal []float32, wid int) {
 	deltas := t.deltas[wid]
 	partialDeltas := t.partialDeltas[wid]
-	lastDeltas := deltas[len(n.Layers)-1]
-
-	for i, n := range n.Layers[len(n.Layers)-1].Neurons {
-		lastDeltas[i] = loss.Df(
-			n.Value,
-			ideal[i],
-			n.DActivate(n.Value))
-	}
-
-	for i := len(n.Layers) - 2; i >= 0; i-- {
-
-		l := n.Layers[i]
-		iD := deltas[i]
-		nextD := deltas[i+1]
-
-		for j, n := range l.Neurons {
-			var sum float32
-			for k, s := range n.Out {
-				sum += s.Weight * nextD[k]
-			}
-			iD[j] = n.DActivate(n.Value) * sum
-		}
-
-	}
-
-	for i, l := range n.Layers {
-
-		iD := deltas[i]
-		iPD := partialDeltas[i]
-		for j, n := range l.Neurons {
-			jD := iD[j]
-			jPD := iPD[j]
-			for k, s := range n.In {
-				jPD[k] += jD * s.In
-			}
-		}
-
-	}
+	state := t.states[wid]
+	last := len(n.Layers) - 1
 
+	outputDeltas(n, state, ideal, deltas[last], partialDeltas[last])
+	backpropagate(n, state, input, deltas, partialDeltas, partialDeltas)
 }
 
 func (t *BatchTrainer) update(n *deep.Neural, it int) {
-	// var idx int
-
-	mut := sync.Mutex{}
-	ch := make(chan bool, len(n.Layers))
-	for i, l := range n.Layers {
-		go func(l *deep.Layer, i int) {
-			idx := i
-			iAD := t.accumulatedDeltas[i]
-			for j, n := range l.Neurons {
-				jAD := iAD[j]
-				for k, s := range n.In {
-
-					update := t.solver.Update(s.Weight,
-						jAD[k],
-						it,
-						idx)
-
-					mut.Lock()
-					s.Weight += update
-					mut.Unlock()
-
-					jAD[k] = 0
-
-					idx++
+	t.updateLayers(n.Layers, it)
+}
+
+// updateLayers applies the accumulated deltas of each layer, skipping nil
+// layers, whose deltas are empty
+func (t *BatchTrainer) updateLayers(layers []*deep.Layer, it int) {
+	wg := sync.WaitGroup{}
+	var offset int
+	for i, l := range layers {
+		if l == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, l *deep.Layer, iAD []float32, idx int) {
+			if i == 0 && t.sparse {
+				t.updateColumns(l, iAD, it, idx)
+			} else if t.rows != nil && t.seen[i] != nil {
+				t.updateRows(i, l, it, idx)
+			} else {
+				for k := range l.Weights {
+					l.Weights[k] += t.solver.Update(l.Weights[k], iAD[k], it, idx+k)
+					iAD[k] = 0
 				}
 			}
-
-			ch <- false
-		}(l, i)
-	}
-
-	for <-ch {
-
+			bAD := iAD[len(l.Weights):]
+			idx += len(l.Weights)
+			for k := range l.Bias {
+				l.Bias[k] += t.solver.Update(l.Bias[k], bAD[k], it, idx+k)
+				bAD[k] = 0
+			}
+			pAD := bAD[len(l.Bias):]
+			idx += len(l.Bias)
+			for k := range l.Params {
+				l.Params[k] += t.solver.Update(l.Params[k], pAD[k], it, idx+k)
+				pAD[k] = 0
+			}
+			wg.Done()
+		}(i, l, t.accumulatedDeltas[i], offset)
+		offset += l.NumWeights()
 	}
+	wg.Wait()
 }
@@ -0,0 +1,101 @@
+package training
+
+import (
+	"time"
+
+	deep "github.com/nathanleary/neural-net"
+)
+
+// batchPass holds the buffers of a forward and backward pass of a
+// mini-batch one layer at a time
+type batchPass struct {
+	states []*deep.State
+	// deltas of each example and layer
+	deltas [][][]float32
+	inputs [][]float32
+	// layerInputs gathers the inputs of batch normalization layers
+	layerInputs [][]float32
+}
+
+func newBatchPass(n *deep.Neural, size int) *batchPass {
+	b := &batchPass{
+		states:      make([]*deep.State, size),
+		deltas:      make([][][]float32, size),
+		inputs:      make([][]float32, size),
+		layerInputs: make([][]float32, size),
+	}
+	for k := range b.states {
+		b.states[k] = n.NewState()
+		b.deltas[k] = make([][]float32, len(n.Layers))
+		for i, l := range n.Layers {
+			b.deltas[k][i] = make([]float32, l.Size)
+		}
+	}
+	return b
+}
+
+// learn forwards and backpropagates batch, adding the weight gradients of
+// each layer into grads. It leaves grads untouched if batch is invalid.
+func (b *batchPass) learn(n *deep.Neural, batch Examples, grads [][]float32) error {
+	states, inputs, deltas := b.states[:len(batch)], b.inputs[:len(batch)], b.deltas[:len(batch)]
+	for k, e := range batch {
+		inputs[k] = e.Input
+	}
+	if err := n.ForwardBatchInto(b.layerInputs, states, inputs, true); err != nil {
+		return err
+	}
+
+	last := len(n.Layers) - 1
+	for k, s := range states {
+		outputDeltas(n, s, batch[k].Response, deltas[k][last], grads[last])
+		for _, d := range deltas[k][:last] {
+			for j := range d {
+				d[j] = 0
+			}
+		}
+	}
+
+	for i := last; i >= 0; i-- {
+		n.BackpropagateBatch(states, i, inputs, deltas, grads[i])
+		if i == 0 {
+			break
+		}
+		for k, s := range states {
+			dactivate(n.Layers[i-1], s, i-1, deltas[k][i-1], grads[i-1])
+		}
+	}
+	return nil
+}
+
+// trainBatchNorm trains a network with batch normalization layers, passing
+// each mini-batch through the network one layer at a time so that they
+// normalize with the statistics of the batch. The layers distribute their
+// work through the Executor of n rather than the trainer's workers. It stops
+// at the first invalid batch, without updating the weights, and returns its
+// error.
+func (t *BatchTrainer) trainBatchNorm(n *deep.Neural, examples, validation Examples, iterations int) error {
+	t.internalb = newBatchTraining(n, 1)
+	pass := newBatchPass(n, t.batchSize)
+
+	train := make(Examples, len(examples))
+	copy(train, examples)
+
+	t.printer.Init(n)
+	t.solver.Init(n.NumWeights())
+
+	ts := time.Now()
+	for it := 1; it <= iterations; it++ {
+		train.Shuffle()
+		for _, batch := range train.SplitSize(t.batchSize) {
+			if err := pass.learn(n, batch, t.accumulatedDeltas); err != nil {
+				return err
+			}
+			t.update(n, it)
+		}
+
+		if t.verbosity > 0 && it%t.verbosity == 0 && len(validation) > 0 {
+			t.printer.PrintProgress(n, validation, time.Since(ts), it)
+		}
+	}
+	return nil
+}
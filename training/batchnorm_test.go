@@ -0,0 +1,90 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TrainBatchNorm(t *testing.T) {
+	rand.Seed(0)
+	var data Examples
+	for i := 0; i < 400; i++ {
+		x := []float32{rand.Float32()*10 + 5, rand.Float32()*4 - 2}
+		data = append(data, Example{x, []float32{(x[0]-10)*x[1]/10 + 1}})
+	}
+
+	newNet := func() *deep.Neural {
+		return deep.NewNeural(&deep.Config{
+			Inputs:     2,
+			Layout:     []int{16, 0, 8, 1},
+			Layers:     []deep.LayerConfig{{}, {Type: deep.LayerBatchNorm}},
+			Activation: []deep.ActivationType{deep.ActivationLinear, deep.ActivationReLU, deep.ActivationTanh},
+			Mode:       deep.ModeRegression,
+			Weight:     deep.NewNormal(0.5, 0),
+			Bias:       true,
+		})
+	}
+
+	for _, trainer := range []Trainer{
+		NewBatchTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0, 16, 1),
+		NewTrainer(NewAdam(0.005, 0.9, 0.999, 1e-8), 0),
+	} {
+		n := newNet()
+		trainer.Train(n, append(Examples{}, data...), nil, 150)
+		loss, err := crossValidate(n, data)
+		assert.NoError(t, err)
+		assert.True(t, loss < 0.05)
+	}
+}
+
+func Test_TrainBatchNormStatistics(t *testing.T) {
+	rand.Seed(0)
+	var data Examples
+	for i := 0; i < 64; i++ {
+		data = append(data, Example{[]float32{rand.Float32()*2 + 3}, []float32{rand.Float32()}})
+	}
+	n := deep.NewNeural(&deep.Config{
+		Inputs: 1,
+		Layout: []int{0, 1},
+		Layers: []deep.LayerConfig{{Type: deep.LayerBatchNorm}},
+		Mode:   deep.ModeRegression,
+		Weight: deep.NewNormal(0.5, 0),
+	})
+
+	NewBatchTrainer(NewSGD(0, 0, 0, false), 0, 64, 1).Train(n, data, nil, 100)
+
+	// The running statistics converge to those of the data
+	var mean, variance float32
+	for _, e := range data {
+		mean += e.Input[0] / 64
+	}
+	for _, e := range data {
+		variance += (e.Input[0] - mean) * (e.Input[0] - mean) / 63
+	}
+	assert.InDelta(t, mean, n.Layers[0].Mean[0], 1e-3)
+	assert.InDelta(t, variance, n.Layers[0].Variance[0], 1e-3)
+}
+
+func Test_TrainBatchNormInvalid(t *testing.T) {
+	n := deep.NewNeural(&deep.Config{
+		Inputs: 2,
+		Layout: []int{0, 1},
+		Layers: []deep.LayerConfig{{Type: deep.LayerBatchNorm}},
+		Mode:   deep.ModeRegression,
+		Weight: deep.NewNormal(0.5, 0),
+	})
+	data := Examples{
+		{Input: []float32{1, 2}, Response: []float32{1}},
+		{Input: []float32{1}, Response: []float32{0}},
+	}
+	weights := n.Weights()
+
+	trainer := NewBatchTrainer(NewSGD(0.1, 0, 0, false), 0, 2, 1)
+	trainer.internalb = newBatchTraining(n, 1)
+	err := trainer.trainBatchNorm(n, data, nil, 1)
+	assert.EqualError(t, err, "Invalid input dimension at 1 - expected: 2 got: 1")
+	assert.Equal(t, weights, n.Weights())
+}
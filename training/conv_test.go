@@ -0,0 +1,86 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+// bars returns 5x5 images of a single vertical or horizontal bar
+func bars(size int) Examples {
+	var data Examples
+	for i := 0; i < size; i++ {
+		img := make([]float32, 25)
+		pos, vertical := rand.Intn(5), rand.Intn(2) == 0
+		for k := 0; k < 5; k++ {
+			if vertical {
+				img[k*5+pos] = 1
+			} else {
+				img[pos*5+k] = 1
+			}
+		}
+		if vertical {
+			data = append(data, Example{img, []float32{1, 0}})
+		} else {
+			data = append(data, Example{img, []float32{0, 1}})
+		}
+	}
+	return data
+}
+
+func newConvNet() *deep.Neural {
+	return deep.NewNeural(&deep.Config{
+		Inputs:     25,
+		Shape:      &deep.Shape{Height: 5, Width: 5, Channels: 1},
+		Layout:     []int{4, 2},
+		Layers:     []deep.LayerConfig{{Type: deep.LayerConv2D, Kernel: 3, Stride: 2, Padding: 1}},
+		Activation: []deep.ActivationType{deep.ActivationReLU},
+		Mode:       deep.ModeMultiClass,
+		Weight:     deep.NewNormal(0.5, 0),
+		Bias:       true,
+	})
+}
+
+func Test_Conv2D(t *testing.T) {
+	rand.Seed(0)
+	data := bars(100)
+
+	n := newConvNet()
+	NewTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0).Train(n, data, nil, 50)
+	acc, err := accuracy(n, data)
+	assert.NoError(t, err)
+	assert.True(t, acc > 0.95)
+
+	n = newConvNet()
+	NewBatchTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0, 10, 2).Train(n, data, nil, 200)
+	acc, err = accuracy(n, data)
+	assert.NoError(t, err)
+	assert.True(t, acc > 0.95)
+}
+
+func Test_Pooling(t *testing.T) {
+	rand.Seed(0)
+	data := bars(100)
+
+	for _, pool := range []deep.LayerType{deep.LayerMaxPool, deep.LayerAvgPool} {
+		n := deep.NewNeural(&deep.Config{
+			Inputs: 25,
+			Shape:  &deep.Shape{Height: 5, Width: 5, Channels: 1},
+			Layout: []int{4, 0, 2},
+			Layers: []deep.LayerConfig{
+				{Type: deep.LayerConv2D, Kernel: 3, Padding: 1},
+				{Type: pool, Kernel: 2, Stride: 1},
+			},
+			Activation: []deep.ActivationType{deep.ActivationReLU},
+			Mode:       deep.ModeMultiClass,
+			Weight:     deep.NewNormal(0.5, 0),
+			Bias:       true,
+		})
+		NewBatchTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0, 10, 2).Train(n, data, nil, 100)
+		acc, err := accuracy(n, data)
+		assert.NoError(t, err)
+		assert.True(t, acc > 0.95, pool.String())
+	}
+}
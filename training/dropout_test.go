@@ -0,0 +1,111 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDropoutNet() *deep.Neural {
+	return deep.NewNeural(&deep.Config{
+		Inputs:     3,
+		Layout:     []int{8, 2},
+		Activation: []deep.ActivationType{deep.ActivationSigmoid, deep.ActivationLinear},
+		Mode:       deep.ModeRegression,
+		Weight:     deep.NewNormal(0.5, 0),
+		Bias:       true,
+		Dropout:    []float32{0.5},
+		Seed:       3,
+	})
+}
+
+// dropoutLoss returns the loss of n on input with the hidden units masked by
+// mask
+func dropoutLoss(n *deep.Neural, mask []float32, input, ideal []float32) func() float32 {
+	return func() float32 {
+		hidden, out := n.Layers[0], n.Layers[1]
+		h := make([]float32, hidden.Size)
+		for j := range h {
+			h[j] = mask[j] * deep.Sigmoid{}.F(deep.Dot(hidden.Row(j), input)+hidden.Bias[j], false)
+		}
+		var loss float32
+		for j := 0; j < out.Size; j++ {
+			d := deep.Dot(out.Row(j), h) - ideal[j]
+			loss += d * d / 2
+		}
+		return loss
+	}
+}
+
+func Test_DropoutGradients(t *testing.T) {
+	rand.Seed(0)
+	n := newDropoutNet()
+	input, ideal := []float32{0.5, -0.3, 0.8}, []float32{0.2, -0.6}
+
+	online := &OnlineTrainer{internal: newTraining(n)}
+	n.ForwardState(online.state, input, true)
+	online.calculateDeltas(n, input, ideal)
+
+	batch := NewBatchTrainer(nil, 0, 1, 1)
+	batch.internalb = newBatchTraining(n, 1)
+	n.ForwardState(batch.states[0], input, true)
+	batch.calculateDeltas(n, input, ideal, 0)
+
+	for _, c := range []struct {
+		state *deep.State
+		grad  func(j, k int) float32
+	}{
+		{online.state, func(j, k int) float32 { return online.deltas[0][j] * input[k] }},
+		{batch.states[0], func(j, k int) float32 { return batch.partialDeltas[0][0][j*3+k] }},
+	} {
+		mask := c.state.Mask(0)
+		var dropped int
+		for _, m := range mask {
+			if m == 0 {
+				dropped++
+			}
+		}
+		assert.True(t, dropped > 0 && dropped < len(mask))
+
+		loss := dropoutLoss(n, mask, input, ideal)
+		for j := 0; j < n.Layers[0].Size; j++ {
+			for k := range input {
+				numeric := deep.NumericGradient(loss, &n.Layers[0].Weights[j*n.Layers[0].Inputs+k], 1e-2)
+				err := deep.RelativeError(c.grad(j, k), numeric)
+				assert.True(t, err < 1e-3, "weight %d, %d: %v", j, k, err)
+				if mask[j] == 0 {
+					assert.Equal(t, float32(0), c.grad(j, k))
+				}
+			}
+		}
+	}
+}
+
+func Test_DropoutReproducible(t *testing.T) {
+	var data Examples
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		x := []float32{r.Float32(), r.Float32(), r.Float32()}
+		data = append(data, Example{x, []float32{x[0] - x[1], x[1] * x[2]}})
+	}
+
+	for _, newTrainer := range []func() Trainer{
+		func() Trainer { return NewTrainer(NewSGD(0.1, 0.1, 0, false), 0) },
+		func() Trainer { return NewBatchTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0, 10, 1) },
+		func() Trainer { return NewBatchTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0, 10, 4) },
+	} {
+		train := func() *deep.Neural {
+			rand.Seed(0)
+			n := newDropoutNet()
+			newTrainer().Train(n, append(Examples{}, data...), nil, 200)
+			return n
+		}
+		n := train()
+		assert.Equal(t, n.Weights(), train().Weights())
+		loss, err := crossValidate(n, data)
+		assert.NoError(t, err)
+		assert.True(t, loss < 0.02)
+	}
+}
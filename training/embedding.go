@@ -0,0 +1,77 @@
+package training
+
+import deep "github.com/nathanleary/neural-net"
+
+// trackRows makes the trainer update only the embedding rows looked up in
+// each batch
+func (t *BatchTrainer) trackRows(n *deep.Neural) {
+	t.rows, t.seen = nil, nil
+	for i, l := range n.Layers {
+		if l.Type != deep.LayerEmbedding {
+			continue
+		}
+		if t.rows == nil {
+			t.rows = make([][][]int, t.parallelism)
+			for w := range t.rows {
+				t.rows[w] = make([][]int, len(n.Layers))
+			}
+			t.seen = make([][]bool, len(n.Layers))
+		}
+		t.seen[i] = make([]bool, l.Rows())
+	}
+}
+
+// lookupRows records the embedding rows worker wid looked up for input
+func (t *BatchTrainer) lookupRows(n *deep.Neural, input []float32, wid int) {
+	if t.rows == nil {
+		return
+	}
+	for i, l := range n.Layers {
+		if l.Type != deep.LayerEmbedding {
+			continue
+		}
+		t.rows[wid][i] = l.EmbeddingRows(t.states[wid].Input(i, input), t.rows[wid][i])
+	}
+}
+
+// updateRows updates the embedding rows of layer i looked up by any worker
+// during the batch, merging only their partial gradients
+func (t *BatchTrainer) updateRows(i int, l *deep.Layer, it, idx int) {
+	seen := t.seen[i]
+	for w := range t.rows {
+		for _, j := range t.rows[w][i] {
+			if seen[j] {
+				continue
+			}
+			seen[j] = true
+			from, to := l.RowRange(j)
+			for k := from; k < to; k++ {
+				var g float32
+				for _, wPD := range t.partialDeltas {
+					g += wPD[i][k]
+					wPD[i][k] = 0
+				}
+				l.Weights[k] += t.solver.Update(l.Weights[k], g, it, idx+k)
+			}
+		}
+	}
+	for w := range t.rows {
+		for _, j := range t.rows[w][i] {
+			seen[j] = false
+		}
+		t.rows[w][i] = t.rows[w][i][:0]
+	}
+}
+
+// updateRows updates the embedding rows of l looked up for input from their
+// gradients in grad, and clears them
+func (t *OnlineTrainer) updateRows(l *deep.Layer, grad, input []float32, it, offset int) {
+	t.rows = l.EmbeddingRows(input, t.rows[:0])
+	for _, j := range t.rows {
+		from, to := l.RowRange(j)
+		for k := from; k < to; k++ {
+			l.Weights[k] += t.solver.Update(l.Weights[k], grad[k], it, offset+k)
+			grad[k] = 0
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TrainEmbedding(t *testing.T) {
+	rand.Seed(0)
+
+	// The target adds a value learned per category to a numeric input.
+	// Category 19 never occurs.
+	values := make([]float32, 20)
+	for i := range values {
+		values[i] = rand.Float32()*2 - 1
+	}
+	var data Examples
+	for i := 0; i < 400; i++ {
+		id, x := rand.Intn(19), rand.Float32()
+		data = append(data, Example{[]float32{x, float32(id)}, []float32{values[id] + x}})
+	}
+
+	newNet := func() *deep.Neural {
+		return deep.NewNeural(&deep.Config{
+			Inputs: 2,
+			Layout: []int{0, 8, 1},
+			Layers: []deep.LayerConfig{{
+				Type:       deep.LayerEmbedding,
+				Embeddings: []deep.Embedding{{Column: 1, Categories: 20, Size: 2}},
+			}},
+			Activation: []deep.ActivationType{deep.ActivationLinear, deep.ActivationTanh},
+			Mode:       deep.ModeRegression,
+			Weight:     deep.NewNormal(0.5, 0),
+			Bias:       true,
+		})
+	}
+
+	for _, trainer := range []Trainer{
+		NewTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0),
+		NewBatchTrainer(NewAdam(0.02, 0.9, 0.999, 1e-8), 0, 20, 2),
+	} {
+		n := newNet()
+		unseen := append([]float32{}, n.Layers[0].Row(19)...)
+		trainer.Train(n, data, nil, 100)
+
+		loss, err := crossValidate(n, data)
+		assert.NoError(t, err)
+		assert.True(t, loss < 0.01)
+		assert.Equal(t, unseen, n.Layers[0].Row(19))
+	}
+}
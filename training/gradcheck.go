@@ -0,0 +1,79 @@
+package training
+
+import (
+	"fmt"
+
+	math "github.com/chewxy/math32"
+	deep "github.com/nathanleary/neural-net"
+)
+
+// gradientRecorder is a Solver that adds up the gradients of each weight
+// instead of updating it
+type gradientRecorder struct {
+	gradients []float32
+}
+
+func (r *gradientRecorder) Init(size int) {
+	r.gradients = make([]float32, size)
+}
+
+func (r *gradientRecorder) Update(value, gradient float32, iteration, idx int) float32 {
+	r.gradients[idx] += gradient
+	return 0
+}
+
+// GradCheck trains n for one iteration over examples with the trainer
+// returned by trainer, through a solver that records the gradients instead
+// of updating the weights, and compares them with the central finite
+// differences of step h of the summed loss of the examples. It returns the
+// worst relative error in the weights, bias and activation parameters of
+// each layer. The forward pass of training must match prediction, so n must
+// be a float32 feed-forward network without dropout or batch normalization.
+// Step h defaults to 1e-3 if unset.
+func GradCheck(n *deep.Neural, examples Examples, h float32, trainer func(Solver) Trainer) ([]float32, error) {
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("Invalid gradient check examples - expected examples")
+	}
+	if n.Config.Precision == deep.PrecisionFloat64 {
+		return nil, fmt.Errorf("Invalid gradient check network - expected float32 precision")
+	}
+	if n.Recurrent() || n.BatchNormalized() {
+		return nil, fmt.Errorf("Invalid gradient check network - expected no recurrent or batch normalization layers")
+	}
+	for i, rate := range n.Config.Dropout {
+		if rate > 0 {
+			return nil, fmt.Errorf("Invalid layer %d - expected no dropout got: %f", i, rate)
+		}
+	}
+	h = fparam(h, 1e-3)
+
+	recorder := &gradientRecorder{}
+	trainer(recorder).Train(n, examples, nil, 1)
+
+	loss := deep.GetLoss(n.Config.Loss)
+	objective := func() float32 {
+		var sum float32
+		for _, e := range examples {
+			sum += deep.Objective(loss, n.Predict(e.Input), e.Response)
+		}
+		return sum
+	}
+
+	worst := make([]float32, len(n.Layers))
+	var offset int
+	for i, l := range n.Layers {
+		idx := offset
+		for _, params := range [][]float32{l.Weights, l.Bias, l.Params} {
+			for k := range params {
+				numeric := deep.NumericGradient(objective, &params[k], h)
+				err := deep.RelativeError(recorder.gradients[idx], numeric)
+				if err > worst[i] || math.IsNaN(err) {
+					worst[i] = err
+				}
+				idx++
+			}
+		}
+		offset += l.NumWeights()
+	}
+	return worst, nil
+}
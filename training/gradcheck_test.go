@@ -0,0 +1,76 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func gradCheckExamples(inputs, outputs int, classes bool) Examples {
+	examples := make(Examples, 4)
+	for i := range examples {
+		examples[i].Input = make([]float32, inputs)
+		for k := range examples[i].Input {
+			examples[i].Input[k] = rand.Float32()*2 - 1
+		}
+		examples[i].Response = make([]float32, outputs)
+		if classes {
+			examples[i].Response[i%outputs] = 1
+			continue
+		}
+		for k := range examples[i].Response {
+			examples[i].Response[k] = rand.Float32()*2 - 1
+		}
+	}
+	return examples
+}
+
+func Test_GradCheck(t *testing.T) {
+	trainers := map[string]func(Solver) Trainer{
+		"online": func(s Solver) Trainer { return NewTrainer(s, 0) },
+		"batch":  func(s Solver) Trainer { return NewBatchTrainer(s, 0, 2, 2) },
+	}
+	for name, trainer := range trainers {
+		for _, mode := range []deep.Mode{deep.ModeMultiClass, deep.ModeRegression, deep.ModeBinary} {
+			rand.Seed(0)
+			n := deep.NewNeural(&deep.Config{
+				Inputs: 3,
+				Layout: []int{4, 0, 4, 2},
+				Layers: []deep.LayerConfig{{}, {Type: deep.LayerNorm}},
+				Activation: []deep.ActivationType{
+					deep.ActivationTanh,
+					deep.ActivationLinear,
+					deep.ActivationELU,
+				},
+				Mode: mode,
+				Bias: true,
+			})
+			examples := gradCheckExamples(3, 2, mode != deep.ModeRegression)
+			weights := n.Weights()
+
+			worst, err := GradCheck(n, examples, 1e-2, trainer)
+			assert.NoError(t, err)
+			assert.Len(t, worst, len(n.Layers))
+			for i, e := range worst {
+				assert.True(t, e < 5e-3, "%s trainer, mode %d, layer %d: %v", name, mode, i, e)
+			}
+			assert.Equal(t, weights, n.Weights())
+		}
+	}
+}
+
+func Test_GradCheckInvalid(t *testing.T) {
+	trainer := func(s Solver) Trainer { return NewTrainer(s, 0) }
+	n := deep.NewNeural(&deep.Config{
+		Inputs:  2,
+		Layout:  []int{3, 1},
+		Dropout: []float32{0.5},
+		Mode:    deep.ModeRegression,
+	})
+	_, err := GradCheck(n, gradCheckExamples(2, 1, false), 0, trainer)
+	assert.Error(t, err)
+	_, err = GradCheck(n, nil, 0, trainer)
+	assert.Error(t, err)
+}
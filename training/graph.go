@@ -0,0 +1,195 @@
+package training
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	deep "github.com/nathanleary/neural-net"
+)
+
+// GraphExample holds one input per input node of a graph and one response
+// per output node, in the order they are declared
+type GraphExample struct {
+	Input    [][]float32
+	Response [][]float32
+}
+
+// GraphExamples is a set of graph examples
+type GraphExamples []GraphExample
+
+// Shuffle shuffles slice in-place
+func (e GraphExamples) Shuffle() {
+	for i := range e {
+		j := rand.Intn(i + 1)
+		e[i], e[j] = e[j], e[i]
+	}
+}
+
+// Split assigns each element to two new slices
+// according to probability p
+func (e GraphExamples) Split(p float32) (first, second GraphExamples) {
+	for i := 0; i < len(e); i++ {
+		if p > rand.Float32() {
+			first = append(first, e[i])
+		} else {
+			second = append(second, e[i])
+		}
+	}
+	return
+}
+
+// GraphTrainer is a graph trainer
+type GraphTrainer interface {
+	TrainGraph(g *deep.Graph, examples, validation GraphExamples, iterations int) error
+}
+
+// graphTraining holds the buffers of one worker training a graph
+type graphTraining struct {
+	state *deep.GraphState
+	// deltas holds the gradients with respect to the pre-activation
+	// outputs of each output node
+	deltas [][]float32
+	// outputs holds the index in Nodes of each output node
+	outputs []int
+}
+
+func newGraphTraining(g *deep.Graph) *graphTraining {
+	deltas := make([][]float32, len(g.Outputs))
+	outputs := make([]int, len(g.Outputs))
+	for k, out := range g.Outputs {
+		deltas[k] = make([]float32, out.Size)
+		for i, node := range g.Nodes {
+			if node == out {
+				outputs[k] = i
+			}
+		}
+	}
+	return &graphTraining{state: g.NewState(), deltas: deltas, outputs: outputs}
+}
+
+// learn runs e forward through g and adds the weight and activation
+// parameter gradients of each node into grads
+func (t *graphTraining) learn(g *deep.Graph, e GraphExample, grads [][]float32) error {
+	if err := g.ForwardState(t.state, e.Input, true); err != nil {
+		return err
+	}
+	for k, out := range g.Outputs {
+		loss := deep.GetLoss(out.Config.Loss)
+		for j, y := range t.state.Outputs[k] {
+			x := t.state.OutputSums[k][j]
+			out.Layer.BackwardParam(j, x, y, loss.Df(y, e.Response[k][j], 1), grads[t.outputs[k]])
+			t.deltas[k][j] = loss.Df(y, e.Response[k][j], out.Layer.DActivate(j, x, y))
+		}
+	}
+	g.Backpropagate(t.state, t.deltas, grads)
+	return nil
+}
+
+// newGraphGradients returns a gradient buffer for each node of g, empty for
+// nodes without weights
+func newGraphGradients(g *deep.Graph) [][]float32 {
+	grads := make([][]float32, len(g.Nodes))
+	for i, node := range g.Nodes {
+		if node.Layer != nil {
+			grads[i] = make([]float32, node.Layer.NumWeights())
+		}
+	}
+	return grads
+}
+
+// graphLayers returns the layer of each node of g, nil for nodes without
+// weights
+func graphLayers(g *deep.Graph) []*deep.Layer {
+	layers := make([]*deep.Layer, len(g.Nodes))
+	for i, node := range g.Nodes {
+		layers[i] = node.Layer
+	}
+	return layers
+}
+
+// TrainGraph trains g online, one example at a time. It stops at the first
+// invalid example and returns its error.
+func (t *OnlineTrainer) TrainGraph(g *deep.Graph, examples, validation GraphExamples, iterations int) error {
+	w := newGraphTraining(g)
+	grads := newGraphGradients(g)
+
+	train := make(GraphExamples, len(examples))
+	copy(train, examples)
+
+	t.printer.InitGraph(g)
+	t.solver.Init(g.NumWeights())
+
+	ts := time.Now()
+	for i := 1; i <= iterations; i++ {
+
+		train.Shuffle()
+		for _, e := range train {
+			if err := w.learn(g, e, grads); err != nil {
+				return err
+			}
+
+			var offset int
+			for j, l := range graphLayers(g) {
+				if l == nil {
+					continue
+				}
+				t.updateGradients(l, grads[j], i, offset)
+				offset += l.NumWeights()
+			}
+		}
+
+		if t.verbosity > 0 && i%t.verbosity == 0 && len(validation) > 0 {
+			t.printer.PrintProgressGraph(g, validation, time.Since(ts), i)
+		}
+	}
+	return nil
+}
+
+// TrainGraph trains g in parallelized mini-batches. It stops at the first
+// batch with an invalid example and returns its error.
+func (t *BatchTrainer) TrainGraph(g *deep.Graph, examples, validation GraphExamples, iterations int) error {
+	workers := make([]*graphTraining, t.parallelism)
+	partialDeltas := make([][][]float32, t.parallelism)
+	for w := range workers {
+		workers[w] = newGraphTraining(g)
+		partialDeltas[w] = newGraphGradients(g)
+	}
+	t.internalb = &internalb{
+		partialDeltas:     partialDeltas,
+		accumulatedDeltas: newGraphGradients(g),
+	}
+	layers := graphLayers(g)
+
+	t.printer.InitGraph(g)
+	return t.run(g.NumWeights(), len(examples), iterations, func(i, wid int) error {
+		return workers[wid].learn(g, examples[i], partialDeltas[wid])
+	}, func(it int) {
+		t.updateLayers(layers, it)
+	}, func(elapsed time.Duration, it int) {
+		if len(validation) > 0 {
+			t.printer.PrintProgressGraph(g, validation, elapsed, it)
+		}
+	})
+}
+
+// GraphLoss returns the loss of each output node of g over examples
+func GraphLoss(g *deep.Graph, examples GraphExamples) ([]float32, error) {
+	predictions := make([][][]float32, len(g.Outputs))
+	responses := make([][][]float32, len(g.Outputs))
+	for i, e := range examples {
+		out, err := g.Predict(e.Input)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid graph example %d - %v", i, err)
+		}
+		for k := range out {
+			predictions[k] = append(predictions[k], out[k])
+			responses[k] = append(responses[k], e.Response[k])
+		}
+	}
+	losses := make([]float32, len(g.Outputs))
+	for k, node := range g.Outputs {
+		losses[k] = deep.GetLoss(node.Config.Loss).F(predictions[k], responses[k])
+	}
+	return losses, nil
+}
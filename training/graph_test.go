@@ -0,0 +1,105 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+// newGraphData returns examples of two inputs, whose responses are the
+// class of the sign of their sums' product, and the sum of both
+func newGraphData(size int) GraphExamples {
+	data := make(GraphExamples, size)
+	for i := range data {
+		a := []float32{rand.Float32()*2 - 1, rand.Float32()*2 - 1}
+		b := []float32{rand.Float32()*2 - 1}
+		class := []float32{1, 0}
+		if (a[0]+a[1])*b[0] < 0 {
+			class = []float32{0, 1}
+		}
+		data[i] = GraphExample{
+			Input:    [][]float32{a, b},
+			Response: [][]float32{class, {a[0] + a[1] + b[0]}},
+		}
+	}
+	return data
+}
+
+func newTrainGraph() *deep.Graph {
+	g, err := deep.NewGraph(&deep.GraphConfig{
+		Nodes: []deep.NodeConfig{
+			deep.Input("a", 2),
+			deep.Input("b", 1),
+			deep.Concat("ab", "a", "b"),
+			deep.Dense("h1", "ab", 16, deep.ActivationTanh),
+			deep.Dense("h2", "h1", 16, deep.ActivationTanh),
+			deep.Add("h", "h1", "h2"),
+			deep.Output("class", "h", 2, deep.ModeMultiClass),
+			deep.Output("sum", "ab", 1, deep.ModeRegression),
+		},
+		Weight: deep.NewNormal(0.5, 0),
+		Bias:   true,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+func Test_TrainGraph(t *testing.T) {
+	rand.Seed(0)
+	data, validation := newGraphData(500), newGraphData(100)
+
+	for _, trainer := range []GraphTrainer{
+		NewTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0),
+		NewBatchTrainer(NewAdam(0.02, 0.9, 0.999, 1e-8), 0, 10, 2),
+	} {
+		g := newTrainGraph()
+		before, err := GraphLoss(g, validation)
+		assert.NoError(t, err)
+		assert.NoError(t, trainer.TrainGraph(g, data, nil, 100))
+		after, err := GraphLoss(g, validation)
+		assert.NoError(t, err)
+
+		assert.True(t, after[0] < before[0]/2, "class loss %f -> %f", before[0], after[0])
+		assert.True(t, after[1] < 0.01, "sum loss %f -> %f", before[1], after[1])
+
+		correct := 0
+		for _, e := range validation {
+			out, err := g.Predict(e.Input)
+			assert.NoError(t, err)
+			if deep.ArgMax(out[0]) == deep.ArgMax(e.Response[0]) {
+				correct++
+			}
+		}
+		assert.True(t, correct > 85, "accuracy %d/%d", correct, len(validation))
+	}
+}
+
+func Test_TrainGraphKeepsExamples(t *testing.T) {
+	rand.Seed(0)
+	data := newGraphData(20)
+	order := append(GraphExamples{}, data...)
+	assert.NoError(t, NewTrainer(NewSGD(0.01, 0, 0, false), 0).TrainGraph(newTrainGraph(), data, nil, 2))
+	assert.Equal(t, order, data)
+}
+
+func Test_TrainGraphInvalid(t *testing.T) {
+	rand.Seed(0)
+	data := newGraphData(10)
+	data[3].Input = [][]float32{{0.5}, {0.5}}
+
+	for _, trainer := range []GraphTrainer{
+		NewTrainer(NewSGD(0.01, 0, 0, false), 0),
+		NewBatchTrainer(NewSGD(0.01, 0, 0, false), 0, 4, 2),
+	} {
+		g := newTrainGraph()
+		err := trainer.TrainGraph(g, data, nil, 1)
+		assert.EqualError(t, err, "Invalid input dimension of a - expected: 2 got: 1")
+	}
+
+	_, err := GraphLoss(newTrainGraph(), data)
+	assert.EqualError(t, err, "Invalid graph example 3 - Invalid input dimension of a - expected: 2 got: 1")
+}
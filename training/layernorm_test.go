@@ -0,0 +1,41 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TrainLayerNorm(t *testing.T) {
+	rand.Seed(0)
+	var data Examples
+	for i := 0; i < 300; i++ {
+		x := []float32{rand.Float32()*2 - 1, rand.Float32()*2 - 1}
+		data = append(data, Example{x, []float32{x[0] * x[1]}})
+	}
+
+	newNet := func() *deep.Neural {
+		return deep.NewNeural(&deep.Config{
+			Inputs:     2,
+			Layout:     []int{12, 0, 12, 0, 1},
+			Layers:     []deep.LayerConfig{{}, {Type: deep.LayerNorm}, {}, {Type: deep.LayerNorm}},
+			Activation: []deep.ActivationType{deep.ActivationLinear, deep.ActivationTanh, deep.ActivationLinear, deep.ActivationTanh},
+			Mode:       deep.ModeRegression,
+			Weight:     deep.NewNormal(0.5, 0),
+			Bias:       true,
+		})
+	}
+
+	for _, trainer := range []Trainer{
+		NewTrainer(NewAdam(0.005, 0.9, 0.999, 1e-8), 0),
+		NewBatchTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0, 10, 2),
+	} {
+		n := newNet()
+		trainer.Train(n, append(Examples{}, data...), nil, 100)
+		loss, err := crossValidate(n, data)
+		assert.NoError(t, err)
+		assert.True(t, loss < 0.01)
+	}
+}
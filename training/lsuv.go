@@ -0,0 +1,78 @@
+package training
+
+import (
+	"fmt"
+
+	math "github.com/chewxy/math32"
+	deep "github.com/nathanleary/neural-net"
+)
+
+// LSUV initializes the weights of an untrained n by layer-sequential unit
+// variance: it orthonormalizes the weights of every layer, then rescales
+// those of each layer in turn until the variance of its outputs over the
+// inputs of sample is within tolerance of 1, trying at most iterations
+// times. Layers without fan-in weights, such as normalization layers, and
+// output layers with a non-linear activation are left as they are.
+// Tolerance and iterations default to 0.1 and 10 if unset.
+func LSUV(n *deep.Neural, sample Examples, tolerance float32, iterations int) error {
+	if len(sample) == 0 {
+		return fmt.Errorf("Invalid LSUV sample - expected examples")
+	}
+	if n.Config.Precision == deep.PrecisionFloat64 {
+		return fmt.Errorf("Invalid LSUV network - expected float32 precision")
+	}
+	if tolerance == 0 {
+		tolerance = 0.1
+	}
+	if iterations == 0 {
+		iterations = 10
+	}
+
+	var layers []int
+	for i, l := range n.Layers {
+		in, out := l.Fan()
+		if in == 0 || i == len(n.Layers)-1 && l.A != deep.ActivationLinear {
+			continue
+		}
+		deep.NewOrthogonal(1)(l.Weights, in, out)
+		layers = append(layers, i)
+	}
+
+	states := make([]*deep.State, len(sample))
+	for k := range states {
+		states[k] = n.NewState()
+	}
+	for _, i := range layers {
+		l := n.Layers[i]
+		for it := 0; it < iterations; it++ {
+			var values []float32
+			for k, e := range sample {
+				if err := n.ForwardState(states[k], e.Input, false); err != nil {
+					return err
+				}
+				values = append(values, states[k].Values[i]...)
+			}
+			variance := outputVariance(values)
+			if variance == 0 || math.IsNaN(variance) || math.IsInf(variance, 0) {
+				return fmt.Errorf("Invalid layer %d - output variance: %f", i, variance)
+			}
+			if math.Abs(variance-1) < tolerance {
+				break
+			}
+			scale := 1 / math.Sqrt(variance)
+			for k := range l.Weights {
+				l.Weights[k] *= scale
+			}
+		}
+	}
+	return nil
+}
+
+func outputVariance(values []float32) float32 {
+	mean := deep.Mean(values)
+	var sum float32
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return sum / float32(len(values))
+}
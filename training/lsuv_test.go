@@ -0,0 +1,59 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LSUV(t *testing.T) {
+	rand.Seed(0)
+	n := deep.NewNeural(&deep.Config{
+		Inputs: 10,
+		Layout: []int{32, 32, 32, 32, 0, 32, 3},
+		Layers: []deep.LayerConfig{{}, {}, {}, {}, {Type: deep.LayerNorm}},
+		Activation: []deep.ActivationType{
+			deep.ActivationRootSwish,
+			deep.ActivationDoubleRoot,
+			deep.ActivationRootSwish,
+			deep.ActivationDoubleRoot,
+			deep.ActivationLinear,
+			deep.ActivationReLU,
+		},
+		Mode:   deep.ModeMultiClass,
+		Weight: deep.NewNormal(0.05, 0),
+		Bias:   true,
+	})
+	sample := make(Examples, 100)
+	for i := range sample {
+		sample[i].Input = make([]float32, 10)
+		for k := range sample[i].Input {
+			sample[i].Input[k] = rand.Float32()*2 - 1
+		}
+	}
+	out := append([]float32{}, n.Layers[6].Weights...)
+	gain := append([]float32{}, n.Layers[4].Weights...)
+
+	assert.NoError(t, LSUV(n, sample, 0.05, 20))
+
+	s := n.NewState()
+	variances := make([]float32, len(n.Layers))
+	for i := range variances {
+		var values []float32
+		for _, e := range sample {
+			assert.NoError(t, n.ForwardState(s, e.Input, false))
+			values = append(values, s.Values[i]...)
+		}
+		variances[i] = outputVariance(values)
+	}
+	for _, i := range []int{0, 1, 2, 3, 5} {
+		assert.InDelta(t, 1, variances[i], 0.05, "layer %d", i)
+	}
+	assert.Equal(t, out, n.Layers[6].Weights)
+	assert.Equal(t, gain, n.Layers[4].Weights)
+
+	assert.Error(t, LSUV(n, nil, 0, 0))
+	assert.Error(t, LSUV(n, Examples{{Input: []float32{1}}}, 0, 0))
+}
@@ -0,0 +1,71 @@
+package training
+
+import "math/rand"
+
+// Example64 is a float64 input-target pair, used to train float64 networks
+type Example64 struct {
+	Input    []float64
+	Response []float64
+}
+
+// Examples64 is a set of float64 input-output pairs
+type Examples64 []Example64
+
+// Float64 widens examples to float64
+func (e Examples) Float64() Examples64 {
+	res := make(Examples64, len(e))
+	for i := range e {
+		res[i] = Example64{
+			Input:    toFloat64(e[i].Input),
+			Response: toFloat64(e[i].Response),
+		}
+	}
+	return res
+}
+
+// Shuffle shuffles slice in-place
+func (e Examples64) Shuffle() {
+	for i := range e {
+		j := rand.Intn(i + 1)
+		e[i], e[j] = e[j], e[i]
+	}
+}
+
+// Split assigns each element to two new slices
+// according to probability p
+func (e Examples64) Split(p float64) (first, second Examples64) {
+	for i := 0; i < len(e); i++ {
+		if p > rand.Float64() {
+			first = append(first, e[i])
+		} else {
+			second = append(second, e[i])
+		}
+	}
+	return
+}
+
+// SplitSize splits slice into parts of size size
+func (e Examples64) SplitSize(size int) []Examples64 {
+	res := make([]Examples64, 0)
+	for i := 0; i < len(e); i += size {
+		res = append(res, e[i:min(i+size, len(e))])
+	}
+	return res
+}
+
+// SplitN splits slice into n parts
+func (e Examples64) SplitN(n int) []Examples64 {
+	res := make([]Examples64, n)
+	for i, el := range e {
+		res[i%n] = append(res[i%n], el)
+	}
+	return res
+}
+
+func toFloat64(xx []float32) []float64 {
+	out := make([]float64, len(xx))
+	for i, x := range xx {
+		out[i] = float64(x)
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func newParametricNet(mode deep.Mode) *deep.Neural {
+	return deep.NewNeural(&deep.Config{
+		Inputs: 3,
+		Layout: []int{4, 4, 2},
+		Layers: []deep.LayerConfig{{}, {SharedParam: true}},
+		Activation: []deep.ActivationType{
+			deep.ActivationPReLU,
+			deep.ActivationSwishBeta,
+		},
+		Mode: mode,
+		Bias: true,
+	})
+}
+
+func Test_GradCheckParametric(t *testing.T) {
+	trainers := map[string]func(Solver) Trainer{
+		"online": func(s Solver) Trainer { return NewTrainer(s, 0) },
+		"batch":  func(s Solver) Trainer { return NewBatchTrainer(s, 0, 2, 2) },
+	}
+	for name, trainer := range trainers {
+		for _, mode := range []deep.Mode{deep.ModeMultiClass, deep.ModeRegression} {
+			rand.Seed(0)
+			n := newParametricNet(mode)
+			n.Layers[0].Params = []float32{0.1, 0.2, 0.3, 0.4}
+
+			// A small step keeps the pre-activations from crossing the kink
+			// of PReLU at 0
+			worst, err := GradCheck(n, gradCheckExamples(3, 2, mode != deep.ModeRegression), 1e-3, trainer)
+			assert.NoError(t, err)
+			for i, e := range worst {
+				assert.True(t, e < 1e-2, "%s trainer, mode %d, layer %d: %v", name, mode, i, e)
+			}
+		}
+	}
+}
+
+func Test_TrainParametric(t *testing.T) {
+	rand.Seed(0)
+	data := Examples{}
+	for i := 0; i < 100; i++ {
+		x := rand.Float32()*2 - 1
+		data = append(data, Example{Input: []float32{x, -x, 1}, Response: []float32{x * x, x}})
+	}
+
+	for name, trainer := range map[string]Trainer{
+		"online": NewTrainer(NewAdam(0.01, 0, 0, 0), 0),
+		"batch":  NewBatchTrainer(NewAdam(0.01, 0, 0, 0), 0, 10, 2),
+	} {
+		n := newParametricNet(deep.ModeRegression)
+		before, err := CalculateLoss(n, data)
+		assert.NoError(t, err)
+		trainer.Train(n, data, nil, 100)
+
+		after, err := CalculateLoss(n, data)
+		assert.NoError(t, err)
+		assert.True(t, after < before/2, name)
+		assert.NotEqual(t, []float32{0.25, 0.25, 0.25, 0.25}, n.Layers[0].Params, name)
+		assert.NotEqual(t, []float32{1}, n.Layers[1].Params, name)
+	}
+}
+
+func Test_TrainGraphParametric(t *testing.T) {
+	rand.Seed(0)
+	g, err := deep.NewGraph(&deep.GraphConfig{
+		Nodes: []deep.NodeConfig{
+			deep.Input("x", 1),
+			deep.Dense("h", "x", 4, deep.ActivationSwishBeta),
+			deep.Output("y", "h", 1, deep.ModeRegression),
+		},
+		Weight: deep.NewNormal(0.5, 0),
+		Bias:   true,
+	})
+	assert.NoError(t, err)
+	examples := GraphExamples{}
+	for i := 0; i < 50; i++ {
+		x := rand.Float32()*2 - 1
+		examples = append(examples, GraphExample{Input: [][]float32{{x}}, Response: [][]float32{{x * x}}})
+	}
+
+	assert.NoError(t, NewTrainer(NewAdam(0.01, 0, 0, 0), 0).TrainGraph(g, examples, nil, 20))
+	assert.NotEqual(t, []float32{1, 1, 1, 1}, g.Nodes[1].Layer.Params)
+}
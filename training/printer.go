@@ -29,40 +29,246 @@ func (p *StatsPrinter) Init(n *deep.Neural) {
 	}
 }
 
-// PrintProgress prints the current state of training
+// PrintProgress prints the current state of training, or why the validation
+// examples could not be evaluated
 func (p *StatsPrinter) PrintProgress(n *deep.Neural, validation Examples, elapsed time.Duration, iteration int) {
+	predictions, err := predict(n, validation)
+	if err != nil {
+		p.printError(elapsed, iteration, err)
+		return
+	}
 	fmt.Fprintf(p.w, "%d\t%s\t%.4f\t%s\n",
 		iteration,
 		elapsed.String(),
-		crossValidate(n, validation),
-		formatAccuracy(n, validation))
+		validationLoss(n, predictions, validation),
+		formatAccuracy(n, predictions, validation))
+	p.w.Flush()
+}
+
+// PrintProgress64 prints the current state of training a float64 network,
+// or why the validation examples could not be evaluated
+func (p *StatsPrinter) PrintProgress64(n *deep.Neural, validation Examples64, elapsed time.Duration, iteration int) {
+	predictions, err := predict64(n, validation)
+	if err != nil {
+		p.printError(elapsed, iteration, err)
+		return
+	}
+	fmt.Fprintf(p.w, "%d\t%s\t%.4f\t%s\n",
+		iteration,
+		elapsed.String(),
+		validationLoss64(n, predictions, validation),
+		formatAccuracy64(n, predictions, validation))
+	p.w.Flush()
+}
+
+// printError prints err in place of the loss of an iteration
+func (p *StatsPrinter) printError(elapsed time.Duration, iteration int, err error) {
+	fmt.Fprintf(p.w, "%d\t%s\t%v\n", iteration, elapsed.String(), err)
 	p.w.Flush()
 }
 
-func formatAccuracy(n *deep.Neural, validation Examples) string {
+// PrintProgressSparse prints the current state of training on sparse
+// examples, or why a validation example could not be evaluated
+func (p *StatsPrinter) PrintProgressSparse(n *deep.Neural, validation SparseExamples, elapsed time.Duration, iteration int) {
+	predictions, responses := make([][]float32, len(validation)), make([][]float32, len(validation))
+	correct := 0
+	for i, e := range validation {
+		predictions[i] = n.PredictSparse(e.Input)
+		if predictions[i] == nil {
+			// PredictSparse drops the error, which a forward pass recovers
+			err := n.ForwardStateSparse(n.NewState(), e.Input, false)
+			p.printError(elapsed, iteration, fmt.Errorf("Invalid validation example %d - %v", i, err))
+			return
+		}
+		responses[i] = e.Response
+		if deep.ArgMax(e.Response) == deep.ArgMax(predictions[i]) {
+			correct++
+		}
+	}
+
+	var acc string
 	if n.Config.Mode == deep.ModeMultiClass {
-		return fmt.Sprintf("%.2f\t", accuracy(n, validation))
+		acc = fmt.Sprintf("%.2f\t", float32(correct)/float32(len(validation)))
+	}
+	fmt.Fprintf(p.w, "%d\t%s\t%.4f\t%s\n",
+		iteration,
+		elapsed.String(),
+		deep.GetLoss(n.Config.Loss).F(predictions, responses),
+		acc)
+	p.w.Flush()
+}
+
+func formatAccuracy(n *deep.Neural, predictions [][]float32, validation Examples) string {
+	if n.Config.Mode != deep.ModeMultiClass {
+		return ""
 	}
-	return ""
+	return fmt.Sprintf("%.2f\t", validationAccuracy(predictions, validation))
 }
 
-func accuracy(n *deep.Neural, validation Examples) float32 {
+func accuracy(n *deep.Neural, validation Examples) (float32, error) {
+	predictions, err := predict(n, validation)
+	if err != nil {
+		return 0, err
+	}
+	return validationAccuracy(predictions, validation), nil
+}
+
+// validationAccuracy returns the share of predictions with the class of their
+// validation example
+func validationAccuracy(predictions [][]float32, validation Examples) float32 {
 	correct := 0
-	for _, e := range validation {
-		est := n.Predict(e.Input)
-		if deep.ArgMax(e.Response) == deep.ArgMax(est) {
+	for i, est := range predictions {
+		if deep.ArgMax(validation[i].Response) == deep.ArgMax(est) {
 			correct++
 		}
 	}
 	return float32(correct) / float32(len(validation))
 }
 
-func crossValidate(n *deep.Neural, validation Examples) float32 {
-	predictions, responses := make([][]float32, len(validation)), make([][]float32, len(validation))
+func crossValidate(n *deep.Neural, validation Examples) (float32, error) {
+	predictions, err := predict(n, validation)
+	if err != nil {
+		return 0, err
+	}
+	return validationLoss(n, predictions, validation), nil
+}
+
+// validationLoss returns the loss of n's predictions on the validation examples
+func validationLoss(n *deep.Neural, predictions [][]float32, validation Examples) float32 {
+	responses := make([][]float32, len(validation))
 	for i := 0; i < len(validation); i++ {
-		predictions[i] = n.Predict(validation[i].Input)
 		responses[i] = validation[i].Response
 	}
 
 	return deep.GetLoss(n.Config.Loss).F(predictions, responses)
 }
+
+// predict runs all examples through n as a single batch
+func predict(n *deep.Neural, examples Examples) ([][]float32, error) {
+	inputs := make([][]float32, len(examples))
+	for i := range examples {
+		inputs[i] = examples[i].Input
+	}
+	return n.PredictBatch(inputs)
+}
+
+func formatAccuracy64(n *deep.Neural, predictions [][]float64, validation Examples64) string {
+	if n.Config.Mode != deep.ModeMultiClass {
+		return ""
+	}
+	return fmt.Sprintf("%.2f\t", validationAccuracy64(predictions, validation))
+}
+
+func accuracy64(n *deep.Neural, validation Examples64) (float64, error) {
+	predictions, err := predict64(n, validation)
+	if err != nil {
+		return 0, err
+	}
+	return validationAccuracy64(predictions, validation), nil
+}
+
+// validationAccuracy64 returns the share of predictions with the class of their
+// validation example
+func validationAccuracy64(predictions [][]float64, validation Examples64) float64 {
+	correct := 0
+	for i, est := range predictions {
+		if deep.ArgMax64(validation[i].Response) == deep.ArgMax64(est) {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(validation))
+}
+
+func crossValidate64(n *deep.Neural, validation Examples64) (float64, error) {
+	predictions, err := predict64(n, validation)
+	if err != nil {
+		return 0, err
+	}
+	return validationLoss64(n, predictions, validation), nil
+}
+
+// validationLoss64 returns the loss of n's predictions on the validation examples
+func validationLoss64(n *deep.Neural, predictions [][]float64, validation Examples64) float64 {
+	responses := make([][]float64, len(validation))
+	for i := 0; i < len(validation); i++ {
+		responses[i] = validation[i].Response
+	}
+
+	return deep.GetLoss64(n.Config.Loss).F64(predictions, responses)
+}
+
+// predict64 runs all examples through a float64 network as a single batch
+func predict64(n *deep.Neural, examples Examples64) ([][]float64, error) {
+	inputs := make([][]float64, len(examples))
+	for i := range examples {
+		inputs[i] = examples[i].Input
+	}
+	return n.PredictBatch64(inputs)
+}
+
+// PrintProgressSequences prints the current state of training on sequences,
+// or why a validation sequence could not be evaluated
+func (p *StatsPrinter) PrintProgressSequences(n *deep.Neural, validation SequenceExamples, elapsed time.Duration, iteration int) {
+	var predictions, responses [][]float32
+	correct := 0
+	for i, e := range validation {
+		out, err := n.PredictSequence(e.Input)
+		if err != nil {
+			p.printError(elapsed, iteration, fmt.Errorf("Invalid validation sequence %d - %v", i, err))
+			return
+		}
+		for t, y := range out {
+			if ideal := e.target(t); ideal != nil {
+				predictions, responses = append(predictions, y), append(responses, ideal)
+				if deep.ArgMax(ideal) == deep.ArgMax(y) {
+					correct++
+				}
+			}
+		}
+	}
+
+	if len(predictions) == 0 {
+		p.printError(elapsed, iteration, fmt.Errorf("Invalid validation sequences - no step has a target"))
+		return
+	}
+
+	var acc string
+	if n.Config.Mode == deep.ModeMultiClass {
+		acc = fmt.Sprintf("%.2f\t", float32(correct)/float32(len(predictions)))
+	}
+	fmt.Fprintf(p.w, "%d\t%s\t%.4f\t%s\n",
+		iteration,
+		elapsed.String(),
+		deep.GetLoss(n.Config.Loss).F(predictions, responses),
+		acc)
+	p.w.Flush()
+}
+
+// InitGraph initializes printer for a graph, with a loss column per output
+func (p *StatsPrinter) InitGraph(g *deep.Graph) {
+	fmt.Fprintf(p.w, "Epochs\tElapsed\t")
+	for _, out := range g.Outputs {
+		fmt.Fprintf(p.w, "%s (%s)\t", out.Config.Name, out.Config.Loss)
+	}
+	fmt.Fprintf(p.w, "\n---\t---\t")
+	for range g.Outputs {
+		fmt.Fprintf(p.w, "---\t")
+	}
+	fmt.Fprintf(p.w, "\n")
+}
+
+// PrintProgressGraph prints the current state of training a graph, or why
+// the validation examples could not be evaluated
+func (p *StatsPrinter) PrintProgressGraph(g *deep.Graph, validation GraphExamples, elapsed time.Duration, iteration int) {
+	losses, err := GraphLoss(g, validation)
+	if err != nil {
+		p.printError(elapsed, iteration, err)
+		return
+	}
+	fmt.Fprintf(p.w, "%d\t%s\t", iteration, elapsed.String())
+	for _, loss := range losses {
+		fmt.Fprintf(p.w, "%.4f\t", loss)
+	}
+	fmt.Fprintf(p.w, "\n")
+	p.w.Flush()
+}
@@ -0,0 +1,71 @@
+package training
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"text/tabwriter"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PrintProgressInvalid(t *testing.T) {
+	n := deep.NewNeural(&deep.Config{
+		Inputs: 2,
+		Layout: []int{3, 2},
+		Mode:   deep.ModeMultiClass,
+		Weight: deep.NewNormal(1, 0),
+		Bias:   true,
+	})
+	valid := Examples{{Input: []float32{0.1, 0.2}, Response: []float32{1, 0}}}
+	invalid := append(valid, Example{Input: []float32{0.1}, Response: []float32{0, 1}})
+
+	_, err := CalculateLoss(n, valid)
+	assert.NoError(t, err)
+	_, err = CalculateLoss(n, invalid)
+	assert.Error(t, err)
+
+	var out bytes.Buffer
+	p := &StatsPrinter{tabwriter.NewWriter(&out, 16, 0, 3, ' ', 0)}
+	p.PrintProgress(n, valid, 0, 1)
+	loss, err := CalculateLoss(n, valid)
+	assert.NoError(t, err)
+	acc, err := accuracy(n, valid)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), fmt.Sprintf("%.4f", loss))
+	assert.Contains(t, out.String(), fmt.Sprintf("%.2f", acc))
+
+	out.Reset()
+	p.PrintProgress(n, invalid, 0, 1)
+	assert.Contains(t, out.String(), "Invalid input dimension at 1")
+
+	out.Reset()
+	p.PrintProgressSparse(n, SparseExamples{
+		{Input: deep.Sparse{Indices: []int{1}, Values: []float32{1}}, Response: []float32{1, 0}},
+		{Input: deep.Sparse{Indices: []int{2}, Values: []float32{1}}, Response: []float32{0, 1}},
+	}, 0, 1)
+	assert.Contains(t, out.String(), "Invalid validation example 1 - Invalid sparse input index")
+
+	r := deep.NewNeural(&deep.Config{
+		Inputs: 1,
+		Layout: []int{4, 1},
+		Layers: []deep.LayerConfig{{Type: deep.LayerLSTM}},
+		Mode:   deep.ModeBinary,
+		Weight: deep.NewNormal(1, 0),
+	})
+	out.Reset()
+	p.PrintProgressSequences(r, SequenceExamples{
+		{Input: [][]float32{{0.5}}, Response: [][]float32{{1}}},
+		{Input: [][]float32{{0.5, 1}}, Response: [][]float32{{0}}},
+	}, 0, 1)
+	assert.Contains(t, out.String(), "Invalid validation sequence 1")
+
+	out.Reset()
+	p.PrintProgressSequences(r, SequenceExamples{{Input: [][]float32{{0.5}}}}, 0, 1)
+	assert.Contains(t, out.String(), "Invalid validation sequences - no step has a target")
+
+	out.Reset()
+	p.PrintProgressGraph(newTrainGraph(), GraphExamples{{Input: [][]float32{{0.5}}}}, 0, 1)
+	assert.Contains(t, out.String(), "Invalid graph example 0 - Invalid number of inputs")
+}
@@ -0,0 +1,94 @@
+package training
+
+import (
+	"fmt"
+
+	math "github.com/chewxy/math32"
+	deep "github.com/nathanleary/neural-net"
+)
+
+// Quantize returns an int8 copy of n calibrated on the inputs of the given
+// examples. A few hundred representative examples are usually enough.
+func Quantize(n *deep.Neural, calibration Examples) (*deep.Quantized, error) {
+	inputs := make([][]float32, len(calibration))
+	for i := range calibration {
+		inputs[i] = calibration[i].Input
+	}
+	return deep.Quantize(n, inputs)
+}
+
+// QuantizationReport compares a quantized network with the float network it
+// was created from
+type QuantizationReport struct {
+	Loss          float32
+	QuantizedLoss float32
+	// Accuracy is only reported for classification modes
+	Accuracy          float32
+	QuantizedAccuracy float32
+	// Agreement is the share of examples on which both networks predict the
+	// same class
+	Agreement float32
+	// MaxError is the largest absolute difference between any two outputs
+	MaxError float32
+}
+
+// CompareQuantized evaluates n and its quantized copy q on validation
+func CompareQuantized(n *deep.Neural, q *deep.Quantized, validation Examples) (QuantizationReport, error) {
+	predictions, err := predict(n, validation)
+	if err != nil {
+		return QuantizationReport{}, err
+	}
+
+	responses := make([][]float32, len(validation))
+	quantized := make([][]float32, len(validation))
+	for i := range validation {
+		responses[i] = validation[i].Response
+		quantized[i] = q.Predict(validation[i].Input)
+	}
+
+	loss := deep.GetLoss(n.Config.Loss)
+	r := QuantizationReport{
+		Loss:          loss.F(predictions, responses),
+		QuantizedLoss: loss.F(quantized, responses),
+	}
+	if n.Config.Mode != deep.ModeRegression {
+		r.Accuracy = classAccuracy(n.Config.Mode, predictions, responses)
+		r.QuantizedAccuracy = classAccuracy(n.Config.Mode, quantized, responses)
+		r.Agreement = classAccuracy(n.Config.Mode, quantized, predictions)
+	}
+	for i := range predictions {
+		for j := range predictions[i] {
+			r.MaxError = math.Max(r.MaxError, math.Abs(predictions[i][j]-quantized[i][j]))
+		}
+	}
+	return r, nil
+}
+
+// classAccuracy returns the share of estimates that predict the same classes
+// as targets
+func classAccuracy(mode deep.Mode, estimates, targets [][]float32) float32 {
+	correct := 0
+	for i := range estimates {
+		if mode == deep.ModeMultiClass {
+			if deep.ArgMax(estimates[i]) == deep.ArgMax(targets[i]) {
+				correct++
+			}
+			continue
+		}
+		match := true
+		for j := range estimates[i] {
+			if deep.Round(estimates[i][j]) != deep.Round(targets[i][j]) {
+				match = false
+			}
+		}
+		if match {
+			correct++
+		}
+	}
+	return float32(correct) / float32(len(estimates))
+}
+
+func (r QuantizationReport) String() string {
+	return fmt.Sprintf("loss: %.4f (int8: %.4f)\taccuracy: %.2f (int8: %.2f)\tagreement: %.2f\tmax error: %.4f",
+		r.Loss, r.QuantizedLoss, r.Accuracy, r.QuantizedAccuracy, r.Agreement, r.MaxError)
+}
@@ -0,0 +1,44 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Quantize(t *testing.T) {
+	rand.Seed(0)
+	var data Examples
+	for i := 0; i < 200; i++ {
+		x, y := rand.Float32()*4-2, rand.Float32()*4-2
+		if x*y > 0 {
+			data = append(data, Example{[]float32{x, y}, []float32{1, 0}})
+		} else {
+			data = append(data, Example{[]float32{x, y}, []float32{0, 1}})
+		}
+	}
+
+	n := deep.NewNeural(&deep.Config{
+		Inputs:     2,
+		Layout:     []int{8, 2},
+		Activation: []deep.ActivationType{deep.ActivationTanh},
+		Mode:       deep.ModeMultiClass,
+		Weight:     deep.NewNormal(1, 0),
+		Bias:       true,
+	})
+	trainer := NewBatchTrainer(NewAdam(0.02, 0.9, 0.999, 1e-8), 0, 20, 1)
+	trainer.Train(n, data, data, 300)
+
+	q, err := Quantize(n, data[:50])
+	assert.Nil(t, err)
+
+	r, err := CompareQuantized(n, q, data)
+	assert.Nil(t, err)
+	assert.True(t, r.Accuracy > 0.9)
+	assert.True(t, r.Agreement > 0.95)
+	assert.InDelta(t, r.Accuracy, r.QuantizedAccuracy, 0.05)
+	assert.InDelta(t, r.Loss, r.QuantizedLoss, 0.05)
+	assert.True(t, r.MaxError < 0.2)
+}
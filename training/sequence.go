@@ -0,0 +1,284 @@
+package training
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	deep "github.com/nathanleary/neural-net"
+)
+
+// SequenceExample is an input sequence with its targets. Response holds a
+// target for every step of Input, or a single target for the last step.
+type SequenceExample struct {
+	Input    [][]float32
+	Response [][]float32
+}
+
+// SequenceExamples is a set of input-output sequences
+type SequenceExamples []SequenceExample
+
+// Sequences turns each example into a sequence of one step
+func (e Examples) Sequences() SequenceExamples {
+	res := make(SequenceExamples, len(e))
+	for i := range e {
+		res[i] = SequenceExample{
+			Input:    [][]float32{e[i].Input},
+			Response: [][]float32{e[i].Response},
+		}
+	}
+	return res
+}
+
+// Shuffle shuffles slice in-place
+func (e SequenceExamples) Shuffle() {
+	for i := range e {
+		j := rand.Intn(i + 1)
+		e[i], e[j] = e[j], e[i]
+	}
+}
+
+// Split assigns each element to two new slices
+// according to probability p
+func (e SequenceExamples) Split(p float32) (first, second SequenceExamples) {
+	for i := 0; i < len(e); i++ {
+		if p > rand.Float32() {
+			first = append(first, e[i])
+		} else {
+			second = append(second, e[i])
+		}
+	}
+	return
+}
+
+// Windows turns each step with a target into an example whose input is the
+// window of steps ending at it, for networks with attention layers
+func (e SequenceExamples) Windows(window int) Examples {
+	var res Examples
+	for _, seq := range e {
+		for t := range seq.Input {
+			if ideal := seq.target(t); ideal != nil {
+				res = append(res, Example{
+					Input:    deep.WindowInput(seq.Input[:t+1], window),
+					Response: ideal,
+				})
+			}
+		}
+	}
+	return res
+}
+
+// target returns the target of step t, or nil if the step has none
+func (e SequenceExample) target(t int) []float32 {
+	if len(e.Response) == len(e.Input) {
+		return e.Response[t]
+	}
+	if t == len(e.Input)-1 && len(e.Response) > 0 {
+		return e.Response[len(e.Response)-1]
+	}
+	return nil
+}
+
+// SequenceTrainer trains networks on sequences, backpropagating through at
+// most steps of time at once, or through whole sequences if steps is 0.
+// Sequences train in float32 only, so TrainSequences panics on networks with
+// PrecisionFloat64. It returns the error of the first invalid sequence.
+type SequenceTrainer interface {
+	TrainSequences(n *deep.Neural, examples, validation SequenceExamples, iterations, steps int) error
+}
+
+// bptt holds the buffers of backpropagation through time over one sequence
+type bptt struct {
+	// states records the forward pass of every step of the window, and prev
+	// the last step of the window before
+	states []*deep.State
+	prev   *deep.State
+	carry  *deep.State
+	// deltas of each layer at each step of the window
+	deltas [][][]float32
+	inputs [][]float32
+}
+
+func newBPTT(n *deep.Neural) *bptt {
+	return &bptt{
+		carry:  n.NewState(),
+		deltas: make([][][]float32, len(n.Layers)),
+	}
+}
+
+// grow makes room for windows of size steps
+func (b *bptt) grow(n *deep.Neural, steps int) {
+	for len(b.states) < steps {
+		b.states = append(b.states, n.NewState())
+		b.inputs = append(b.inputs, nil)
+		for i, l := range n.Layers {
+			b.deltas[i] = append(b.deltas[i], make([]float32, l.Size))
+		}
+	}
+}
+
+// learn backpropagates e through n in windows of at most steps, adding the
+// weight gradients of each layer into grads and calling flush, unless it is
+// nil, after each window. It stops at the first invalid step and returns its
+// error.
+func (b *bptt) learn(n *deep.Neural, e SequenceExample, steps int, grads [][]float32, flush func()) error {
+	if steps <= 0 {
+		steps = len(e.Input)
+	}
+	last := len(n.Layers) - 1
+
+	b.prev = nil
+	for start := 0; start < len(e.Input); start += steps {
+		w := min(steps, len(e.Input)-start)
+		b.grow(n, w)
+		states := b.states[:w]
+
+		for t, s := range states {
+			prev := b.prev
+			if t > 0 {
+				prev = states[t-1]
+			}
+			if err := n.ForwardStep(s, prev, e.Input[start+t], true); err != nil {
+				return fmt.Errorf("Invalid step %d - %v", start+t, err)
+			}
+
+			if ideal := e.target(start + t); ideal != nil {
+				outputDeltas(n, s, ideal, b.deltas[last][t], grads[last])
+			} else {
+				for k := range b.deltas[last][t] {
+					b.deltas[last][t][k] = 0
+				}
+			}
+		}
+
+		for i := last; i >= 0; i-- {
+			inputs := b.inputs[:w]
+			for t := range inputs {
+				if i == 0 {
+					inputs[t] = e.Input[start+t]
+				} else {
+					inputs[t] = states[t].Values[i-1]
+				}
+			}
+
+			var dx [][]float32
+			if i > 0 {
+				dx = b.deltas[i-1][:w]
+				for _, d := range dx {
+					for k := range d {
+						d[k] = 0
+					}
+				}
+			}
+			n.Layers[i].BackwardSequence(i, b.prev, states, inputs, b.deltas[i][:w], grads[i], dx)
+
+			for t, d := range dx {
+				dactivate(n.Layers[i-1], states[t], i-1, d, grads[i-1])
+			}
+		}
+
+		if flush != nil {
+			flush()
+		}
+
+		// The last step carries its state into the next window
+		b.states[w-1], b.carry = b.carry, b.states[w-1]
+		b.prev = b.carry
+	}
+	return nil
+}
+
+// TrainSequences trains n on sequences with truncated backpropagation
+// through time, updating the weights after every window of at most steps,
+// or after every sequence if steps is 0. Networks with attention layers
+// train on the window ending at each step with a target instead. It stops at
+// the first invalid sequence and returns its error.
+func (t *OnlineTrainer) TrainSequences(n *deep.Neural, examples, validation SequenceExamples, iterations, steps int) error {
+	if n.Config.Precision == deep.PrecisionFloat64 {
+		panic("training: sequences require float32 precision")
+	}
+	if w := n.Window(); w > 0 {
+		t.Train(n, examples.Windows(w), validation.Windows(w), iterations)
+		return nil
+	}
+	t.internal = newTraining(n)
+	b := newBPTT(n)
+	grads := make([][]float32, len(n.Layers))
+	for i, l := range n.Layers {
+		grads[i] = make([]float32, l.NumWeights())
+	}
+
+	train := make(SequenceExamples, len(examples))
+	copy(train, examples)
+
+	t.printer.Init(n)
+	t.solver.Init(n.NumWeights())
+
+	ts := time.Now()
+	for i := 1; i <= iterations; i++ {
+		it := i
+		flush := func() {
+			var offset int
+			for k, l := range n.Layers {
+				t.updateGradients(l, grads[k], it, offset)
+				offset += l.NumWeights()
+			}
+		}
+
+		train.Shuffle()
+		for _, e := range train {
+			if err := b.learn(n, e, steps, grads, flush); err != nil {
+				return err
+			}
+		}
+
+		if t.verbosity > 0 && i%t.verbosity == 0 && len(validation) > 0 {
+			t.printer.PrintProgressSequences(n, validation, time.Since(ts), i)
+		}
+	}
+	return nil
+}
+
+// TrainSequences trains n on sequences with truncated backpropagation
+// through time, accumulating the gradients of every window of at most steps
+// of each sequence in the batch, or of whole sequences if steps is 0.
+// Networks with attention layers train on the window ending at each step
+// with a target instead. It stops at the first batch with an invalid
+// sequence and returns its error.
+func (t *BatchTrainer) TrainSequences(n *deep.Neural, examples, validation SequenceExamples, iterations, steps int) error {
+	if n.Config.Precision == deep.PrecisionFloat64 {
+		panic("training: sequences require float32 precision")
+	}
+	if w := n.Window(); w > 0 {
+		t.Train(n, examples.Windows(w), validation.Windows(w), iterations)
+		return nil
+	}
+	t.internalb = newBatchTraining(n, t.parallelism)
+	// Grow the windows up front, so that the states of each worker draw their
+	// dropout seeds in order
+	longest := 0
+	for _, e := range examples {
+		if len(e.Input) > longest {
+			longest = len(e.Input)
+		}
+	}
+	if steps > 0 {
+		longest = min(longest, steps)
+	}
+	seqs := make([]*bptt, t.parallelism)
+	for w := range seqs {
+		seqs[w] = newBPTT(n)
+		seqs[w].grow(n, longest)
+	}
+
+	t.printer.Init(n)
+	return t.run(n.NumWeights(), len(examples), iterations, func(i, wid int) error {
+		return seqs[wid].learn(n, examples[i], steps, t.partialDeltas[wid], nil)
+	}, func(it int) {
+		t.update(n, it)
+	}, func(elapsed time.Duration, it int) {
+		if len(validation) > 0 {
+			t.printer.PrintProgressSequences(n, validation, elapsed, it)
+		}
+	})
+}
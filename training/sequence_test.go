@@ -0,0 +1,159 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+// rememberFirst returns sequences labeled by the sign of their first input
+func rememberFirst(size, length int) SequenceExamples {
+	data := make(SequenceExamples, size)
+	for i := range data {
+		input := make([][]float32, length)
+		for step := range input {
+			input[step] = []float32{rand.Float32()*2 - 1}
+		}
+		var label float32
+		if input[0][0] > 0 {
+			label = 1
+		}
+		data[i] = SequenceExample{Input: input, Response: [][]float32{{label}}}
+	}
+	return data
+}
+
+func sequenceAccuracy(n *deep.Neural, data SequenceExamples) float32 {
+	correct := 0
+	for _, e := range data {
+		out, _ := n.PredictSequence(e.Input)
+		if deep.Round(out[len(out)-1][0]) == e.Response[0][0] {
+			correct++
+		}
+	}
+	return float32(correct) / float32(len(data))
+}
+
+func Test_TrainSequences(t *testing.T) {
+	rand.Seed(0)
+	data := rememberFirst(200, 5)
+
+	for _, typ := range []deep.LayerType{deep.LayerLSTM, deep.LayerGRU} {
+		newNet := func() *deep.Neural {
+			return deep.NewNeural(&deep.Config{
+				Inputs: 1,
+				Layout: []int{8, 1},
+				Layers: []deep.LayerConfig{{Type: typ}},
+				Mode:   deep.ModeBinary,
+				Weight: deep.NewNormal(0.5, 0),
+				Bias:   true,
+			})
+		}
+
+		n := newNet()
+		assert.NoError(t, NewTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0).TrainSequences(n, data, nil, 30, 0))
+		assert.True(t, sequenceAccuracy(n, data) > 0.95, typ.String())
+
+		n = newNet()
+		assert.NoError(t, NewBatchTrainer(NewAdam(0.02, 0.9, 0.999, 1e-8), 0, 10, 2).TrainSequences(n, data, nil, 60, 0))
+		assert.True(t, sequenceAccuracy(n, data) > 0.95, typ.String())
+	}
+}
+
+func Test_TruncatedBPTT(t *testing.T) {
+	rand.Seed(0)
+
+	// Each step predicts the input of the step before
+	data := make(SequenceExamples, 50)
+	for i := range data {
+		input, response := make([][]float32, 12), make([][]float32, 12)
+		for step := range input {
+			input[step] = []float32{rand.Float32()*2 - 1}
+			response[step] = []float32{0}
+			if step > 0 {
+				response[step] = input[step-1]
+			}
+		}
+		data[i] = SequenceExample{Input: input, Response: response}
+	}
+
+	n := deep.NewNeural(&deep.Config{
+		Inputs: 1,
+		Layout: []int{6, 1},
+		Layers: []deep.LayerConfig{{Type: deep.LayerGRU}},
+		Mode:   deep.ModeRegression,
+		Weight: deep.NewNormal(0.5, 0),
+		Bias:   true,
+	})
+	assert.NoError(t, NewTrainer(NewAdam(0.01, 0.9, 0.999, 1e-8), 0).TrainSequences(n, data, nil, 50, 3))
+
+	out, err := n.PredictSequence(data[0].Input)
+	assert.Nil(t, err)
+	for step := 1; step < len(out); step++ {
+		assert.InDelta(t, data[0].Response[step][0], out[step][0], 0.1)
+	}
+}
+
+func Test_TrainRecurrentExamples(t *testing.T) {
+	rand.Seed(0)
+	n := deep.NewNeural(&deep.Config{
+		Inputs: 2,
+		Layout: []int{4, 1},
+		Layers: []deep.LayerConfig{{Type: deep.LayerLSTM}},
+		Mode:   deep.ModeBinary,
+		Weight: deep.NewNormal(0.5, 0),
+		Bias:   true,
+	})
+	data := Examples{
+		{[]float32{0, 0}, []float32{0}},
+		{[]float32{1, 0}, []float32{1}},
+		{[]float32{0, 1}, []float32{1}},
+		{[]float32{1, 1}, []float32{1}},
+	}
+	NewBatchTrainer(NewAdam(0.05, 0.9, 0.999, 1e-8), 0, 4, 1).Train(n, data, nil, 300)
+	for _, e := range data {
+		assert.Equal(t, e.Response[0], deep.Round(n.Predict(e.Input)[0]))
+	}
+}
+
+func Test_TrainSequencesFloat64(t *testing.T) {
+	n := deep.NewNeural(&deep.Config{
+		Inputs:    1,
+		Layout:    []int{4, 1},
+		Mode:      deep.ModeBinary,
+		Weight:    deep.NewNormal(0.5, 0),
+		Precision: deep.PrecisionFloat64,
+	})
+	data := rememberFirst(4, 3)
+	for _, trainer := range []SequenceTrainer{
+		NewTrainer(NewSGD(0.1, 0, 0, false), 0),
+		NewBatchTrainer(NewSGD(0.1, 0, 0, false), 0, 2, 1),
+	} {
+		assert.Panics(t, func() { trainer.TrainSequences(n, data, nil, 1, 0) })
+	}
+}
+
+func Test_TrainSequencesInvalid(t *testing.T) {
+	data := rememberFirst(4, 3)
+	data[2].Input[1] = []float32{0.5, 1}
+	for _, trainer := range []SequenceTrainer{
+		NewTrainer(NewSGD(0.1, 0, 0, false), 0),
+		NewBatchTrainer(NewSGD(0.1, 0, 0, false), 0, 4, 2),
+	} {
+		n := deep.NewNeural(&deep.Config{
+			Inputs: 1,
+			Layout: []int{4, 1},
+			Layers: []deep.LayerConfig{{Type: deep.LayerLSTM}},
+			Mode:   deep.ModeBinary,
+			Weight: deep.NewNormal(0.5, 0),
+		})
+		weights := n.Weights()
+		err := trainer.TrainSequences(n, data, nil, 1, 0)
+		assert.EqualError(t, err, "Invalid step 1 - Invalid input dimension - expected: 1 got: 2")
+		if _, ok := trainer.(*BatchTrainer); ok {
+			assert.Equal(t, weights, n.Weights())
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSkipNet() *deep.Neural {
+	return deep.NewNeural(&deep.Config{
+		Inputs:     2,
+		Layout:     []int{4, 4, 4, 0, 2},
+		Layers:     []deep.LayerConfig{{}, {}, {Residual: []int{0}}, {Type: deep.LayerNorm, Residual: []int{1}}, {Concat: []int{-1, 1}}},
+		Activation: []deep.ActivationType{deep.ActivationTanh, deep.ActivationTanh, deep.ActivationSigmoid, deep.ActivationTanh},
+		Mode:       deep.ModeRegression,
+		Weight:     deep.NewNormal(0.5, 0),
+		Bias:       true,
+	})
+}
+
+func Test_SkipGradients(t *testing.T) {
+	rand.Seed(0)
+	n := newSkipNet()
+	input, ideal := []float32{0.5, -0.3}, []float32{0.2, -0.6}
+
+	// loss is half the squared error, whose gradients the trainers compute
+	loss := func() float32 {
+		var sum float32
+		for k, y := range n.Predict(input) {
+			sum += (y - ideal[k]) * (y - ideal[k]) / 2
+		}
+		return sum
+	}
+	const eps = 1e-2
+
+	online := &OnlineTrainer{internal: newTraining(n)}
+	n.ForwardState(online.state, input, true)
+	online.calculateDeltas(n, input, ideal)
+
+	batch := NewBatchTrainer(nil, 0, 1, 1)
+	batch.internalb = newBatchTraining(n, 1)
+	n.ForwardState(batch.states[0], input, true)
+	batch.calculateDeltas(n, input, ideal, 0)
+
+	for i, l := range n.Layers {
+		grads := make([]float32, l.NumWeights())
+		l.Backward(online.state.Input(i, input), online.deltas[i], grads, nil)
+		check := func(x *float32, k int) {
+			grad := deep.NumericGradient(loss, x, eps)
+			assert.InDelta(t, grad, grads[k], 2e-3)
+			assert.InDelta(t, grad, batch.partialDeltas[0][i][k], 2e-3)
+		}
+		for k := range l.Weights {
+			check(&l.Weights[k], k)
+		}
+		for k := range l.Bias {
+			check(&l.Bias[k], len(l.Weights)+k)
+		}
+	}
+}
+
+func Test_TrainResidual(t *testing.T) {
+	rand.Seed(0)
+	var data Examples
+	for i := 0; i < 300; i++ {
+		x := []float32{rand.Float32()*2 - 1, rand.Float32()*2 - 1}
+		data = append(data, Example{x, []float32{x[0] * x[1]}})
+	}
+
+	// Ten hidden layers, each adding its input to its pre-activations
+	newNet := func() *deep.Neural {
+		c := &deep.Config{
+			Inputs: 2,
+			Mode:   deep.ModeRegression,
+			Weight: deep.NewNormal(0.3, 0),
+			Bias:   true,
+		}
+		for i := 0; i < 10; i++ {
+			c.Layout = append(c.Layout, 8)
+			c.Activation = append(c.Activation, deep.ActivationTanh)
+			var spec deep.LayerConfig
+			if i > 0 {
+				spec.Residual = []int{i - 1}
+			}
+			c.Layers = append(c.Layers, spec)
+		}
+		c.Layout = append(c.Layout, 1)
+		return deep.NewNeural(c)
+	}
+
+	for _, trainer := range []Trainer{
+		NewTrainer(NewAdam(0.002, 0.9, 0.999, 1e-8), 0),
+		NewBatchTrainer(NewAdam(0.005, 0.9, 0.999, 1e-8), 0, 10, 2),
+	} {
+		n := newNet()
+		trainer.Train(n, append(Examples{}, data...), nil, 100)
+		loss, err := crossValidate(n, data)
+		assert.NoError(t, err)
+		assert.True(t, loss < 0.01)
+	}
+}
+
+func Test_SkipBatchNormGradients(t *testing.T) {
+	rand.Seed(0)
+	n := deep.NewNeural(&deep.Config{
+		Inputs: 2,
+		Layout: []int{3, 0, 0, 1},
+		Layers: []deep.LayerConfig{
+			{},
+			{Type: deep.LayerBatchNorm, Residual: []int{0}},
+			{Type: deep.LayerBatchNorm, Concat: []int{0}},
+		},
+		Activation: []deep.ActivationType{deep.ActivationTanh, deep.ActivationSigmoid, deep.ActivationTanh},
+		Mode:       deep.ModeRegression,
+		Weight:     deep.NewNormal(0.5, 0),
+		Bias:       true,
+	})
+	assert.Equal(t, 6, n.Layers[2].Size)
+
+	var batch Examples
+	inputs := make([][]float32, 4)
+	for k := range inputs {
+		batch = append(batch, Example{[]float32{rand.Float32(), rand.Float32() - 1}, []float32{rand.Float32()}})
+		inputs[k] = batch[k].Input
+	}
+	pass := newBatchPass(n, len(batch))
+
+	// loss is half the squared error of the batch normalized with its own
+	// statistics
+	loss := func() float32 {
+		n.ForwardBatch(pass.states, inputs, true)
+		var sum float32
+		for k, s := range pass.states {
+			d := s.Output()[0] - batch[k].Response[0]
+			sum += d * d / 2
+		}
+		return sum
+	}
+	const eps = 1e-3
+
+	grads := make([][]float32, len(n.Layers))
+	for i, l := range n.Layers {
+		grads[i] = make([]float32, l.NumWeights())
+	}
+	pass.learn(n, batch, grads)
+
+	for i, l := range n.Layers {
+		for k := range l.Weights {
+			assert.InDelta(t, deep.NumericGradient(loss, &l.Weights[k], eps), grads[i][k], 5e-3)
+		}
+		for k := range l.Bias {
+			assert.InDelta(t, deep.NumericGradient(loss, &l.Bias[k], eps), grads[i][len(l.Weights)+k], 5e-3)
+		}
+	}
+}
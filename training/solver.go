@@ -1,6 +1,10 @@
 package training
 
-import math "github.com/chewxy/math32"
+import (
+	gomath "math"
+
+	math "github.com/chewxy/math32"
+)
 
 // Solver implements an update rule for training a NN
 type Solver interface {
@@ -8,6 +12,34 @@ type Solver interface {
 	Update(value, gradient float32, iteration, idx int) float32
 }
 
+// Solver64 is implemented by solvers that can update the weights of float64
+// networks at full precision
+type Solver64 interface {
+	Init64(size int)
+	Update64(value, gradient float64, iteration, idx int) float64
+}
+
+// solver64 returns s as a Solver64. Solvers that only implement Solver
+// compute their updates in float32.
+func solver64(s Solver) Solver64 {
+	if s64, ok := s.(Solver64); ok {
+		return s64
+	}
+	return narrowSolver{s}
+}
+
+type narrowSolver struct {
+	Solver
+}
+
+func (s narrowSolver) Init64(size int) {
+	s.Init(size)
+}
+
+func (s narrowSolver) Update64(value, gradient float64, iteration, idx int) float64 {
+	return float64(s.Update(float32(value), float32(gradient), iteration, idx))
+}
+
 // SGD is stochastic gradient descent with nesterov/momentum
 type SGD struct {
 	lr       float32
@@ -15,6 +47,8 @@ type SGD struct {
 	momentum float32
 	nesterov bool
 	moments  []float32
+
+	moments64 []float64
 }
 
 // NewSGD returns a new SGD solver
@@ -45,6 +79,25 @@ func (o *SGD) Update(value, gradient float32, iteration, idx int) float32 {
 	return o.moments[idx]
 }
 
+// Init64 initializes vectors using number of weights in a float64 network
+func (o *SGD) Init64(size int) {
+	o.moments64 = make([]float64, size)
+}
+
+// Update64 returns the update for a given float64 weight
+func (o *SGD) Update64(value, gradient float64, iteration, idx int) float64 {
+	lr := float64(o.lr) / (1 + float64(o.decay)*float64(iteration))
+	momentum := float64(o.momentum)
+
+	o.moments64[idx] = momentum*o.moments64[idx] - lr*gradient
+
+	if o.nesterov {
+		o.moments64[idx] = momentum*o.moments64[idx] - lr*gradient
+	}
+
+	return o.moments64[idx]
+}
+
 // Adam is an Adam solver
 type Adam struct {
 	lr      float32
@@ -53,6 +106,8 @@ type Adam struct {
 	epsilon float32
 
 	v, m []float32
+
+	v64, m64 []float64
 }
 
 // NewAdam returns a new Adam solver
@@ -80,6 +135,22 @@ func (o *Adam) Update(value, gradient float32, t, idx int) float32 {
 	return -lrt * (o.m[idx] / (math.Sqrt(o.v[idx]) + o.epsilon))
 }
 
+// Init64 initializes vectors using number of weights in a float64 network
+func (o *Adam) Init64(size int) {
+	o.v64, o.m64 = make([]float64, size), make([]float64, size)
+}
+
+// Update64 returns the update for a given float64 weight
+func (o *Adam) Update64(value, gradient float64, t, idx int) float64 {
+	beta, beta2 := float64(o.beta), float64(o.beta2)
+	lrt := float64(o.lr) * (gomath.Sqrt(1.0 - gomath.Pow(beta2, float64(t)))) /
+		(1.0 - gomath.Pow(beta, float64(t)))
+	o.m64[idx] = beta*o.m64[idx] + (1.0-beta)*gradient
+	o.v64[idx] = beta2*o.v64[idx] + (1.0-beta2)*gradient*gradient
+
+	return -lrt * (o.m64[idx] / (gomath.Sqrt(o.v64[idx]) + float64(o.epsilon)))
+}
+
 func fparam(val, fallback float32) float32 {
 	if val == 0.0 {
 		return fallback
@@ -0,0 +1,200 @@
+package training
+
+import (
+	"math/rand"
+	"time"
+
+	deep "github.com/nathanleary/neural-net"
+)
+
+// SparseExample is an input-target pair with a sparse input
+type SparseExample struct {
+	Input    deep.Sparse
+	Response []float32
+}
+
+// SparseExamples is a set of sparse input-output pairs
+type SparseExamples []SparseExample
+
+// Dense expands the inputs of e into dense vectors of the given size
+func (e SparseExamples) Dense(size int) Examples {
+	res := make(Examples, len(e))
+	for i := range e {
+		res[i] = Example{Input: e[i].Input.Dense(size), Response: e[i].Response}
+	}
+	return res
+}
+
+// Shuffle shuffles slice in-place
+func (e SparseExamples) Shuffle() {
+	for i := range e {
+		j := rand.Intn(i + 1)
+		e[i], e[j] = e[j], e[i]
+	}
+}
+
+// Split assigns each element to two new slices
+// according to probability p
+func (e SparseExamples) Split(p float32) (first, second SparseExamples) {
+	for i := 0; i < len(e); i++ {
+		if p > rand.Float32() {
+			first = append(first, e[i])
+		} else {
+			second = append(second, e[i])
+		}
+	}
+	return
+}
+
+// TrainSparse trains n on sparse examples, only touching the first layer
+// weights of the non-zero inputs. Float64 and recurrent networks are trained
+// on dense inputs.
+func (t *OnlineTrainer) TrainSparse(n *deep.Neural, examples, validation SparseExamples, iterations int) {
+	if n.Config.Precision == deep.PrecisionFloat64 {
+		t.Train64(n, examples.Dense(n.Config.Inputs).Float64(), validation.Dense(n.Config.Inputs).Float64(), iterations)
+		return
+	}
+	if n.Recurrent() {
+		t.Train(n, examples.Dense(n.Config.Inputs), validation.Dense(n.Config.Inputs), iterations)
+		return
+	}
+	t.internal = newTraining(n)
+
+	train := make(SparseExamples, len(examples))
+	copy(train, examples)
+
+	t.printer.Init(n)
+	t.solver.Init(n.NumWeights())
+
+	ts := time.Now()
+	for i := 1; i <= iterations; i++ {
+
+		train.Shuffle()
+		for j := 0; j < len(train); j++ {
+			t.learnSparse(n, train[j], i)
+		}
+
+		if t.verbosity > 0 && i%t.verbosity == 0 && len(validation) > 0 {
+			t.printer.PrintProgressSparse(n, validation, time.Since(ts), i)
+		}
+	}
+}
+
+func (t *OnlineTrainer) learnSparse(n *deep.Neural, e SparseExample, it int) {
+	n.ForwardStateSparse(t.state, e.Input, true)
+	t.calculateDeltas(n, nil, e.Response)
+
+	l := n.Layers[0]
+	for j, d := range t.deltas[0] {
+		row := l.Row(j)
+		for i, k := range e.Input.Indices {
+			row[k] += t.solver.Update(row[k], d*e.Input.Values[i], it, j*l.Inputs+k)
+		}
+	}
+	t.updateBias(l, t.deltas[0], it, 0)
+	t.updateParams(l, t.grads[0], it, 0)
+
+	offset := l.NumWeights()
+	for i := 1; i < len(n.Layers); i++ {
+		t.updateLayer(i, n.Layers[i], t.state.Input(i, nil), it, offset)
+		offset += n.Layers[i].NumWeights()
+	}
+}
+
+// TrainSparse trains n on sparse examples, only touching the first layer
+// weights of the inputs that are non-zero in each batch. Float64, recurrent
+// and batch normalized networks are trained on dense inputs.
+func (t *BatchTrainer) TrainSparse(n *deep.Neural, examples, validation SparseExamples, iterations int) {
+	if n.Config.Precision == deep.PrecisionFloat64 {
+		t.Train64(n, examples.Dense(n.Config.Inputs).Float64(), validation.Dense(n.Config.Inputs).Float64(), iterations)
+		return
+	}
+	if n.Recurrent() || n.BatchNormalized() {
+		t.Train(n, examples.Dense(n.Config.Inputs), validation.Dense(n.Config.Inputs), iterations)
+		return
+	}
+
+	t.internalb = newBatchTraining(n, t.parallelism)
+	t.sparse = true
+	t.touched = make([][]bool, t.parallelism)
+	t.columns = make([][]int, t.parallelism)
+	t.active = make([]bool, n.Config.Inputs)
+	for w := range t.touched {
+		t.touched[w] = make([]bool, n.Config.Inputs)
+	}
+
+	t.printer.Init(n)
+	t.run(n.NumWeights(), len(examples), iterations, func(i, wid int) error {
+		e := examples[i]
+		n.ForwardStateSparse(t.states[wid], e.Input, true)
+		t.calculateSparseDeltas(n, e.Input, e.Response, wid)
+		return nil
+	}, func(it int) {
+		t.update(n, it)
+	}, func(elapsed time.Duration, it int) {
+		if len(validation) > 0 {
+			t.printer.PrintProgressSparse(n, validation, elapsed, it)
+		}
+	})
+}
+
+func (t *BatchTrainer) calculateSparseDeltas(n *deep.Neural, input deep.Sparse, ideal []float32, wid int) {
+	t.backpropagate(n, nil, ideal, wid)
+
+	l := n.Layers[0]
+	iPD := t.partialDeltas[wid][0]
+	bPD := iPD[len(l.Weights):]
+	for j, jD := range t.deltas[wid][0] {
+		jPD := iPD[j*l.Inputs : (j+1)*l.Inputs]
+		for i, k := range input.Indices {
+			jPD[k] += jD * input.Values[i]
+		}
+		if l.Bias != nil {
+			bPD[j] += jD
+		}
+	}
+
+	touched := t.touched[wid]
+	for _, k := range input.Indices {
+		if !touched[k] {
+			touched[k] = true
+			t.columns[wid] = append(t.columns[wid], k)
+		}
+	}
+}
+
+// updateColumns updates the first layer weights in the columns touched by
+// any worker during the batch, merging only their partial gradients, and
+// merges the gradients of the bias and activation parameters into iAD
+func (t *BatchTrainer) updateColumns(l *deep.Layer, iAD []float32, it, idx int) {
+	for w, columns := range t.columns {
+		for _, k := range columns {
+			t.touched[w][k] = false
+			if t.active[k] {
+				continue
+			}
+			t.active[k] = true
+			for j := 0; j < l.Size; j++ {
+				wk := j*l.Inputs + k
+				var g float32
+				for _, wPD := range t.partialDeltas {
+					g += wPD[0][wk]
+					wPD[0][wk] = 0
+				}
+				l.Weights[wk] += t.solver.Update(l.Weights[wk], g, it, idx+wk)
+			}
+		}
+	}
+	for _, wPD := range t.partialDeltas {
+		for k := len(l.Weights); k < len(iAD); k++ {
+			iAD[k] += wPD[0][k]
+			wPD[0][k] = 0
+		}
+	}
+	for w, columns := range t.columns {
+		for _, k := range columns {
+			t.active[k] = false
+		}
+		t.columns[w] = columns[:0]
+	}
+}
@@ -0,0 +1,88 @@
+package training
+
+import (
+	"math/rand"
+	"testing"
+
+	deep "github.com/nathanleary/neural-net"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TrainSparse(t *testing.T) {
+	rand.Seed(0)
+
+	// Bag of words over 1000 terms, where the label is set by term 3 and
+	// terms above 500 never occur
+	var data SparseExamples
+	for i := 0; i < 200; i++ {
+		input := deep.Sparse{Indices: []int{rand.Intn(500), 3}, Values: []float32{1, 1}}
+		response := []float32{1}
+		if i%2 == 0 {
+			input.Indices[1] = 4
+			response[0] = 0
+		}
+		data = append(data, SparseExample{Input: input, Response: response})
+	}
+
+	for _, trainer := range []interface {
+		TrainSparse(*deep.Neural, SparseExamples, SparseExamples, int)
+	}{
+		NewTrainer(NewSGD(0.5, 0.1, 0, false), 0),
+		NewBatchTrainer(NewAdam(0.01, 0, 0, 0), 0, 20, 2),
+	} {
+		n := deep.NewNeural(&deep.Config{
+			Inputs:     1000,
+			Layout:     []int{4, 1},
+			Activation: []deep.ActivationType{deep.ActivationTanh},
+			Mode:       deep.ModeBinary,
+			Weight:     deep.NewUniform(0.5, 0),
+			Bias:       true,
+		})
+		untouched := make([]float32, n.Layers[0].Size)
+		for j := range untouched {
+			untouched[j] = n.Layers[0].Row(j)[700]
+		}
+
+		trainer.TrainSparse(n, data, nil, 100)
+
+		for _, e := range data {
+			assert.InEpsilon(t, e.Response[0]+1, n.PredictSparse(e.Input)[0]+1, 0.1)
+		}
+		for j := range untouched {
+			assert.Equal(t, untouched[j], n.Layers[0].Row(j)[700])
+		}
+	}
+}
+
+func Test_TrainSparseMatchesDense(t *testing.T) {
+	rand.Seed(0)
+	var data SparseExamples
+	for i := 0; i < 40; i++ {
+		input := deep.Sparse{Indices: []int{rand.Intn(20), 20 + rand.Intn(10)}, Values: []float32{1, rand.Float32()}}
+		data = append(data, SparseExample{Input: input, Response: []float32{float32(i % 2)}})
+	}
+
+	train := func(sparse bool) *deep.Neural {
+		rand.Seed(1)
+		n := deep.NewNeural(&deep.Config{
+			Inputs:     30,
+			Layout:     []int{4, 1},
+			Activation: []deep.ActivationType{deep.ActivationTanh},
+			Mode:       deep.ModeBinary,
+			Weight:     deep.NewUniform(0.5, 0),
+			Bias:       true,
+		})
+		trainer := NewBatchTrainer(NewSGD(0.1, 0, 0, false), 0, 8, 2)
+		if sparse {
+			trainer.TrainSparse(n, data, nil, 5)
+		} else {
+			trainer.Train(n, data.Dense(n.Config.Inputs), nil, 5)
+		}
+		return n
+	}
+	sparse, dense := train(true), train(false)
+	for i, l := range dense.Layers {
+		assert.InDeltaSlice(t, l.Weights, sparse.Layers[i].Weights, 1e-5)
+		assert.InDeltaSlice(t, l.Bias, sparse.Layers[i].Bias, 1e-5)
+	}
+}
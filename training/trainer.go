@@ -14,9 +14,10 @@ type Trainer interface {
 // OnlineTrainer is a basic, online network trainer
 type OnlineTrainer struct {
 	*internal
-	solver    Solver
-	printer   *StatsPrinter
-	verbosity int
+	internal64 *internal64
+	solver     Solver
+	printer    *StatsPrinter
+	verbosity  int
 }
 
 // NewTrainer creates a new trainer
@@ -30,21 +31,45 @@ func NewTrainer(solver Solver, verbosity int) *OnlineTrainer {
 
 type internal struct {
 	deltas [][]float32
+	state  *deep.State
+
+	// grads holds the weight gradients of layers that are not dense, and
+	// the activation parameter gradients of layers that have them
+	grads [][]float32
+	// rows holds the embedding rows looked up for the current example
+	rows []int
 }
 
-func newTraining(layers []*deep.Layer) *internal {
-	deltas := make([][]float32, len(layers))
-	for i, l := range layers {
-		deltas[i] = make([]float32, len(l.Neurons))
+func newTraining(n *deep.Neural) *internal {
+	deltas := make([][]float32, len(n.Layers))
+	grads := make([][]float32, len(n.Layers))
+	for i, l := range n.Layers {
+		deltas[i] = make([]float32, l.Size)
+		if l.Type != deep.LayerDense || l.Params != nil {
+			grads[i] = make([]float32, l.NumWeights())
+		}
 	}
 	return &internal{
 		deltas: deltas,
+		state:  n.NewState(),
+		grads:  grads,
 	}
 }
 
-// Train trains n
+// Train trains n. Float64 networks are trained through Train64, and
+// recurrent networks on sequences of one step. Batch normalization layers
+// normalize with their running statistics, which online training leaves
+// untouched.
 func (t *OnlineTrainer) Train(n *deep.Neural, examples, validation Examples, iterations int) {
-	t.internal = newTraining(n.Layers)
+	if n.Config.Precision == deep.PrecisionFloat64 {
+		t.Train64(n, examples.Float64(), validation.Float64(), iterations)
+		return
+	}
+	if n.Recurrent() {
+		t.TrainSequences(n, examples.Sequences(), validation.Sequences(), iterations, 0)
+		return
+	}
+	t.internal = newTraining(n)
 
 	train := make(Examples, len(examples))
 	copy(train, examples)
@@ -67,42 +92,138 @@ func (t *OnlineTrainer) Train(n *deep.Neural, examples, validation Examples, ite
 }
 
 func (t *OnlineTrainer) learn(n *deep.Neural, e Example, it int) {
-	n.Forward(e.Input, true)
-	t.calculateDeltas(n, e.Response)
-	t.update(n, it)
+	n.ForwardState(t.state, e.Input, true)
+	t.calculateDeltas(n, e.Input, e.Response)
+	t.update(n, e.Input, it)
 }
 
-func (t *OnlineTrainer) calculateDeltas(n *deep.Neural, ideal []float32) {
-	for i, neuron := range n.Layers[len(n.Layers)-1].Neurons {
-		t.deltas[len(n.Layers)-1][i] = deep.GetLoss(n.Config.Loss).Df(
-			neuron.Value,
-			ideal[i],
-			neuron.DActivate(neuron.Value))
+func (t *OnlineTrainer) calculateDeltas(n *deep.Neural, input, ideal []float32) {
+	last := len(n.Layers) - 1
+	outputDeltas(n, t.state, ideal, t.deltas[last], t.grads[last])
+	backpropagate(n, t.state, input, t.deltas, nil, t.grads)
+}
+
+// outputDeltas sets the deltas of the output layer of n from the forward
+// pass in s against ideal, and adds the gradients of its activation
+// parameters into grad unless it is nil
+func outputDeltas(n *deep.Neural, s *deep.State, ideal, deltas, grad []float32) {
+	loss := deep.GetLoss(n.Config.Loss)
+	last := len(n.Layers) - 1
+	out := n.Layers[last]
+	for k, y := range s.Output() {
+		x := s.Sums[last][k]
+		out.BackwardParam(k, x, y, loss.Df(y, ideal[k], 1), grad)
+		deltas[k] = loss.Df(y, ideal[k], out.DActivate(k, x, y))
 	}
+}
 
-	for i := len(n.Layers) - 2; i >= 0; i-- {
-		for j, neuron := range n.Layers[i].Neurons {
-			var sum float32
-			for k, s := range neuron.Out {
-				sum += s.Weight * t.deltas[i+1][k]
+// backpropagate computes the deltas of every layer from those of the output
+// layer, given the forward pass in s from input. It adds the weight
+// gradients of each layer i > 0 into grads[i] unless grads is nil, and the
+// activation parameter gradients of each layer into params[i] unless params
+// is nil.
+func backpropagate(n *deep.Neural, s *deep.State, input []float32, deltas [][]float32, grads, params [][]float32) {
+	for _, d := range deltas[:len(deltas)-1] {
+		for j := range d {
+			d[j] = 0
+		}
+	}
+	for i := len(n.Layers) - 1; i > 0; i-- {
+		var grad []float32
+		if grads != nil {
+			grad = grads[i]
+		}
+		n.Backpropagate(s, i, input, deltas, grad)
+
+		var param []float32
+		if params != nil {
+			param = params[i-1]
+		}
+		dactivate(n.Layers[i-1], s, i-1, deltas[i-1], param)
+	}
+}
+
+// dactivate multiplies the gradients d with respect to the outputs of layer
+// i by the derivative of its activation, given the forward pass in s, and
+// routes them through its dropout mask. It adds the gradients of the
+// activation parameters into grad unless it is nil.
+func dactivate(l *deep.Layer, s *deep.State, i int, d, grad []float32) {
+	mask := s.Mask(i)
+	for j, y := range s.Values[i] {
+		if mask != nil {
+			if mask[j] == 0 {
+				d[j] = 0
+				continue
 			}
-			t.deltas[i][j] = neuron.DActivate(neuron.Value) * sum
+			d[j] *= mask[j]
+			y /= mask[j]
 		}
+		x := s.Sums[i][j]
+		l.BackwardParam(j, x, y, d[j], grad)
+		d[j] *= l.DActivate(j, x, y)
 	}
 }
 
-func (t *OnlineTrainer) update(n *deep.Neural, it int) {
-	var idx int
+func (t *OnlineTrainer) update(n *deep.Neural, input []float32, it int) {
+	var offset int
 	for i, l := range n.Layers {
-		for j := range l.Neurons {
-			for k := range l.Neurons[j].In {
-				update := t.solver.Update(l.Neurons[j].In[k].Weight,
-					t.deltas[i][j]*l.Neurons[j].In[k].In,
-					it,
-					idx)
-				l.Neurons[j].In[k].Weight += update
-				idx++
-			}
+		t.updateLayer(i, l, t.state.Input(i, input), it, offset)
+		offset += l.NumWeights()
+	}
+}
+
+func (t *OnlineTrainer) updateLayer(i int, l *deep.Layer, input []float32, it, offset int) {
+	deltas := t.deltas[i]
+	if l.Type == deep.LayerEmbedding {
+		l.Backward(input, deltas, t.grads[i], nil)
+		t.updateRows(l, t.grads[i], input, it, offset)
+		return
+	}
+	if l.Type != deep.LayerDense {
+		l.Backward(input, deltas, t.grads[i], nil)
+		t.updateGradients(l, t.grads[i], it, offset)
+		return
+	}
+
+	for j, d := range deltas {
+		row := l.Row(j)
+		idx := offset + j*l.Inputs
+		for k := range row {
+			row[k] += t.solver.Update(row[k], d*input[k], it, idx+k)
 		}
 	}
+	t.updateBias(l, deltas, it, offset)
+	t.updateParams(l, t.grads[i], it, offset)
+}
+
+// updateGradients updates the weights of l from their accumulated gradients
+// in grad, and clears grad
+func (t *OnlineTrainer) updateGradients(l *deep.Layer, grad []float32, it, offset int) {
+	for k := range l.Weights {
+		l.Weights[k] += t.solver.Update(l.Weights[k], grad[k], it, offset+k)
+		grad[k] = 0
+	}
+	bias := grad[len(l.Weights):]
+	for k := range l.Bias {
+		l.Bias[k] += t.solver.Update(l.Bias[k], bias[k], it, offset+len(l.Weights)+k)
+		bias[k] = 0
+	}
+	t.updateParams(l, grad, it, offset)
+}
+
+// updateParams updates the activation parameters of l from their
+// accumulated gradients at the end of grad, and clears them
+func (t *OnlineTrainer) updateParams(l *deep.Layer, grad []float32, it, offset int) {
+	idx := len(l.Weights) + len(l.Bias)
+	for k := range l.Params {
+		l.Params[k] += t.solver.Update(l.Params[k], grad[idx+k], it, offset+idx+k)
+		grad[idx+k] = 0
+	}
+}
+
+func (t *OnlineTrainer) updateBias(l *deep.Layer, deltas []float32, it, offset int) {
+	idx := offset + len(l.Weights)
+	for j := range l.Bias {
+		l.Bias[j] += t.solver.Update(l.Bias[j], deltas[j], it, idx+j)
+	}
 }
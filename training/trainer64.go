@@ -0,0 +1,259 @@
+package training
+
+import (
+	"sync"
+	"time"
+
+	deep "github.com/nathanleary/neural-net"
+)
+
+type internal64 struct {
+	deltas [][]float64
+	state  *deep.State
+	solver Solver64
+}
+
+func newTraining64(n *deep.Neural, solver Solver) *internal64 {
+	deltas := make([][]float64, len(n.Layers))
+	for i, l := range n.Layers {
+		deltas[i] = make([]float64, l.Size)
+	}
+	return &internal64{
+		deltas: deltas,
+		state:  n.NewState(),
+		solver: solver64(solver),
+	}
+}
+
+// Train64 trains a float64 network on float64 examples
+func (t *OnlineTrainer) Train64(n *deep.Neural, examples, validation Examples64, iterations int) {
+	t.internal64 = newTraining64(n, t.solver)
+
+	train := make(Examples64, len(examples))
+	copy(train, examples)
+
+	t.printer.Init(n)
+	t.internal64.solver.Init64(n.NumWeights())
+
+	ts := time.Now()
+	for i := 1; i <= iterations; i++ {
+
+		train.Shuffle()
+		for j := 0; j < len(train); j++ {
+			t.learn64(n, train[j], i)
+		}
+
+		if t.verbosity > 0 && i%t.verbosity == 0 && len(validation) > 0 {
+			t.printer.PrintProgress64(n, validation, time.Since(ts), i)
+		}
+	}
+}
+
+func (t *OnlineTrainer) learn64(n *deep.Neural, e Example64, it int) {
+	n.ForwardState64(t.internal64.state, e.Input, true)
+	calculateDeltas64(n, t.internal64.state, t.internal64.deltas, e.Response)
+	t.update64(n, e.Input, it)
+}
+
+func (t *OnlineTrainer) update64(n *deep.Neural, input []float64, it int) {
+	tr := t.internal64
+	var idx int
+	for i, l := range n.Layers {
+		if i > 0 {
+			input = tr.state.Values64[i-1]
+		}
+		for j, d := range tr.deltas[i] {
+			row := l.Row64(j)
+			for k := range row {
+				row[k] += tr.solver.Update64(row[k], d*input[k], it, idx)
+				idx++
+			}
+		}
+		for j := range l.Bias64 {
+			l.Bias64[j] += tr.solver.Update64(l.Bias64[j], tr.deltas[i][j], it, idx)
+			idx++
+		}
+	}
+}
+
+// calculateDeltas64 backpropagates the loss of the forward pass in s into
+// the per-neuron deltas of a float64 network
+func calculateDeltas64(n *deep.Neural, s *deep.State, deltas [][]float64, ideal []float64) {
+	loss := deep.GetLoss64(n.Config.Loss)
+	last := len(n.Layers) - 1
+	out := n.Layers[last]
+	for i, y := range s.Output64() {
+		deltas[last][i] = loss.Df64(
+			y,
+			ideal[i],
+			out.DActivate64(s.Sums64[last][i], y))
+	}
+
+	for i := len(n.Layers) - 2; i >= 0; i-- {
+		l, next := n.Layers[i], n.Layers[i+1]
+		mask := s.Mask(i)
+		for j, y := range s.Values64[i] {
+			var sum float64
+			for k, d := range deltas[i+1] {
+				sum += next.Weights64[k*next.Inputs+j] * d
+			}
+			if mask != nil {
+				if mask[j] == 0 {
+					deltas[i][j] = 0
+					continue
+				}
+				sum *= float64(mask[j])
+				y /= float64(mask[j])
+			}
+			deltas[i][j] = l.DActivate64(s.Sums64[i][j], y) * sum
+		}
+	}
+}
+
+type internalb64 struct {
+	deltas            [][][]float64
+	partialDeltas     [][][]float64
+	accumulatedDeltas [][]float64
+	states            []*deep.State
+	solver            Solver64
+}
+
+func newBatchTraining64(n *deep.Neural, parallelism int, solver Solver) *internalb64 {
+	deltas := make([][][]float64, parallelism)
+	partialDeltas := make([][][]float64, parallelism)
+	accumulatedDeltas := make([][]float64, len(n.Layers))
+	states := make([]*deep.State, parallelism)
+	for w := 0; w < parallelism; w++ {
+		deltas[w] = make([][]float64, len(n.Layers))
+		partialDeltas[w] = make([][]float64, len(n.Layers))
+		states[w] = n.NewState()
+
+		for i, l := range n.Layers {
+			deltas[w][i] = make([]float64, l.Size)
+			partialDeltas[w][i] = make([]float64, l.NumWeights())
+			accumulatedDeltas[i] = make([]float64, l.NumWeights())
+		}
+	}
+	return &internalb64{
+		deltas:            deltas,
+		partialDeltas:     partialDeltas,
+		accumulatedDeltas: accumulatedDeltas,
+		states:            states,
+		solver:            solver64(solver),
+	}
+}
+
+// Train64 trains a float64 network on float64 examples
+func (t *BatchTrainer) Train64(n *deep.Neural, examples, validation Examples64, iterations int) {
+	t.internalb64 = newBatchTraining64(n, t.parallelism, t.solver)
+	tr := t.internalb64
+
+	train := make(Examples64, len(examples))
+	copy(train, examples)
+
+	wg := sync.WaitGroup{}
+
+	// As in run, each worker learns the same slots of every batch
+	workChs := make([]chan Example64, t.parallelism)
+	for i := range workChs {
+		workChs[i] = make(chan Example64, (t.batchSize+t.parallelism-1)/t.parallelism)
+		defer close(workChs[i])
+
+		go func(id int, workCh <-chan Example64) {
+			for e := range workCh {
+				n.ForwardState64(tr.states[id], e.Input, true)
+				t.calculateDeltas64(n, e.Input, e.Response, id)
+				wg.Done()
+			}
+		}(i, workChs[i])
+	}
+
+	t.printer.Init(n)
+	tr.solver.Init64(n.NumWeights())
+
+	ts := time.Now()
+	for it := 1; it <= iterations; it++ {
+
+		train.Shuffle()
+		batches := train.SplitSize(t.batchSize)
+
+		for _, b := range batches {
+			wg.Add(len(b))
+
+			for k, item := range b {
+				workChs[k%t.parallelism] <- item
+			}
+			wg.Wait()
+
+			for _, wPD := range tr.partialDeltas {
+				for i, iPD := range wPD {
+					iAD := tr.accumulatedDeltas[i]
+					for k, v := range iPD {
+						iAD[k] += v
+						iPD[k] = 0
+					}
+				}
+			}
+
+			t.update64(n, it)
+		}
+
+		if t.verbosity > 0 && it%t.verbosity == 0 && len(validation) > 0 {
+			t.printer.PrintProgress64(n, validation, time.Since(ts), it)
+		}
+	}
+}
+
+func (t *BatchTrainer) calculateDeltas64(n *deep.Neural, input, ideal []float64, wid int) {
+	tr := t.internalb64
+	deltas := tr.deltas[wid]
+	partialDeltas := tr.partialDeltas[wid]
+	state := tr.states[wid]
+
+	calculateDeltas64(n, state, deltas, ideal)
+
+	for i, l := range n.Layers {
+		if i > 0 {
+			input = state.Values64[i-1]
+		}
+		iD := deltas[i]
+		iPD := partialDeltas[i]
+		for j, jD := range iD {
+			jPD := iPD[j*l.Inputs : (j+1)*l.Inputs]
+			for k, x := range input {
+				jPD[k] += jD * x
+			}
+		}
+		if l.Bias64 != nil {
+			bPD := iPD[len(l.Weights64):]
+			for j, jD := range iD {
+				bPD[j] += jD
+			}
+		}
+	}
+}
+
+func (t *BatchTrainer) update64(n *deep.Neural, it int) {
+	tr := t.internalb64
+	wg := sync.WaitGroup{}
+	var offset int
+	for i, l := range n.Layers {
+		wg.Add(1)
+		go func(l *deep.Layer, iAD []float64, idx int) {
+			for k := range l.Weights64 {
+				l.Weights64[k] += tr.solver.Update64(l.Weights64[k], iAD[k], it, idx)
+				iAD[k] = 0
+				idx++
+			}
+			bAD := iAD[len(l.Weights64):]
+			for k := range l.Bias64 {
+				l.Bias64[k] += tr.solver.Update64(l.Bias64[k], bAD[k], it, idx)
+				bAD[k] = 0
+				idx++
+			}
+			wg.Done()
+		}(l, tr.accumulatedDeltas[i], offset)
+		offset += l.NumWeights()
+	}
+	wg.Wait()
+}
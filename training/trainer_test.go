@@ -2,6 +2,7 @@ package training
 
 import (
 	"fmt"
+	gomath "math"
 	"math/rand"
 	"testing"
 
@@ -23,13 +24,13 @@ func Test_BoundedRegression(t *testing.T) {
 	for _, f := range funcs {
 
 		data := Examples{}
-		for i := 0.0; i < 1; i += 0.01 {
+		for i := float32(0.0); i < 1; i += 0.01 {
 			data = append(data, Example{Input: []float32{i}, Response: []float32{f(i)}})
 		}
 		n := deep.NewNeural(&deep.Config{
 			Inputs:     1,
 			Layout:     []int{4, 4, 1},
-			Activation: deep.ActivationTanh,
+			Activation: []deep.ActivationType{deep.ActivationTanh, deep.ActivationTanh, deep.ActivationTanh},
 			Mode:       deep.ModeRegression,
 			Weight:     deep.NewUniform(0.5, 0),
 			Bias:       true,
@@ -48,14 +49,14 @@ func Test_BoundedRegression(t *testing.T) {
 func Test_RegressionLinearOuts(t *testing.T) {
 	rand.Seed(0)
 	squares := Examples{}
-	for i := 0.0; i < 100.0; i++ {
+	for i := float32(0.0); i < 100.0; i++ {
 		squares = append(squares, Example{Input: []float32{i}, Response: []float32{math.Sqrt(i)}})
 	}
 	squares.Shuffle()
 	n := deep.NewNeural(&deep.Config{
 		Inputs:     1,
 		Layout:     []int{3, 3, 1},
-		Activation: deep.ActivationReLU,
+		Activation: []deep.ActivationType{deep.ActivationReLU, deep.ActivationReLU, deep.ActivationReLU},
 		Mode:       deep.ModeRegression,
 		Weight:     deep.NewNormal(0.5, 0.5),
 		Bias:       true,
@@ -70,6 +71,41 @@ func Test_RegressionLinearOuts(t *testing.T) {
 	}
 }
 
+func Test_RegressionFloat64(t *testing.T) {
+	rand.Seed(0)
+
+	data := Examples64{}
+	for i := 0.0; i < 1; i += 0.01 {
+		data = append(data, Example64{Input: []float64{i}, Response: []float64{gomath.Sin(i)}})
+	}
+
+	for _, trainer := range []interface {
+		Train64(*deep.Neural, Examples64, Examples64, int)
+	}{
+		NewTrainer(NewSGD(0.25, 0.5, 0, false), 0),
+		NewBatchTrainer(NewAdam(0.01, 0, 0, 0), 0, 10, 2),
+	} {
+		n := deep.NewNeural(&deep.Config{
+			Inputs:     1,
+			Layout:     []int{4, 4, 1},
+			Activation: []deep.ActivationType{deep.ActivationTanh, deep.ActivationTanh},
+			Mode:       deep.ModeRegression,
+			Weight:     deep.NewUniform(0.5, 0),
+			Bias:       true,
+			Precision:  deep.PrecisionFloat64,
+		})
+
+		trainer.Train64(n, data, nil, 2000)
+
+		for _, x := range []float64{0.0, 0.1, 0.25, 0.5, 0.75, 0.9} {
+			assert.InEpsilon(t, gomath.Sin(x)+1, n.Predict64([]float64{x})[0]+1, 0.05)
+		}
+		loss, err := crossValidate64(n, data)
+		assert.NoError(t, err)
+		assert.True(t, loss < 1e-3)
+	}
+}
+
 func Test_Training(t *testing.T) {
 	rand.Seed(0)
 
@@ -84,7 +120,7 @@ func Test_Training(t *testing.T) {
 	n := deep.NewNeural(&deep.Config{
 		Inputs:     1,
 		Layout:     []int{5, 1},
-		Activation: deep.ActivationSigmoid,
+		Activation: []deep.ActivationType{deep.ActivationSigmoid, deep.ActivationSigmoid},
 		Weight:     deep.NewUniform(0.5, 0),
 		Bias:       true,
 	})
@@ -117,7 +153,7 @@ func Test_Prediction(t *testing.T) {
 	n := deep.NewNeural(&deep.Config{
 		Inputs:     2,
 		Layout:     []int{2, 2, 1},
-		Activation: deep.ActivationSigmoid,
+		Activation: []deep.ActivationType{deep.ActivationSigmoid, deep.ActivationSigmoid, deep.ActivationSigmoid},
 		Weight:     deep.NewUniform(0.5, 0),
 		Bias:       true,
 	})
@@ -134,7 +170,7 @@ func Test_CrossVal(t *testing.T) {
 	n := deep.NewNeural(&deep.Config{
 		Inputs:     2,
 		Layout:     []int{1, 1},
-		Activation: deep.ActivationTanh,
+		Activation: []deep.ActivationType{deep.ActivationTanh, deep.ActivationTanh},
 		Loss:       deep.LossMeanSquared,
 		Weight:     deep.NewUniform(0.5, 0),
 		Bias:       true,
@@ -145,7 +181,9 @@ func Test_CrossVal(t *testing.T) {
 
 	for _, d := range data {
 		assert.InEpsilon(t, n.Predict(d.Input)[0]+1, d.Response[0]+1, 0.1)
-		assert.InEpsilon(t, 1, crossValidate(n, data)+1, 0.01)
+		loss, err := crossValidate(n, data)
+		assert.NoError(t, err)
+		assert.InEpsilon(t, 1, loss+1, 0.01)
 	}
 }
 
@@ -166,7 +204,7 @@ func Test_MultiClass(t *testing.T) {
 	n := deep.NewNeural(&deep.Config{
 		Inputs:     2,
 		Layout:     []int{2, 2},
-		Activation: deep.ActivationReLU,
+		Activation: []deep.ActivationType{deep.ActivationReLU, deep.ActivationReLU},
 		Mode:       deep.ModeMultiClass,
 		Loss:       deep.LossMeanSquared,
 		Weight:     deep.NewUniform(0.1, 0),
@@ -184,7 +222,9 @@ func Test_MultiClass(t *testing.T) {
 		} else {
 			assert.InEpsilon(t, n.Predict(d.Input)[1]+1, d.Response[1]+1, 0.1)
 		}
-		assert.InEpsilon(t, 1, crossValidate(n, data)+1, 0.01)
+		loss, err := crossValidate(n, data)
+		assert.NoError(t, err)
+		assert.InEpsilon(t, 1, loss+1, 0.01)
 	}
 
 }
@@ -194,7 +234,7 @@ func Test_or(t *testing.T) {
 	n := deep.NewNeural(&deep.Config{
 		Inputs:     2,
 		Layout:     []int{1, 1},
-		Activation: deep.ActivationTanh,
+		Activation: []deep.ActivationType{deep.ActivationTanh, deep.ActivationTanh},
 		Mode:       deep.ModeBinary,
 		Weight:     deep.NewUniform(0.5, 0),
 		Bias:       true,
@@ -220,7 +260,7 @@ func Test_xor(t *testing.T) {
 	n := deep.NewNeural(&deep.Config{
 		Inputs:     2,
 		Layout:     []int{3, 1}, // Sufficient for modeling (AND+OR) - with 5-6 neuron always converges
-		Activation: deep.ActivationSigmoid,
+		Activation: []deep.ActivationType{deep.ActivationSigmoid, deep.ActivationSigmoid},
 		Mode:       deep.ModeBinary,
 		Weight:     deep.NewUniform(.25, 0),
 		Bias:       true,
@@ -108,6 +108,12 @@ func Sum(xx []float32) (sum float32) {
 // Softmax is the softmax function
 func Softmax(xx []float32) []float32 {
 	out := make([]float32, len(xx))
+	SoftmaxTo(out, xx)
+	return out
+}
+
+// SoftmaxTo writes the softmax of xx into out, which may be xx itself
+func SoftmaxTo(out, xx []float32) {
 	var sum float32
 	max := Max(xx)
 	for i, x := range xx {
@@ -117,7 +123,6 @@ func Softmax(xx []float32) []float32 {
 	for i := range out {
 		out[i] /= sum
 	}
-	return out
 }
 
 // Round to nearest integer
@@ -133,3 +138,39 @@ func Dot(xx, yy []float32) float32 {
 	}
 	return p
 }
+
+// mulTBlock is the number of rows of b processed together by MulT, chosen so
+// that a block of weights stays in cache while it is applied to every row of a
+const mulTBlock = 64
+
+// MulT computes the matrix product c = a·bᵀ, where a is m x k, b is n x k and
+// c is m x n, all stored in row-major order
+func MulT(c, a, b []float32, m, n, k int) {
+	for j0 := 0; j0 < n; j0 += mulTBlock {
+		j1 := j0 + mulTBlock
+		if j1 > n {
+			j1 = n
+		}
+		for i := 0; i < m; i++ {
+			ai := a[i*k : (i+1)*k]
+			ci := c[i*n : (i+1)*n]
+			for j := j0; j < j1; j++ {
+				ci[j] = Dot(ai, b[j*k:(j+1)*k])
+			}
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a <= b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a >= b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,81 @@
+package deep
+
+import "math"
+
+// Dot64 is the float64 dot product
+func Dot64(xx, yy []float64) float64 {
+	var p float64
+	for i := range xx {
+		p += xx[i] * yy[i]
+	}
+	return p
+}
+
+// Max64 is the largest element
+func Max64(xx []float64) float64 {
+	max := xx[0]
+	for _, x := range xx {
+		if x > max {
+			max = x
+		}
+	}
+	return max
+}
+
+// ArgMax64 is the index of the largest element
+func ArgMax64(xx []float64) int {
+	max, idx := xx[0], 0
+	for i, x := range xx {
+		if x > max {
+			max, idx = xx[i], i
+		}
+	}
+	return idx
+}
+
+// SoftmaxTo64 writes the softmax of xx into out, which may be xx itself
+func SoftmaxTo64(out, xx []float64) {
+	var sum float64
+	max := Max64(xx)
+	for i, x := range xx {
+		out[i] = math.Exp(x - max)
+		sum += out[i]
+	}
+	for i := range out {
+		out[i] /= sum
+	}
+}
+
+// MulT64 computes the float64 matrix product c = a·bᵀ, where a is m x k, b is
+// n x k and c is m x n, all stored in row-major order
+func MulT64(c, a, b []float64, m, n, k int) {
+	for j0 := 0; j0 < n; j0 += mulTBlock {
+		j1 := j0 + mulTBlock
+		if j1 > n {
+			j1 = n
+		}
+		for i := 0; i < m; i++ {
+			ai := a[i*k : (i+1)*k]
+			ci := c[i*n : (i+1)*n]
+			for j := j0; j < j1; j++ {
+				ci[j] = Dot64(ai, b[j*k:(j+1)*k])
+			}
+		}
+	}
+}
+
+func toFloat64(xx []float32) []float64 {
+	out := make([]float64, len(xx))
+	for i, x := range xx {
+		out[i] = float64(x)
+	}
+	return out
+}
+
+func toFloat32(xx []float64) []float32 {
+	out := make([]float32, len(xx))
+	for i, x := range xx {
+		out[i] = float32(x)
+	}
+	return out
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func Test_softmax(t *testing.T) {
-	assert.Equal(t, Sum(Softmax([]float32{0.5, 1, 1, 2.5})), 1.0)
+	assert.InEpsilon(t, Sum(Softmax([]float32{0.5, 1, 1, 2.5})), 1.0, 1e-6)
 
 	s := Softmax([]float32{1, 2, 3, 4, 1, 2, 3})
 	e := []float32{0.024, 0.064, 0.175, 0.475, 0.024, 0.064, 0.175}
@@ -20,8 +20,8 @@ func Test_Standardize(t *testing.T) {
 
 	s := []float32{10.0, 5.0, 0.0}
 
-	assert.Equal(t, Mean(s), 5.0)
-	assert.Equal(t, StandardDeviation(s), 5.0)
+	assert.Equal(t, Mean(s), float32(5.0))
+	assert.Equal(t, StandardDeviation(s), float32(5.0))
 
 	Standardize(s)
 
@@ -36,8 +36,8 @@ func Test_Normalize(t *testing.T) {
 
 	s := []float32{10.0, 5.0, 0.0}
 
-	assert.Equal(t, Mean(s), 5.0)
-	assert.Equal(t, StandardDeviation(s), 5.0)
+	assert.Equal(t, Mean(s), float32(5.0))
+	assert.Equal(t, StandardDeviation(s), float32(5.0))
 
 	Normalize(s)
 
@@ -51,17 +51,33 @@ func Test_Normalize(t *testing.T) {
 func Test_MinMax(t *testing.T) {
 	s := []float32{5.0, 10.0, 0.0}
 
-	assert.Equal(t, 0.0, Min(s))
-	assert.Equal(t, 10.0, Max(s))
+	assert.Equal(t, float32(0.0), Min(s))
+	assert.Equal(t, float32(10.0), Max(s))
 	assert.Equal(t, 1, ArgMax(s))
 }
 
 func Test_Dot(t *testing.T) {
-	assert.Equal(t, 17.0, Dot([]float32{1.0, 6.0, 3.0}, []float32{2.0, 2.0, 1.0}))
+	assert.Equal(t, float32(17.0), Dot([]float32{1.0, 6.0, 3.0}, []float32{2.0, 2.0, 1.0}))
 }
 
 func Test_Sgn(t *testing.T) {
-	assert.Equal(t, Sgn(0), 0.)
-	assert.Equal(t, Sgn(-5), -1.)
-	assert.Equal(t, Sgn(3), 1.)
+	assert.Equal(t, Sgn(0), float32(0.))
+	assert.Equal(t, Sgn(-5), float32(-1.))
+	assert.Equal(t, Sgn(3), float32(1.))
+}
+
+func Test_MulT(t *testing.T) {
+	a := []float32{
+		1, 2, 3,
+		4, 5, 6,
+	}
+	b := []float32{
+		1, 0, 1,
+		0, 1, 0,
+		2, 2, 2,
+		1, 1, 0,
+	}
+	c := make([]float32, 2*4)
+	MulT(c, a, b, 2, 4, 3)
+	assert.Equal(t, []float32{4, 2, 12, 3, 10, 5, 30, 9}, c)
 }
@@ -1,6 +1,9 @@
 package deep
 
-import "math/rand"
+import (
+	"math"
+	"math/rand"
+)
 
 // A WeightInitializer returns a (random) weight
 type WeightInitializer func() float32
@@ -25,3 +28,123 @@ func NewNormal(stdDev, mean float32) WeightInitializer {
 func Normal(stdDev, mean float32) float32 {
 	return float32(rand.NormFloat64())*stdDev + mean
 }
+
+// A LayerInitializer fills the weights of a layer, laid out in rows of fanIn
+// weights, given the number of inputs (fan-in) and outputs (fan-out) of each
+// of its neurons
+type LayerInitializer func(weights []float32, fanIn, fanOut int)
+
+// GlorotUniform draws weights from u(-√(6/(fanIn+fanOut)), √(6/(fanIn+fanOut))),
+// also known as Xavier initialization, which suits tanh and sigmoid layers
+func GlorotUniform(weights []float32, fanIn, fanOut int) {
+	limit := float32(math.Sqrt(6 / float64(fanIn+fanOut)))
+	fill(weights, NewUniform(2*limit, 0))
+}
+
+// GlorotNormal draws weights from N(0, √(2/(fanIn+fanOut)))
+func GlorotNormal(weights []float32, fanIn, fanOut int) {
+	fill(weights, NewNormal(float32(math.Sqrt(2/float64(fanIn+fanOut))), 0))
+}
+
+// HeUniform draws weights from u(-√(6/fanIn), √(6/fanIn))
+func HeUniform(weights []float32, fanIn, fanOut int) {
+	limit := float32(math.Sqrt(6 / float64(fanIn)))
+	fill(weights, NewUniform(2*limit, 0))
+}
+
+// HeNormal draws weights from N(0, √(2/fanIn)), which suits ReLU layers
+func HeNormal(weights []float32, fanIn, fanOut int) {
+	fill(weights, NewNormal(float32(math.Sqrt(2/float64(fanIn))), 0))
+}
+
+// LeCunUniform draws weights from u(-√(3/fanIn), √(3/fanIn))
+func LeCunUniform(weights []float32, fanIn, fanOut int) {
+	limit := float32(math.Sqrt(3 / float64(fanIn)))
+	fill(weights, NewUniform(2*limit, 0))
+}
+
+// LeCunNormal draws weights from N(0, √(1/fanIn))
+func LeCunNormal(weights []float32, fanIn, fanOut int) {
+	fill(weights, NewNormal(float32(math.Sqrt(1/float64(fanIn))), 0))
+}
+
+// NewOrthogonal returns an initializer of random orthogonal weight matrices
+// scaled by gain. Rows are orthonormal, or columns if there are more rows
+// than columns.
+func NewOrthogonal(gain float32) LayerInitializer {
+	return func(weights []float32, fanIn, fanOut int) {
+		Orthogonal(weights, len(weights)/fanIn, fanIn, gain)
+	}
+}
+
+// DefaultInitializer returns the initializer of layers with the given
+// activation: LeCun for SELU, He for the ReLU family and Glorot otherwise
+func DefaultInitializer(activation ActivationType) LayerInitializer {
+	switch activation {
+	case ActivationSELU:
+		return LeCunNormal
+	case ActivationReLU, ActivationELU, ActivationSwish, ActivationMish,
+		ActivationGELU, ActivationGELUTanh, ActivationLeakyReLU, ActivationSoftplus, ActivationHardSwish,
+		ActivationPReLU, ActivationSwishBeta:
+		return HeNormal
+	}
+	return GlorotUniform
+}
+
+func fill(weights []float32, weight WeightInitializer) {
+	for k := range weights {
+		weights[k] = weight()
+	}
+}
+
+// Orthogonal fills the rows x cols matrix w, in row-major order, with the
+// orthonormal rows, or columns if there are more rows than columns, of a
+// random normal matrix scaled by gain
+func Orthogonal(w []float32, rows, cols int, gain float32) {
+	n, m := rows, cols
+	if rows > cols {
+		n, m = cols, rows
+	}
+
+	// Gram-Schmidt, redrawing the rare vectors that are nearly dependent
+	v := make([][]float64, n)
+	for i := range v {
+		v[i] = make([]float64, m)
+		for {
+			for k := range v[i] {
+				v[i][k] = rand.NormFloat64()
+			}
+			for pass := 0; pass < 2; pass++ {
+				for _, u := range v[:i] {
+					var dot float64
+					for k := range u {
+						dot += u[k] * v[i][k]
+					}
+					for k := range u {
+						v[i][k] -= dot * u[k]
+					}
+				}
+			}
+			var norm float64
+			for _, x := range v[i] {
+				norm += x * x
+			}
+			if norm = math.Sqrt(norm); norm > 1e-6 {
+				for k := range v[i] {
+					v[i][k] /= norm
+				}
+				break
+			}
+		}
+	}
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if rows > cols {
+				w[r*cols+c] = gain * float32(v[c][r])
+			} else {
+				w[r*cols+c] = gain * float32(v[r][c])
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package deep
+
+import (
+	"math/rand"
+	"testing"
+
+	math "github.com/chewxy/math32"
+	"github.com/stretchr/testify/assert"
+)
+
+func variance(xx []float32) float32 {
+	mean := Mean(xx)
+	var sum float32
+	for _, x := range xx {
+		sum += (x - mean) * (x - mean)
+	}
+	return sum / float32(len(xx))
+}
+
+func Test_LayerInitializers(t *testing.T) {
+	rand.Seed(0)
+	const in, out = 200, 100
+	w := make([]float32, in*out)
+	for _, test := range []struct {
+		init     LayerInitializer
+		variance float32
+	}{
+		{GlorotUniform, 2. / (in + out)},
+		{GlorotNormal, 2. / (in + out)},
+		{HeUniform, 2. / in},
+		{HeNormal, 2. / in},
+		{LeCunUniform, 1. / in},
+		{LeCunNormal, 1. / in},
+	} {
+		test.init(w, in, out)
+		assert.InDelta(t, 0, Mean(w), 0.01)
+		assert.InEpsilon(t, test.variance, variance(w), 0.05)
+	}
+
+	limit := math.Sqrt(6. / (in + out))
+	GlorotUniform(w, in, out)
+	assert.True(t, Max(w) <= limit && Min(w) >= -limit)
+}
+
+func Test_Orthogonal(t *testing.T) {
+	rand.Seed(0)
+	for _, shape := range [][2]int{{3, 5}, {5, 3}, {4, 4}} {
+		rows, cols := shape[0], shape[1]
+		w := make([]float32, rows*cols)
+		NewOrthogonal(2)(w, cols, rows)
+
+		// The shorter dimension holds orthogonal vectors of norm gain
+		vector := func(i int) []float32 {
+			v := make([]float32, 0, rows+cols)
+			if rows <= cols {
+				return append(v, w[i*cols:(i+1)*cols]...)
+			}
+			for r := 0; r < rows; r++ {
+				v = append(v, w[r*cols+i])
+			}
+			return v
+		}
+		for i := 0; i < min(rows, cols); i++ {
+			for j := 0; j <= i; j++ {
+				expected := float32(0)
+				if i == j {
+					expected = 4
+				}
+				assert.InDelta(t, expected, Dot(vector(i), vector(j)), 1e-5)
+			}
+		}
+	}
+}
+
+func Test_DefaultInitializer(t *testing.T) {
+	rand.Seed(0)
+	n := NewNeural(&Config{
+		Inputs:     400,
+		Layout:     []int{300, 200, 100, 10},
+		Layers:     []LayerConfig{{}, {}, {Init: NewOrthogonal(1)}},
+		Activation: []ActivationType{ActivationReLU, ActivationTanh, ActivationReLU},
+		Mode:       ModeMultiClass,
+		Bias:       true,
+	})
+
+	assert.InEpsilon(t, 2./400, variance(n.Layers[0].Weights), 0.05)
+	assert.InEpsilon(t, 2./(300+200), variance(n.Layers[1].Weights), 0.05)
+	assert.InDelta(t, 1, Dot(n.Layers[2].Row(0), n.Layers[2].Row(0)), 1e-5)
+	assert.InDelta(t, 0, Dot(n.Layers[2].Row(0), n.Layers[2].Row(1)), 1e-5)
+	assert.InEpsilon(t, 2./(100+10), variance(n.Layers[3].Weights), 0.1)
+	for _, l := range n.Layers {
+		assert.Equal(t, make([]float32, l.Size), l.Bias)
+	}
+
+	// SELU layers keep unit variance from LeCun initialization
+	n = NewNeural(&Config{
+		Inputs:     400,
+		Layout:     []int{300, 1},
+		Activation: []ActivationType{ActivationSELU},
+		Mode:       ModeRegression,
+	})
+	assert.InEpsilon(t, 1./400, variance(n.Layers[0].Weights), 0.05)
+
+	// Init replaces Weight for weights, but not for bias
+	n = NewNeural(&Config{
+		Inputs: 400,
+		Layout: []int{300, 1},
+		Init:   LeCunNormal,
+		Weight: NewUniform(1, 5),
+		Bias:   true,
+	})
+	assert.InEpsilon(t, 1./400, variance(n.Layers[0].Weights), 0.05)
+	assert.True(t, Min(n.Layers[0].Bias) >= 4.5)
+}
+
+func Test_LayerFan(t *testing.T) {
+	in, out := NewLayer(3, 4, ActivationReLU).Fan()
+	assert.Equal(t, []int{3, 4}, []int{in, out})
+	in, out = NewConv2D(Shape{Channels: 2, Height: 5, Width: 5}, 6, 3, 1, 0, ActivationReLU).Fan()
+	assert.Equal(t, []int{2 * 3 * 3, 6 * 3 * 3}, []int{in, out})
+	in, out = NewLSTM(3, 4).Fan()
+	assert.Equal(t, []int{7, 4}, []int{in, out})
+	in, out = NewLayerNorm(3, 0, ActivationLinear).Fan()
+	assert.Equal(t, []int{0, 0}, []int{in, out})
+}